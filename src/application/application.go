@@ -3,23 +3,39 @@ package application
 import (
 	"flag"
 	"fmr/bll"
-	"fmr/bll/common"
+	"fmr/bll/report"
 	"fmr/dal"
 	"fmr/util"
-	"log"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
+const taskAnnotate = "annotate"
 const taskCalculate = "calculate"
 const taskCompare = "compare"
+const taskConvert = "convert"
+const taskCrossCheck = "crosscheck"
 const taskExport = "export"
 const taskImport = "import"
+const taskKeygen = "keygen"
+const taskMerge = "merge"
+const taskQuery = "query"
+const taskRepair = "repair"
 const taskVerify = "verify"
 
+const storeCsv = "csv"
+const storeXattr = "xattr"
+
 // Application Contains main application logic.
 type Application struct {
-	config  configuration
-	logFile *os.File
+	config   configuration
+	command  *command
+	logFile  *os.File
+	exitCode int
 }
 
 type configuration struct {
@@ -34,142 +50,210 @@ type configuration struct {
 	filter          string
 	missingOnly     bool
 	logPath         string
+	logFormat       string
+	quiet           bool
+	verbose         bool
+	dryRun          bool
+	configPath      string
+	profile         string
+	checkpoint      time.Duration
+	resume          bool
+	signingKey      string
+	verificationKey string
+	metadataColumns string
+	note            string
+	appendNote      bool
+	tags            string
+	untags          string
+	metadataFile    string
+	where           string
+	checksumPrefix  string
+	format          string
+	conflictPolicy  string
+	inputs          []string
+	archives        bool
+	attributes      bool
+	store           string
+	mtree           bool
+	blockSize       string
+	blockSizeBytes  int64
+	recovery        int
+	replicas        string
+	minCopies       int
+	symlinks        string
+	normalizeNames  bool
+	ignoreCase      bool
+	maxRate         string
+	maxRateBytes    int64
+	nice            int
+	ioIdle          bool
+	dropCache       bool
 }
 
 // Initialize Initializes the application.
 func (app *Application) Initialize() {
 
-	defaultConfig := configuration{taskCalculate, dal.SHA256, "", "", "", "", "", "", "", false, ""}
-	app.parseCommandLineArguments(defaultConfig)
-	app.verifyConfiguration()
+	app.config = configuration{
+		task:           taskCalculate,
+		algorithm:      dal.SHA256,
+		configPath:     getDefaultConfigPath(),
+		checkpoint:     5 * time.Minute,
+		format:         report.QueryFormatTable,
+		conflictPolicy: bll.MergeNewest,
+		symlinks:       util.SymlinksFollow,
+		store:          storeCsv,
+		logFormat:      util.LogFormatText,
+	}
+	app.parseCommandLineArguments(os.Args[1:])
+	app.initializeLog()
+	app.command.verify(app)
 }
 
-// Execute Executes the application.
-func (app *Application) Execute() {
+// Execute Executes the application. Returns the exit code of the process.
+func (app *Application) Execute() int {
 
-	app.initializeLog()
 	defer app.cleanUp()
 
-	conf := app.config
-	db := dal.NewCsvDatabase(conf.inputChecksum, conf.outputChecksum, conf.outputNames)
-
-	if app.config.task == taskCalculate {
-		calculator := bll.NewCalculator(db, conf.inputDirectory, conf.algorithm, conf.basePath)
-		calculator.Calculate(conf.missingOnly)
-	} else if app.config.task == taskCompare {
-		comparer := bll.NewComparer(db, conf.inputDirectory, app.config.basePath)
-		comparer.Compare(app.config.algorithm)
-	} else if app.config.task == taskExport {
-		exporter := bll.NewExporter(db, conf.outputDirectory, conf.basePath)
-		fpFilter := common.NewFingerprintFilter(conf.filter)
-		exporter.Convert(fpFilter)
-	} else if app.config.task == taskImport {
-		importer := bll.NewImporter(db, conf.inputDirectory, conf.outputChecksum)
-		importer.Convert()
-	} else if app.config.task == taskVerify {
-		verifier := bll.NewVerifier(db, conf.basePath)
-		fpFilter := common.NewFingerprintFilter(conf.filter)
-		verifier.Verify(conf.missingOnly, fpFilter)
-	}
-}
-
-func (app *Application) parseCommandLineArguments(defaultConfig configuration) {
-
-	algorithm := flag.String("alg", defaultConfig.algorithm, "The algorithm used to calculate new checksums.")
-	basePath := flag.String(
-		"bp",
-		defaultConfig.basePath,
-		"The first part of the path that will not be stored in the output.")
-	filter := flag.String(
-		"filter",
-		defaultConfig.filter,
-		"A string in \"filename:algorithm\" format that exported or verified filenames/algorithms must match. Both"+
-			"parts are optional.")
-	inputChecksum := flag.String(
-		"inchk",
-		defaultConfig.inputChecksum,
-		"The name of the input CSV containing checksums.")
-	inputDirectory := flag.String(
-		"indir",
-		defaultConfig.inputDirectory,
-		"The source directory for which the checksums will be calculated (or will be compared). Or the directory"+
-			" containing the files to import.")
-	logPath := flag.String(
-		"log",
-		defaultConfig.logPath,
-		"Path of the log file. Optional, by default the program will print log messages to the standard error output.")
-	outputChecksum := flag.String(
-		"outchk",
-		defaultConfig.outputChecksum,
-		"The name of the output CSV file containing checksums.")
-	outputDirectory := flag.String(
-		"outdir",
-		defaultConfig.outputDirectory,
-		"The name of the directory containing exported files.")
-	outputNames := flag.String(
-		"outnames",
-		defaultConfig.outputNames,
-		"The name of the output containing new file name and old filename pairs.")
-	task := flag.String(
-		"task",
-		defaultConfig.task,
-		"The task to execute: calculate, compare, import, export or verify. The first one calculates checksums for a"+
-			" directory and stores the results in a CSV. The second compares stored checksums with the checksums of"+
-			" the files in the given directory and stores filename matches. The third imports checksums from files"+
-			" generated by Linux utilities or Total Commander. The fourth exports to Total Commander's formats. The"+
-			" fifth verifies checksums for the files listed in the given CSV.")
-	missingOnly := flag.Bool(
-		"missingonly",
-		defaultConfig.missingOnly,
-		"For calculate task it means that calculation is requested for those files only that do not have a checksum"+
-			" stored yet. In case of the verify task, set it to true if you would like to verify only if each file"+
-			" exists.")
-
-	flag.Parse()
+	app.command.execute(app)
 
-	app.config = configuration{
-		*task, *algorithm,
-		*inputChecksum, *inputDirectory,
-		*outputChecksum, *outputDirectory, *outputNames,
-		*basePath, *filter, *missingOnly, *logPath}
+	return app.exitCode
 }
 
-func (app *Application) verifyConfiguration() {
+func (app *Application) parseCommandLineArguments(args []string) {
 
-	if app.config.task == taskCalculate {
-		app.stopIfInputDirectoryDoesNotExist()
-		if app.config.missingOnly {
-			app.stopIfInputChecksumDoesNotExist()
-		} else {
-			app.config.inputChecksum = ""
-		}
-	} else if app.config.task == taskCompare {
-		app.stopIfInputChecksumDoesNotExist()
-		app.stopIfInputDirectoryDoesNotExist()
-	} else if app.config.task == taskExport {
-		app.stopIfOutputDirectoryDoesNotExist()
-	} else if app.config.task == taskImport {
-		app.stopIfInputDirectoryDoesNotExist()
-	} else if app.config.task == taskVerify {
-		app.stopIfInputChecksumDoesNotExist()
+	if len(args) == 0 {
+		printGeneralUsage()
+		os.Exit(2)
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		app.printHelp(args[1:])
+		os.Exit(0)
+	}
+
+	if strings.HasPrefix(args[0], "-") {
+		app.parseLegacyArguments(args)
 	} else {
-		log.Fatalln("Unknown task.")
+		app.parseSubcommandArguments(args)
 	}
 }
 
-func (app *Application) initializeLog() {
+func (app *Application) parseSubcommandArguments(args []string) {
+
+	app.command = findCommand(args[0])
+	if app.command == nil {
+		util.LogFatal("Unknown command: " + args[0] + ". Available commands: " + getCommandNames() + ".")
+	}
+
+	fs := app.command.createFlagSet(&app.config)
+	arguments := parseInterleavedArguments(fs, args[1:])
+	app.collectArguments(arguments)
+	app.applyProfile(fs)
+	app.config.task = app.command.name
+}
+
+// parseLegacyArguments Parses the arguments of the "fmr -task <command> [options]" form. Every option is accepted, but
+// the ones the selected command does not use are reported.
+func (app *Application) parseLegacyArguments(args []string) {
+
+	fs := flag.NewFlagSet("fmr", flag.ExitOnError)
+	fs.StringVar(&app.config.task, "task", app.config.task, "The task to execute: "+getCommandNames()+".")
+	for _, opt := range options {
+		registerOption(fs, &app.config, opt.name, "")
+	}
+	fs.Usage = printGeneralUsage
+
+	arguments := parseInterleavedArguments(fs, args)
+	explicitFlags := getExplicitFlags(fs)
+	app.applyProfile(fs)
+
+	app.command = findCommand(app.config.task)
+	if app.command == nil {
+		util.LogFatal("Unknown task.")
+	}
+	app.collectArguments(arguments)
+
+	for name := range explicitFlags {
+		if name != "task" && !app.command.acceptsOption(name) {
+			util.LogWarn(fmt.Sprintf("Option -%s is ignored by the %s task.", name, app.command.name))
+		}
+	}
+}
 
-	if app.config.logPath == "" {
+func (app *Application) printHelp(args []string) {
+
+	if len(args) == 0 {
+		printGeneralUsage()
 		return
 	}
 
-	file, err := os.OpenFile(app.config.logPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-	if err != nil {
-		app.logFile = nil
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		util.LogFatal("Unknown command: " + args[0] + ". Available commands: " + getCommandNames() + ".")
 	}
 
-	app.logFile = file
-	log.SetOutput(app.logFile)
+	fs := cmd.createFlagSet(&app.config)
+	fs.SetOutput(os.Stdout)
+	fs.Usage()
+}
+
+// applyProfile Loads the selected profile (if any) and sets the options that were not given on the command line.
+// Options that are valid, but not used by the selected command are skipped.
+func (app *Application) applyProfile(fs *flag.FlagSet) {
+
+	if app.config.profile == "" {
+		return
+	}
+	if !util.CheckIfFileExists(app.config.configPath) {
+		util.LogFatal("Configuration file " + app.config.configPath + " does not exist.")
+	}
+
+	configFile := util.LoadConfigFile(app.config.configPath)
+	profile, ok := configFile.GetProfile(app.config.profile)
+	if !ok {
+		util.LogFatal("Profile " + app.config.profile + " does not exist in " + app.config.configPath + ".")
+	}
+
+	explicitFlags := getExplicitFlags(fs)
+	for name, value := range profile {
+		if name == "config" || name == "profile" || (name != "task" && findOption(name) == nil) {
+			util.LogFatal("Unknown option in profile " + app.config.profile + ": " + name + ".")
+		}
+		if fs.Lookup(name) == nil || explicitFlags[name] {
+			continue
+		}
+		err := fs.Set(name, value)
+		util.CheckErrDontPanic(err, "Invalid value for option "+name+" in profile "+app.config.profile+".")
+	}
+}
+
+// initializeLog Sets the level, the format and the output of the log. Stops if the log file cannot be opened.
+func (app *Application) initializeLog() {
+
+	conf := app.config
+	if !util.IsLogFormat(conf.logFormat) {
+		util.LogFatal("Invalid log format: " + conf.logFormat + ".")
+	}
+	if conf.quiet && conf.verbose {
+		util.LogFatal("The -quiet and -verbose options cannot be used together.")
+	}
+
+	level := util.LevelInfo
+	if conf.quiet {
+		level = util.LevelWarn
+	} else if conf.verbose {
+		level = util.LevelDebug
+	}
+
+	var output io.Writer = os.Stderr
+	if conf.logPath != "" {
+		file, err := os.OpenFile(conf.logPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot open log file %s: %s.", conf.logPath, err))
+		app.logFile = file
+		output = file
+	}
+
+	util.ConfigureLog(output, level, conf.logFormat)
 }
 
 func (app *Application) cleanUp() {
@@ -182,20 +266,67 @@ func (app *Application) cleanUp() {
 func (app *Application) stopIfInputChecksumDoesNotExist() {
 
 	if app.config.inputChecksum == "" || !util.CheckIfFileExists(app.config.inputChecksum) {
-		log.Fatalln("Input file does not exist.")
+		util.LogFatal("Input file does not exist.")
 	}
 }
 
 func (app *Application) stopIfInputDirectoryDoesNotExist() {
 
 	if app.config.inputDirectory == "" || !util.CheckIfDirectoryExists(app.config.inputDirectory) {
-		log.Fatalln("Directory " + app.config.inputDirectory + " does not exist.")
+		util.LogFatal("Directory " + app.config.inputDirectory + " does not exist.")
 	}
 }
 
 func (app *Application) stopIfOutputDirectoryDoesNotExist() {
 
 	if !util.CheckIfDirectoryExists(app.config.outputDirectory) {
-		log.Fatalln("Directory " + app.config.outputDirectory + " does not exist.")
+		util.LogFatal("Directory " + app.config.outputDirectory + " does not exist.")
+	}
+}
+
+// collectArguments Stores the positional arguments if the command accepts them, stops otherwise.
+func (app *Application) collectArguments(arguments []string) {
+
+	if len(arguments) > 0 && app.command.arguments == "" {
+		util.LogFatal("Unexpected argument: " + arguments[0] + ".")
 	}
+
+	app.config.inputs = arguments
+}
+
+// parseInterleavedArguments Parses the options and returns the positional arguments. Unlike FlagSet.Parse, options
+// may follow positional arguments.
+func parseInterleavedArguments(fs *flag.FlagSet, args []string) []string {
+
+	arguments := make([]string, 0)
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return arguments
+		}
+		arguments = append(arguments, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func getExplicitFlags(fs *flag.FlagSet) map[string]bool {
+
+	explicitFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicitFlags[f.Name] = true })
+
+	return explicitFlags
+}
+
+func getDefaultConfigPath() string {
+
+	if configPath := os.Getenv("FMR_CONFIG"); configPath != "" {
+		return configPath
+	}
+
+	configDirectory, err := os.UserConfigDir()
+	if err != nil {
+		return "fmr.toml"
+	}
+
+	return filepath.Join(configDirectory, "fmr", "config.toml")
 }
@@ -0,0 +1,940 @@
+package application
+
+import (
+	"errors"
+	"flag"
+	"fmr/bll"
+	"fmr/bll/common"
+	"fmr/bll/report"
+	"fmr/dal"
+	"fmr/util"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// command Describes a task of the application together with its options, validation and execution logic.
+type command struct {
+	name        string
+	summary     string
+	description string
+	options     []string
+	arguments   string
+	usages      map[string]string
+	examples    []string
+	verify      func(app *Application)
+	execute     func(app *Application)
+}
+
+var commands = []*command{
+	{
+		name:    taskCalculate,
+		summary: "Calculate checksums for a directory and store them in a CSV.",
+		description: "Calculates checksum for each file in the given directory (recursively) and produces a CSV file" +
+			" containing the results.",
+		options: []string{
+			"indir", "alg", "outchk", "bp", "missingonly", "inchk", "checkpoint", "resume", "signkey", "verifykey",
+			"metacols", "archives", "blocksize", "recovery", "symlinks", "attributes", "store", "nfc",
+			"ignorecase", "max-rate", "nice", "io-idle", "drop-cache"},
+		usages: map[string]string{
+			"inchk": "The earlier generated CSV. Required if -missingonly is set, ignored otherwise.",
+		},
+		examples: []string{
+			"fmr calculate -indir /mnt/archive/photos -bp /mnt/archive -outchk photos.csv",
+			"fmr calculate -indir /mnt/archive/photos -bp /mnt/archive -missingonly -inchk photos.csv -outchk new.csv",
+			"fmr calculate -indir /mnt/archive/photos -bp /mnt/archive -outchk photos.csv -resume",
+			"fmr calculate -indir /mnt/archive/backups -bp /mnt/archive -outchk backups.csv -archives",
+			"fmr calculate -indir /mnt/archive/images -bp /mnt/archive -outchk images.csv -blocksize 4M",
+			"fmr calculate -indir /mnt/archive/photos -bp /mnt/archive -outchk photos.csv -recovery 10",
+			"fmr calculate -indir /etc -bp / -outchk etc.csv -attributes",
+			"fmr calculate -indir /mnt/archive/photos -bp /mnt/archive -store xattr",
+		},
+		verify:  (*Application).verifyCalculateConfiguration,
+		execute: (*Application).executeCalculate,
+	},
+	{
+		name:    taskCompare,
+		summary: "Compare a directory with an earlier snapshot and track moved files.",
+		description: "Compares stored checksums with the checksums of the files in the given directory, stores the" +
+			" old name - new name pairs of the files found and produces a new CSV with the updated filenames." +
+			" With -blocksize, the files whose stored entry has block hashes are reported as appended (the" +
+			" previous content is unchanged) or modified (with the changed byte ranges) instead of new and missing.",
+		options: []string{
+			"indir", "alg", "inchk", "outchk", "outnames", "bp", "signkey", "verifykey", "metacols", "blocksize",
+			"symlinks", "nfc", "ignorecase", "max-rate", "nice", "io-idle", "drop-cache"},
+		examples: []string{
+			"fmr compare -indir /mnt/archive/photos -bp /mnt/archive -inchk photos.csv -outchk photos-new.csv" +
+				" -outnames renames.txt",
+		},
+		verify:  (*Application).verifyCompareConfiguration,
+		execute: (*Application).executeCompare,
+	},
+	{
+		name:    taskExport,
+		summary: "Export checksums to Total Commander's formats.",
+		description: "Exports checksums from the given CSV into .sfv, .md5, .sha, .sha256 and .sha512 files, or into" +
+			" an mtree specification (Checksum.mtree) that can be checked with mtree -f Checksum.mtree -p <dir>.",
+		options: []string{"inchk", "outdir", "filter", "bp", "mtree", "verifykey", "nfc", "ignorecase"},
+		usages: map[string]string{
+			"bp": "The prefix which should be added to each path in the output.",
+		},
+		examples: []string{
+			"fmr export -inchk photos.csv -outdir /tmp/checksums",
+			"fmr export -inchk photos.csv -outdir /tmp/checksums -filter 2019:sha256 -bp /mnt/archive",
+			"fmr export -inchk photos.csv -outdir /tmp/checksums -mtree",
+		},
+		verify:  (*Application).verifyExportConfiguration,
+		execute: (*Application).executeExport,
+	},
+	{
+		name:    taskImport,
+		summary: "Import checksums generated by Linux utilities or Total Commander.",
+		description: "Imports the checksums stored in the .sfv, .md5, .sha, .sha256 and .sha512 files found in the" +
+			" given directory (recursively) into a CSV. The CRC32 values of the members of .zip archives are read" +
+			" from their central directory, Debian md5sums control files (md5sums, <package>.md5sums) and RPM file" +
+			" lists in the format of rpm -q --dump (.rpmdump) and mtree specifications (.mtree) are imported as well.",
+		options: []string{"indir", "outchk", "signkey", "metacols"},
+		usages: map[string]string{
+			"indir": "The directory containing the files to import.",
+		},
+		examples: []string{
+			"fmr import -indir /tmp/checksums -outchk imported.csv",
+		},
+		verify:  (*Application).verifyImportConfiguration,
+		execute: (*Application).executeImport,
+	},
+	{
+		name:    taskVerify,
+		summary: "Verify the files listed in a CSV.",
+		description: "Verifies the checksums (or only the existence) of the files listed in the given CSV. Archive" +
+			" members (<archive>!/<member>) are verified by reading each archive once; the CRC32 stored in ZIP" +
+			" archives is checked as well. Files whose permissions, ownership or extended attributes (stored with" +
+			" -attributes) have changed are reported separately.",
+		options: []string{
+			"inchk", "bp", "missingonly", "filter", "verifykey", "store", "indir", "nfc", "ignorecase",
+			"max-rate", "nice", "io-idle", "drop-cache"},
+		usages: map[string]string{
+			"bp":          "The base path for each entry listed in the input.",
+			"missingonly": "Only check whether each file exists, do not verify checksums.",
+			"indir":       "The directory whose files are verified with the checksums stored in them (-store xattr).",
+		},
+		examples: []string{
+			"fmr verify -inchk photos.csv -bp /mnt/archive",
+			"fmr verify -store xattr -indir /mnt/archive/photos -bp /mnt/archive",
+			"fmr verify -inchk photos.csv -bp /mnt/archive -filter 2019:sha256",
+			"fmr verify -inchk photos.csv -bp /mnt/archive -verifykey ~/.fmr/registry.key.pub",
+			"fmr verify -inchk photos.csv -bp /mnt/archive -max-rate 50MB/s -io-idle -nice 19 -drop-cache",
+		},
+		verify:  (*Application).verifyVerifyConfiguration,
+		execute: (*Application).executeVerify,
+	},
+	{
+		name:    taskRepair,
+		summary: "Repair damaged files from their recovery data or a replica.",
+		description: "Verifies the files listed in the given CSV and reconstructs the corrupt ones from the recovery" +
+			" data created by calculate -recovery (<input>.recovery). Corrupt files without usable recovery data" +
+			" and missing files are restored from a copy with the same checksum on a replica (-replicas). A repaired" +
+			" file replaces the damaged one only if its checksum matches the stored one. Exits with status 1 if any" +
+			" corrupt or missing file could not be repaired.",
+		options: []string{
+			"inchk", "bp", "filter", "replicas", "verifykey", "nfc", "ignorecase", "max-rate", "nice", "io-idle", "drop-cache"},
+		usages: map[string]string{
+			"bp": "The base path for each entry listed in the input.",
+		},
+		examples: []string{
+			"fmr repair -inchk photos.csv -bp /mnt/archive",
+			"fmr repair -inchk photos.csv -bp /mnt/archive -replicas /mnt/backup1,/mnt/backup2",
+		},
+		verify:  (*Application).verifyRepairConfiguration,
+		execute: (*Application).executeRepair,
+	},
+	{
+		name:    taskCrossCheck,
+		summary: "Verify the entries of a CSV on several copies of the files.",
+		description: "Verifies every entry listed in the given CSV on the base path and on each replica, prints a" +
+			" matrix of the good, corrupt and missing copies and flags the entries with fewer good copies than" +
+			" required. Exits with status 1 if any entry is flagged.",
+		options: []string{
+			"inchk", "bp", "replicas", "mincopies", "filter", "verifykey", "nfc", "ignorecase",
+			"max-rate", "nice", "io-idle", "drop-cache"},
+		usages: map[string]string{
+			"bp":       "The base path of the first copy of the entries listed in the input.",
+			"replicas": "Comma separated list of the roots of the other copies, each mirroring the base path.",
+		},
+		examples: []string{
+			"fmr crosscheck -inchk photos.csv -bp /mnt/archive -replicas /mnt/backup1,/mnt/backup2",
+			"fmr crosscheck -inchk photos.csv -bp /mnt/archive -replicas /mnt/backup1,/mnt/backup2 -mincopies 2",
+		},
+		verify:  (*Application).verifyCrossCheckConfiguration,
+		execute: (*Application).executeCrossCheck,
+	},
+	{
+		name:    taskAnnotate,
+		summary: "Set notes, tags and metadata of the entries in a CSV.",
+		description: "Sets or appends a note and adds or removes tags on the entries matching the filter, or imports" +
+			" notes, tags and metadata from a CSV (-metafile) keyed by filename or checksum. The output is the input" +
+			" itself unless -outchk is given.",
+		options: []string{
+			"inchk", "outchk", "filter", "note", "append", "tags", "untags", "metafile", "signkey", "verifykey",
+			"metacols", "nfc", "ignorecase"},
+		usages: map[string]string{
+			"outchk": "The name of the output CSV. Optional, by default the input is updated.",
+		},
+		examples: []string{
+			"fmr annotate -inchk photos.csv -filter 2019/holiday -note \"Holiday in Crete\" -tags travel,2019",
+			"fmr annotate -inchk photos.csv -filter raw/ -untags unsorted",
+			"fmr annotate -inchk photos.csv -metafile owners.csv",
+		},
+		verify:  (*Application).verifyAnnotateConfiguration,
+		execute: (*Application).executeAnnotate,
+	},
+	{
+		name:    taskQuery,
+		summary: "List the entries of a CSV matching a query.",
+		description: "Prints the entries of the given CSV that satisfy every condition of -where and whose checksum" +
+			" starts with -checksum. Conditions have the form <field><operator><value>. Fields: filename, checksum," +
+			" algorithm, created, creator, note, tag, size, modified and meta.<column>. Operators: = != ~ (contains)" +
+			" ^ (starts with) < <= > >=. Dates are compared on the length of the value, so created<2020 means" +
+			" \"created before 2020\". Exits with status 1 if no entry matches.",
+		options: []string{"inchk", "where", "checksum", "format", "verifykey"},
+		examples: []string{
+			"fmr query -inchk registry.csv -checksum 98ea6e4f",
+			"fmr query -inchk registry.csv -where \"tag=contract created<2020\"",
+			"fmr query -inchk registry.csv -where \"note~invoice size>1000000\" -format json",
+			"fmr query -inchk registry.csv -where meta.owner=alice -format \"{{.Checksum}}  {{.Filename}}\"",
+		},
+		verify:  (*Application).verifyQueryConfiguration,
+		execute: (*Application).executeQuery,
+	},
+	{
+		name:    taskMerge,
+		summary: "Merge several CSVs into one, removing duplicates.",
+		description: "Merges the given CSVs into one. Entries having the same path, algorithm and checksum are stored" +
+			" once. Entries having the same path and algorithm, but a different checksum are conflicts, they are" +
+			" reported and resolved according to -conflict. Exits with status 1 if nothing has been saved because" +
+			" of a conflict.",
+		options:   []string{"outchk", "conflict", "signkey", "verifykey", "metacols"},
+		arguments: "<input.csv>...",
+		examples: []string{
+			"fmr merge -outchk registry.csv desktop-2018.csv laptop-2019.csv nas-2020.csv",
+			"fmr merge -outchk registry.csv -conflict fail desktop.csv laptop.csv",
+		},
+		verify:  (*Application).verifyMergeConfiguration,
+		execute: (*Application).executeMerge,
+	},
+	{
+		name:    taskConvert,
+		summary: "Copy checksums between a CSV and the extended attributes of the files.",
+		description: "Writes the checksums listed in -inchk to the user.checksum.<algorithm> extended attributes of" +
+			" the files under -bp or, given -indir instead, collects the checksums stored in the extended attributes" +
+			" of the files under -indir into -outchk. The attributes travel with the files, so converting them back" +
+			" to a CSV after renaming or moving files lists the files under their new names. Linux only.",
+		options: []string{"inchk", "indir", "outchk", "bp", "filter", "signkey", "verifykey", "nfc", "ignorecase"},
+		examples: []string{
+			"fmr convert -inchk photos.csv -bp /mnt/archive",
+			"fmr convert -indir /mnt/archive/photos -bp /mnt/archive -outchk photos.csv",
+		},
+		verify:  (*Application).verifyConvertConfiguration,
+		execute: (*Application).executeConvert,
+	},
+	{
+		name:    taskKeygen,
+		summary: "Generate a key pair for signing checksum databases.",
+		description: "Generates an ed25519 key pair. The private key is saved to the path given by -signkey, the" +
+			" public key to the same path with a .pub extension. Pass the private key to the tasks writing a CSV" +
+			" (-signkey) and the public key to the tasks reading one (-verifykey).",
+		options: []string{"signkey"},
+		usages: map[string]string{
+			"signkey": "Path of the private key to generate.",
+		},
+		examples: []string{
+			"fmr keygen -signkey ~/.fmr/registry.key",
+			"fmr calculate -indir /mnt/archive/photos -outchk photos.csv -signkey ~/.fmr/registry.key",
+		},
+		verify:  (*Application).verifyKeygenConfiguration,
+		execute: (*Application).executeKeygen,
+	},
+}
+
+func findCommand(name string) *command {
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+func (cmd *command) acceptsOption(name string) bool {
+
+	for _, optionName := range cmd.options {
+		if optionName == name {
+			return true
+		}
+	}
+	for _, optionName := range globalOptions {
+		if optionName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cmd *command) createFlagSet(conf *configuration) *flag.FlagSet {
+
+	fs := flag.NewFlagSet("fmr "+cmd.name, flag.ExitOnError)
+	for _, name := range cmd.options {
+		registerOption(fs, conf, name, cmd.usages[name])
+	}
+	for _, name := range globalOptions {
+		registerOption(fs, conf, name, "")
+	}
+	fs.Usage = func() { cmd.printUsage(fs) }
+
+	return fs
+}
+
+func (cmd *command) printUsage(fs *flag.FlagSet) {
+
+	output := fs.Output()
+	usage := strings.TrimSpace("fmr " + cmd.name + " [options] " + cmd.arguments)
+	fmt.Fprintf(output, "Usage: %s\n\n%s\n\nOptions:\n", usage, cmd.description)
+	fs.PrintDefaults()
+	fmt.Fprintf(output, "\nExamples:\n")
+	for _, example := range cmd.examples {
+		fmt.Fprintf(output, "  %s\n", example)
+	}
+}
+
+func printGeneralUsage() {
+
+	output := os.Stderr
+	fmt.Fprintf(output, "Usage: fmr <command> [options]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(output, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(output, "\nRun \"fmr help <command>\" or \"fmr <command> -h\" for the options of a command.\n")
+	fmt.Fprintf(output, "The legacy form \"fmr -task <command> [options]\" is still accepted.\n")
+}
+
+func getCommandNames() string {
+
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func (app *Application) executeCalculate() {
+
+	readOptions := app.applyResourceOptions()
+	conf := app.config
+	db := app.withDryRun(app.createStore())
+	calculator := bll.NewCalculator(db, conf.inputDirectory, conf.algorithm, conf.basePath)
+	calculator.SetProgressListener(app.createProgressReport())
+	calculator.SetReadOptions(readOptions)
+	if !conf.dryRun {
+		calculator.SetCheckpointInterval(conf.checkpoint)
+	}
+	calculator.SetResume(conf.resume)
+	calculator.SetArchives(conf.archives)
+	calculator.SetBlockSize(conf.blockSizeBytes)
+	calculator.SetSymlinkPolicy(conf.symlinks)
+	calculator.SetCaptureAttributes(conf.attributes)
+	calculator.SetPathMatcher(app.createPathMatcher())
+	calculator.SetRecovery(conf.outputChecksum+common.RecoveryDirectorySuffix, conf.recovery)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; ok {
+			util.LogWarn("Interrupted, saving the checksums calculated so far.")
+			calculator.RequestStop()
+			signal.Stop(signals)
+		}
+	}()
+
+	if !calculator.Calculate(conf.missingOnly) {
+		app.exitCode = 1
+	}
+}
+
+func (app *Application) executeCompare() {
+
+	readOptions := app.applyResourceOptions()
+	conf := app.config
+	db := app.withDryRun(app.createDatabase())
+	comparer := bll.NewComparer(db, conf.inputDirectory, conf.basePath)
+	comparer.SetProgressListener(app.createProgressReport())
+	comparer.SetReadOptions(readOptions)
+	comparer.SetBlockSize(conf.blockSizeBytes)
+	comparer.SetSymlinkPolicy(conf.symlinks)
+	comparer.SetPathMatcher(app.createPathMatcher())
+	comparer.Compare(conf.algorithm)
+}
+
+func (app *Application) executeExport() {
+
+	conf := app.config
+	db := app.createDatabase()
+	exporter := bll.NewExporter(db, conf.outputDirectory, conf.basePath)
+	exporter.SetMtree(conf.mtree)
+	fpFilter := app.createFingerprintFilter()
+	exporter.Convert(fpFilter)
+}
+
+func (app *Application) executeImport() {
+
+	conf := app.config
+	db := app.withDryRun(app.createDatabase())
+	importer := bll.NewImporter(db, conf.inputDirectory, conf.outputChecksum)
+	importer.Convert()
+}
+
+func (app *Application) executeVerify() {
+
+	readOptions := app.applyResourceOptions()
+	conf := app.config
+	db := app.createStore()
+	verifier := bll.NewVerifier(db, conf.basePath)
+	verifier.SetProgressListener(app.createProgressReport())
+	verifier.SetReadOptions(readOptions)
+	verifier.SetRecoveryDirectory(conf.inputChecksum + common.RecoveryDirectorySuffix)
+	verifier.SetPathMatcher(app.createPathMatcher())
+	fpFilter := app.createFingerprintFilter()
+	verifier.Verify(conf.missingOnly, fpFilter)
+}
+
+func (app *Application) executeRepair() {
+
+	readOptions := app.applyResourceOptions()
+	conf := app.config
+	db := app.createDatabase()
+	repairer := bll.NewRepairer(db, conf.basePath, conf.inputChecksum+common.RecoveryDirectorySuffix)
+	repairer.SetReadOptions(readOptions)
+	repairer.SetReplicas(parseList(conf.replicas))
+	repairer.SetPathMatcher(app.createPathMatcher())
+	repairer.SetDryRun(conf.dryRun)
+	if !repairer.Repair(app.createFingerprintFilter()) {
+		app.exitCode = 1
+	}
+}
+
+func (app *Application) executeCrossCheck() {
+
+	readOptions := app.applyResourceOptions()
+	conf := app.config
+	db := app.createDatabase()
+	roots := append([]string{conf.basePath}, parseList(conf.replicas)...)
+	checker := bll.NewCrossChecker(db, roots, conf.minCopies)
+	checker.SetProgressListener(app.createProgressReport())
+	checker.SetReadOptions(readOptions)
+	checker.SetPathMatcher(app.createPathMatcher())
+	if !checker.CrossCheck(app.createFingerprintFilter()) {
+		app.exitCode = 1
+	}
+}
+
+func (app *Application) executeAnnotate() {
+
+	conf := app.config
+	db := app.withDryRun(app.createDatabase())
+	annotator := bll.NewAnnotator(db)
+
+	if conf.metadataFile != "" {
+		annotator.ImportMetadata(conf.metadataFile)
+		return
+	}
+
+	annotation := bll.Annotation{
+		Note:       conf.note,
+		AppendNote: conf.appendNote,
+		AddTags:    parseList(conf.tags),
+		RemoveTags: parseList(conf.untags),
+	}
+	fpFilter := app.createFingerprintFilter()
+	annotator.Annotate(annotation, fpFilter)
+}
+
+func (app *Application) executeQuery() {
+
+	conf := app.config
+	query, err := common.ParseQuery(conf.where)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Invalid query: %s.", err))
+	if conf.checksumPrefix != "" {
+		query.AddChecksumPrefix(conf.checksumPrefix)
+	}
+	queryReport, err := report.NewQueryReport(os.Stdout, conf.format)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Invalid format: %s.", err))
+
+	db := app.createDatabase()
+	querier := bll.NewQuerier(db, queryReport)
+	count, err := querier.Query(query)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot write the results: %s.", err))
+	if count == 0 {
+		app.exitCode = 1
+	}
+}
+
+func (app *Application) executeMerge() {
+
+	conf := app.config
+	sources := make([]dal.Database, len(conf.inputs))
+	for i, input := range conf.inputs {
+		sources[i] = app.createSourceDatabase(input)
+	}
+
+	db := app.withDryRun(app.createDatabase())
+	merger := bll.NewMerger(db, conf.conflictPolicy)
+	if !merger.Merge(sources) {
+		util.LogError("Nothing has been saved because of the conflicts.")
+		app.exitCode = 1
+	}
+}
+
+func (app *Application) executeConvert() {
+
+	conf := app.config
+	var converter bll.Converter
+	if conf.inputChecksum != "" {
+		converter = bll.NewConverter(
+			app.createDatabase(), app.withDryRun(dal.NewXattrDatabase(conf.basePath, conf.basePath)))
+	} else {
+		converter = bll.NewConverter(
+			dal.NewXattrDatabase(conf.inputDirectory, conf.basePath), app.withDryRun(app.createDatabase()))
+	}
+	converter.Convert(app.createFingerprintFilter())
+}
+
+func (app *Application) executeKeygen() {
+
+	err := dal.GenerateKeyPair(app.config.signingKey)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot generate key pair %s: %s.", app.config.signingKey, err))
+	util.LogInfo("Private key: " + app.config.signingKey)
+	util.LogInfo("Public key: " + app.config.signingKey + dal.PublicKeyExtension)
+}
+
+// createDatabase Creates the database of the checksums with the configured keys and metadata columns. The format of
+// each file is selected by its extension.
+func (app *Application) createDatabase() dal.FileDatabase {
+
+	conf := app.config
+	db := dal.NewFileDatabase(conf.inputChecksum, conf.outputChecksum, conf.outputNames)
+	app.setUpKeys(db)
+	if conf.metadataColumns != "" {
+		db.SetMetadataColumns(parseMetadataColumns(conf.metadataColumns))
+	}
+
+	return db
+}
+
+// createStore Creates the database holding the checksums of the task: the CSV files or the extended attributes of the
+// files under the input directory.
+func (app *Application) createStore() dal.Database {
+
+	conf := app.config
+	if conf.store == storeXattr {
+		return dal.NewXattrDatabase(conf.inputDirectory, conf.basePath)
+	}
+
+	return app.createDatabase()
+}
+
+// applyResourceOptions Lowers the priority of the process as configured. Returns how the files are read: the hashers of
+// the task share one limiter of the read rate.
+func (app *Application) applyResourceOptions() common.ReadOptions {
+
+	conf := app.config
+	readOptions := common.ReadOptions{DropPageCache: conf.dropCache}
+	if conf.maxRateBytes > 0 {
+		readOptions.Limiter = common.NewRateLimiter(conf.maxRateBytes)
+	}
+	if conf.ioIdle {
+		err := util.SetIdleIOPriority()
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot set the I/O priority: %s.", err))
+	}
+	if conf.nice != 0 {
+		err := util.SetNiceness(conf.nice)
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot set the niceness: %s.", err))
+	}
+
+	return readOptions
+}
+
+// withDryRun Wraps the given database in dry-run mode, so that what would be saved is printed instead.
+func (app *Application) withDryRun(db dal.Database) dal.Database {
+
+	if !app.config.dryRun {
+		return db
+	}
+
+	return dal.NewDryRunDatabase(db, app.config.outputChecksum, app.config.outputNames, os.Stdout)
+}
+
+// createPathMatcher Creates the matcher of the stored filenames with the configured normalization and case folding.
+func (app *Application) createPathMatcher() util.PathMatcher {
+
+	return util.NewPathMatcher(app.config.normalizeNames, app.config.ignoreCase)
+}
+
+// createFingerprintFilter Creates the filter of the entries to process from the -filter expression.
+func (app *Application) createFingerprintFilter() common.FingerprintFilter {
+
+	fpFilter := common.NewFingerprintFilter(app.config.filter)
+	fpFilter.SetPathMatcher(app.createPathMatcher())
+
+	return fpFilter
+}
+
+// createSourceDatabase Creates a read-only database for the given input, checking its signature if requested.
+func (app *Application) createSourceDatabase(inputPath string) dal.FileDatabase {
+
+	db := dal.NewFileDatabase(inputPath, "", "")
+	app.setUpKeys(db)
+
+	return db
+}
+
+// setUpKeys Sets up signing and signature verification if requested.
+func (app *Application) setUpKeys(db dal.FileDatabase) {
+
+	conf := app.config
+	if conf.signingKey != "" {
+		key, err := dal.LoadPrivateKey(conf.signingKey)
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot load private key %s: %s.", conf.signingKey, err))
+		db.SetSigningKey(key)
+	}
+	if conf.verificationKey != "" {
+		key, err := dal.LoadPublicKey(conf.verificationKey)
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot load public key %s: %s.", conf.verificationKey, err))
+		db.SetVerificationKey(key)
+	}
+}
+
+// parseMetadataColumns Splits the comma separated list of custom metadata columns. Stops if a column clashes with a
+// standard one.
+func parseMetadataColumns(text string) []string {
+
+	columns := parseList(text)
+	for _, column := range columns {
+		if dal.IsStandardColumn(column) {
+			util.LogFatal(fmt.Sprintf("The metadata column %s clashes with a standard column.", column))
+		}
+	}
+
+	return columns
+}
+
+// parseByteSize Parses a number of bytes with an optional K, M or G suffix (powers of 1024).
+func parseByteSize(text string) (int64, error) {
+
+	multiplier := int64(1)
+	switch strings.ToUpper(text[len(text)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		text = text[:len(text)-1]
+	}
+
+	size, err := strconv.ParseInt(text, 10, 64)
+
+	return size * multiplier, err
+}
+
+// parseRate Parses a number of bytes per second, e.g. 50MB/s, 512K/s or 1G. The "B" and "/s" suffixes are optional.
+func parseRate(text string) (int64, error) {
+
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "/s"), "B")
+	if text == "" {
+		return 0, errors.New("empty rate")
+	}
+
+	return parseByteSize(text)
+}
+
+// parseList Splits a comma separated list, dropping surrounding whitespace and empty items.
+func parseList(text string) []string {
+
+	items := make([]string, 0)
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// createProgressReport Creates a progress display that redraws its status line on the standard error output if it is a
+// terminal, or writes it to the log periodically otherwise. The status line is not drawn in quiet mode or between JSON
+// entries written to the standard error output.
+func (app *Application) createProgressReport() *report.ProgressReport {
+
+	conf := app.config
+	jsonOnTerminal := conf.logFormat == util.LogFormatJSON && conf.logPath == ""
+	if util.IsTerminal(os.Stderr) && !conf.quiet && !jsonOnTerminal {
+		return report.NewProgressReport(true, os.Stderr, 200*time.Millisecond)
+	}
+
+	return report.NewProgressReport(false, nil, 30*time.Second)
+}
+
+func (app *Application) verifyCalculateConfiguration() {
+
+	app.stopIfInputDirectoryDoesNotExist()
+	app.parseBlockSize()
+	app.parseResourceOptions()
+	app.stopIfSymlinkPolicyIsInvalid()
+	if app.config.recovery < 0 || app.config.recovery > 100 {
+		util.LogFatal("The percent of recovery data must be between 1 and 100.")
+	}
+	if app.config.recovery > 0 && app.config.dryRun {
+		util.LogFatal("Recovery data cannot be created in dry-run mode.")
+	}
+	if app.stopIfStoreIsInvalid() == storeXattr {
+		app.verifyXattrStoreConfiguration()
+		return
+	}
+	if app.config.missingOnly {
+		app.stopIfInputChecksumDoesNotExist()
+	} else {
+		app.config.inputChecksum = ""
+	}
+
+	if app.config.resume {
+		if app.config.missingOnly {
+			util.LogFatal("The -resume and -missingonly options cannot be used together.")
+		}
+		if util.CheckIfFileExists(app.config.outputChecksum) {
+			app.config.inputChecksum = app.config.outputChecksum
+		} else {
+			util.LogWarn("Output file does not exist, nothing to resume.")
+			app.config.resume = false
+		}
+	}
+}
+
+func (app *Application) verifyCompareConfiguration() {
+
+	app.stopIfInputChecksumDoesNotExist()
+	app.stopIfInputDirectoryDoesNotExist()
+	app.parseBlockSize()
+	app.parseResourceOptions()
+	app.stopIfSymlinkPolicyIsInvalid()
+}
+
+// stopIfDryRun Stops the tasks that write files other than the databases, which cannot be simulated.
+func (app *Application) stopIfDryRun() {
+
+	if app.config.dryRun {
+		util.LogFatal("The " + app.config.task + " task does not support -dry-run.")
+	}
+}
+
+func (app *Application) stopIfSymlinkPolicyIsInvalid() {
+
+	if !util.IsSymlinkPolicy(app.config.symlinks) {
+		util.LogFatal("Invalid symlink policy: " + app.config.symlinks + ".")
+	}
+}
+
+func (app *Application) parseBlockSize() {
+
+	if app.config.blockSize == "" {
+		return
+	}
+
+	blockSize, err := parseByteSize(app.config.blockSize)
+	if err != nil || blockSize <= 0 {
+		util.LogFatal("Invalid block size: " + app.config.blockSize + ".")
+	}
+	app.config.blockSizeBytes = blockSize
+}
+
+// parseResourceOptions Checks the options limiting the disk and CPU usage of the tasks that hash files.
+func (app *Application) parseResourceOptions() {
+
+	conf := &app.config
+	if conf.maxRate != "" {
+		maxRate, err := parseRate(conf.maxRate)
+		if err != nil || maxRate <= 0 {
+			util.LogFatal("Invalid maximum rate: " + conf.maxRate + ".")
+		}
+		conf.maxRateBytes = maxRate
+	}
+	if conf.nice < -20 || conf.nice > 19 {
+		util.LogFatal("The niceness must be between -20 and 19.")
+	}
+	if (conf.nice != 0 || conf.ioIdle) && !util.PrioritySupported {
+		util.LogFatal("The priority of the process cannot be changed on this platform.")
+	}
+}
+
+func (app *Application) verifyExportConfiguration() {
+
+	app.stopIfDryRun()
+	app.stopIfInputChecksumDoesNotExist()
+	app.stopIfOutputDirectoryDoesNotExist()
+}
+
+func (app *Application) verifyImportConfiguration() {
+
+	app.stopIfInputDirectoryDoesNotExist()
+}
+
+func (app *Application) verifyVerifyConfiguration() {
+
+	app.parseResourceOptions()
+	if app.stopIfStoreIsInvalid() == storeXattr {
+		app.stopIfInputDirectoryDoesNotExist()
+	} else {
+		app.stopIfInputChecksumDoesNotExist()
+	}
+}
+
+func (app *Application) verifyConvertConfiguration() {
+
+	conf := app.config
+	if !util.XattrsSupported {
+		util.LogFatal("Extended attributes are not supported on this platform.")
+	}
+	if (conf.inputChecksum == "") == (conf.inputDirectory == "") {
+		util.LogFatal("Either the input CSV (-inchk) or the input directory (-indir) is required.")
+	}
+	if conf.inputChecksum != "" {
+		app.stopIfInputChecksumDoesNotExist()
+		if conf.basePath == "" || !util.CheckIfDirectoryExists(conf.basePath) {
+			util.LogFatal("Base path " + conf.basePath + " does not exist.")
+		}
+	} else {
+		app.stopIfInputDirectoryDoesNotExist()
+		if conf.outputChecksum == "" {
+			util.LogFatal("The path of the output CSV (-outchk) is required.")
+		}
+	}
+}
+
+// stopIfStoreIsInvalid Checks the -store option and returns its value.
+func (app *Application) stopIfStoreIsInvalid() string {
+
+	store := app.config.store
+	if store != storeCsv && store != storeXattr {
+		util.LogFatal("Invalid store: " + store + ".")
+	}
+	if store == storeXattr && !util.XattrsSupported {
+		util.LogFatal("Extended attributes are not supported on this platform.")
+	}
+
+	return store
+}
+
+// verifyXattrStoreConfiguration Checks that the calculation only stores what fits in the extended attributes.
+func (app *Application) verifyXattrStoreConfiguration() {
+
+	conf := app.config
+	if conf.blockSizeBytes > 0 || conf.recovery > 0 || conf.archives {
+		util.LogFatal("Block hashes, recovery data and archive members cannot be stored in extended attributes.")
+	}
+	if conf.resume && conf.missingOnly {
+		util.LogFatal("The -resume and -missingonly options cannot be used together.")
+	}
+}
+
+func (app *Application) verifyRepairConfiguration() {
+
+	app.stopIfInputChecksumDoesNotExist()
+	app.parseResourceOptions()
+	for _, replica := range parseList(app.config.replicas) {
+		if !util.CheckIfDirectoryExists(replica) {
+			util.LogFatal("Replica root does not exist: " + replica + ".")
+		}
+	}
+}
+
+func (app *Application) verifyCrossCheckConfiguration() {
+
+	conf := &app.config
+	app.verifyRepairConfiguration()
+	copies := len(parseList(conf.replicas)) + 1
+	if copies < 2 {
+		util.LogFatal("At least one replica is required.")
+	}
+	if conf.minCopies == 0 {
+		conf.minCopies = copies
+	} else if conf.minCopies < 0 || conf.minCopies > copies {
+		util.LogFatal(fmt.Sprintf("The number of copies must be between 1 and %d.", copies))
+	}
+}
+
+func (app *Application) verifyAnnotateConfiguration() {
+
+	conf := &app.config
+	app.stopIfInputChecksumDoesNotExist()
+	if conf.outputChecksum == "" {
+		conf.outputChecksum = conf.inputChecksum
+	}
+
+	editsEntries := conf.note != "" || conf.tags != "" || conf.untags != ""
+	if conf.metadataFile != "" {
+		if editsEntries {
+			util.LogFatal("The -metafile option cannot be combined with -note, -tags and -untags.")
+		}
+		if !util.CheckIfFileExists(conf.metadataFile) {
+			util.LogFatal("Metadata file does not exist.")
+		}
+	} else if !editsEntries {
+		util.LogFatal("Nothing to do: -note, -tags, -untags or -metafile is required.")
+	}
+}
+
+func (app *Application) verifyQueryConfiguration() {
+
+	app.stopIfInputChecksumDoesNotExist()
+	if strings.Trim(app.config.checksumPrefix, "0123456789abcdefABCDEF") != "" {
+		util.LogFatal("The checksum prefix must be hexadecimal.")
+	}
+}
+
+func (app *Application) verifyMergeConfiguration() {
+
+	conf := app.config
+	if len(conf.inputs) == 0 {
+		util.LogFatal("At least one input CSV is required.")
+	}
+	for _, input := range conf.inputs {
+		if !util.CheckIfFileExists(input) {
+			util.LogFatal("Input file does not exist: " + input + ".")
+		}
+	}
+	if conf.outputChecksum == "" {
+		util.LogFatal("The path of the output CSV (-outchk) is required.")
+	}
+	if conf.conflictPolicy != bll.MergeNewest && conf.conflictPolicy != bll.MergeKeepBoth &&
+		conf.conflictPolicy != bll.MergeFail {
+		util.LogFatal("Unknown conflict policy: " + conf.conflictPolicy + ".")
+	}
+}
+
+func (app *Application) verifyKeygenConfiguration() {
+
+	app.stopIfDryRun()
+	if app.config.signingKey == "" {
+		util.LogFatal("The path of the private key (-signkey) is required.")
+	}
+}
@@ -0,0 +1,380 @@
+package application
+
+import (
+	"flag"
+)
+
+// option Describes a command line option and binds it to a field of the configuration.
+type option struct {
+	name     string
+	usage    string
+	register func(fs *flag.FlagSet, conf *configuration, name string, usage string)
+}
+
+// globalOptions Lists the options accepted by every command.
+var globalOptions = []string{"config", "dry-run", "log", "log-format", "profile", "quiet", "verbose"}
+
+var options = []option{
+	{
+		"alg",
+		"The algorithm used to calculate new checksums: crc32, md5, sha1, sha256 or sha512.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.algorithm, name, conf.algorithm, usage)
+		},
+	},
+	{
+		"append",
+		"Append the note to the existing one instead of replacing it.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.appendNote, name, conf.appendNote, usage)
+		},
+	},
+	{
+		"archives",
+		"Also fingerprint the files stored in ZIP and TAR (.tar, .tar.gz, .tgz) archives, named <archive>!/<member>.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.archives, name, conf.archives, usage)
+		},
+	},
+	{
+		"attributes",
+		"Also store the permissions, ownership and extended attributes of the files. Verification reports changed" +
+			" attributes separately from corrupt content.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.attributes, name, conf.attributes, usage)
+		},
+	},
+	{
+		"blocksize",
+		"Also hash the files in blocks of the given size (e.g. 4M, 64K) to locate corruption within large files. The" +
+			" block hashes are saved next to the output (<output>.blocks).",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.blockSize, name, conf.blockSize, usage)
+		},
+	},
+	{
+		"bp",
+		"The first part of the path that will not be stored in the output.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.basePath, name, conf.basePath, usage)
+		},
+	},
+	{
+		"checkpoint",
+		"How often the checksums calculated so far are saved to the output (e.g. 30s, 10m). 0 disables checkpoints.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.DurationVar(&conf.checkpoint, name, conf.checkpoint, usage)
+		},
+	},
+	{
+		"checksum",
+		"Only list the entries whose checksum starts with the given hexadecimal prefix.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.checksumPrefix, name, conf.checksumPrefix, usage)
+		},
+	},
+	{
+		"config",
+		"Path of the TOML configuration file containing named profiles.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.configPath, name, conf.configPath, usage)
+		},
+	},
+	{
+		"conflict",
+		"How to resolve entries having the same path and algorithm, but a different checksum: newest (keep the one" +
+			" created later), keepboth or fail.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.conflictPolicy, name, conf.conflictPolicy, usage)
+		},
+	},
+	{
+		"drop-cache",
+		"Advise the kernel to drop each file from the page cache once it is hashed, so that the run does not" +
+			" evict the data cached for other processes (Linux only).",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.dropCache, name, conf.dropCache, usage)
+		},
+	},
+	{
+		"dry-run",
+		"Run the task without saving anything: print what would be written to the standard output instead, as a diff" +
+			" against the existing output file.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.dryRun, name, conf.dryRun, usage)
+		},
+	},
+	{
+		"filter",
+		"A string in \"filename:algorithm\" format that the filenames/algorithms of the processed entries must match." +
+			" Both parts are optional.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.filter, name, conf.filter, usage)
+		},
+	},
+	{
+		"format",
+		"Output format: table, csv, json or a Go template executed for each entry (e.g. \"{{.Checksum}} {{.Filename}}\").",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.format, name, conf.format, usage)
+		},
+	},
+	{
+		"ignorecase",
+		"Match the stored filenames with the files on disk and the -filter expression ignoring case, for archives" +
+			" copied to or from case-insensitive filesystems.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.ignoreCase, name, conf.ignoreCase, usage)
+		},
+	},
+	{
+		"inchk",
+		"The name of the input CSV containing checksums.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.inputChecksum, name, conf.inputChecksum, usage)
+		},
+	},
+	{
+		"indir",
+		"The source directory for which the checksums will be calculated (or will be compared).",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.inputDirectory, name, conf.inputDirectory, usage)
+		},
+	},
+	{
+		"io-idle",
+		"Set the I/O priority of the process to idle, so that it only reads from the disks when no other" +
+			" process needs them (Linux only).",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.ioIdle, name, conf.ioIdle, usage)
+		},
+	},
+	{
+		"log",
+		"Path of the log file. Optional, by default the program will print log messages to the standard error output.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.logPath, name, conf.logPath, usage)
+		},
+	},
+	{
+		"log-format",
+		"The format of the log: text or json (one object per line with the level, the message and fields such as" +
+			" file, algorithm, outcome and duration).",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.logFormat, name, conf.logFormat, usage)
+		},
+	},
+	{
+		"max-rate",
+		"The maximum rate at which the files are read for hashing, e.g. 50MB/s or 512K/s (powers of 1024)." +
+			" Optional, by default the rate is not limited.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.maxRate, name, conf.maxRate, usage)
+		},
+	},
+	{
+		"metacols",
+		"Comma separated list of custom metadata columns (e.g. project,owner,retention) to add to the output.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.metadataColumns, name, conf.metadataColumns, usage)
+		},
+	},
+	{
+		"metafile",
+		"A CSV with a header row containing notes, tags and metadata to set, keyed by a filename or checksum column.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.metadataFile, name, conf.metadataFile, usage)
+		},
+	},
+	{
+		"mincopies",
+		"The number of good copies each entry needs. Entries with fewer are flagged. Optional, by default every copy" +
+			" must be good.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.IntVar(&conf.minCopies, name, conf.minCopies, usage)
+		},
+	},
+	{
+		"missingonly",
+		"Calculate checksums only for those files that do not have a checksum stored yet.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.missingOnly, name, conf.missingOnly, usage)
+		},
+	},
+	{
+		"mtree",
+		"Export an mtree specification (Checksum.mtree) instead of the checksum files. The paths are relative to the" +
+			" base path of the CSV, -bp is not added.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.mtree, name, conf.mtree, usage)
+		},
+	},
+	{
+		"nfc",
+		"Match the stored filenames with the files on disk and the -filter expression after Unicode NFC" +
+			" normalization, for archives copied between macOS (decomposed names) and other systems.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.normalizeNames, name, conf.normalizeNames, usage)
+		},
+	},
+	{
+		"nice",
+		"The CPU niceness of the process, from -20 (highest priority) to 19 (lowest priority) (Linux only)." +
+			" Optional, 0 (the default value) leaves the niceness unchanged.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.IntVar(&conf.nice, name, conf.nice, usage)
+		},
+	},
+	{
+		"note",
+		"The note to set on the matching entries.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.note, name, conf.note, usage)
+		},
+	},
+	{
+		"outchk",
+		"The name of the output CSV file containing checksums.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.outputChecksum, name, conf.outputChecksum, usage)
+		},
+	},
+	{
+		"outdir",
+		"The name of the directory containing exported files.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.outputDirectory, name, conf.outputDirectory, usage)
+		},
+	},
+	{
+		"outnames",
+		"The name of the output containing new file name and old filename pairs.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.outputNames, name, conf.outputNames, usage)
+		},
+	},
+	{
+		"profile",
+		"The name of the profile to load from the configuration file. Options given on the command line override the" +
+			" values of the profile.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.profile, name, conf.profile, usage)
+		},
+	},
+	{
+		"quiet",
+		"Only log warnings and errors.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.quiet, name, conf.quiet, usage)
+		},
+	},
+	{
+		"recovery",
+		"Create Reed-Solomon recovery data with the given percent of redundancy (1-100) for each file hashed, so" +
+			" that damaged files can be repaired. The recovery files are stored next to the output (<output>.recovery).",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.IntVar(&conf.recovery, name, conf.recovery, usage)
+		},
+	},
+	{
+		"replicas",
+		"Comma separated list of replica roots, each holding a copy of the files under the base path. Damaged or" +
+			" missing files are restored from a copy with the same checksum, at the same relative path or elsewhere" +
+			" on the replica.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.replicas, name, conf.replicas, usage)
+		},
+	},
+	{
+		"resume",
+		"Continue an interrupted calculation: the files stored in the output with the same size and modification time" +
+			" are not hashed again.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.resume, name, conf.resume, usage)
+		},
+	},
+	{
+		"signkey",
+		"Path of the ed25519 private key used to sign the output. A detached signature (<output>.sig) is written next" +
+			" to the output.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.signingKey, name, conf.signingKey, usage)
+		},
+	},
+	{
+		"store",
+		"Where the checksums are kept: csv (the -inchk and -outchk files) or xattr (the user.checksum.<algorithm>" +
+			" extended attributes of the files under -indir, Linux only).",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.store, name, conf.store, usage)
+		},
+	},
+	{
+		"symlinks",
+		"How to handle symbolic links: follow (hash the files they point to, including linked directories), record" +
+			" (store the link target instead of hashing) or skip. Loops are detected; sockets, named pipes and" +
+			" devices are always skipped.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.symlinks, name, conf.symlinks, usage)
+		},
+	},
+	{
+		"tags",
+		"Comma separated list of tags to add to the matching entries.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.tags, name, conf.tags, usage)
+		},
+	},
+	{
+		"untags",
+		"Comma separated list of tags to remove from the matching entries.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.untags, name, conf.untags, usage)
+		},
+	},
+	{
+		"verbose",
+		"Also log debug messages, e.g. each valid file.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.BoolVar(&conf.verbose, name, conf.verbose, usage)
+		},
+	},
+	{
+		"verifykey",
+		"Path of the ed25519 public key used to check the signature (<input>.sig) of the input before it is loaded.",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.verificationKey, name, conf.verificationKey, usage)
+		},
+	},
+	{
+		"where",
+		"Whitespace separated conditions the listed entries must satisfy, e.g. \"tag=contract created<2020\".",
+		func(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+			fs.StringVar(&conf.where, name, conf.where, usage)
+		},
+	},
+}
+
+func findOption(name string) *option {
+
+	for i := range options {
+		if options[i].name == name {
+			return &options[i]
+		}
+	}
+
+	return nil
+}
+
+func registerOption(fs *flag.FlagSet, conf *configuration, name string, usage string) {
+
+	opt := findOption(name)
+	if opt == nil {
+		panic("Unknown option: " + name + ".")
+	}
+	if usage == "" {
+		usage = opt.usage
+	}
+
+	opt.register(fs, conf, name, usage)
+}
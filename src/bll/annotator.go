@@ -0,0 +1,232 @@
+package bll
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmr/bll/common"
+	"fmr/bll/report"
+	"fmr/dal"
+	"fmr/util"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// NoteSeparator Separates the appended text from the existing note.
+const NoteSeparator = "; "
+
+// Annotation Describes the changes to make on the notes and tags of the entries.
+type Annotation struct {
+	Note       string
+	AppendNote bool
+	AddTags    []string
+	RemoveTags []string
+}
+
+// Annotator Edits the notes, tags and metadata of stored entries.
+type Annotator struct {
+	Db     dal.Database
+	Report *report.AnnotationReport
+}
+
+// NewAnnotator Instantiates a new Annotator object.
+func NewAnnotator(db dal.Database) Annotator {
+
+	report := report.NewAnnotationReport()
+
+	return Annotator{db, report}
+}
+
+// Annotate Applies the given annotation to the entries matching the filter and saves the database.
+func (annotator *Annotator) Annotate(annotation Annotation, fpFilter common.FingerprintFilter) {
+
+	annotator.Db.LoadFingerprints()
+
+	for element := annotator.Db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if fpFilter.FilterFingerprint(fingerprint) && applyAnnotation(fingerprint, annotation) {
+			annotator.Report.AddUpdatedEntry(fingerprint.Filename)
+		}
+	}
+
+	annotator.Db.SaveFingerprints()
+	annotator.Report.LogSummary()
+}
+
+// ImportMetadata Sets the notes, tags and metadata stored in the given CSV and saves the database. The first row of the
+// CSV is a header, the entries are identified by its filename or checksum column. The note and tags columns replace the
+// note and tags of the entry, the other standard columns are ignored and any other column is stored as metadata. Empty
+// values leave the entry unchanged.
+func (annotator *Annotator) ImportMetadata(metadataPath string) {
+
+	annotator.Db.LoadFingerprints()
+	index := annotator.buildIndex()
+
+	file, err := os.Open(metadataPath)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot read file %s.", metadataPath))
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot read the header of %s.", metadataPath))
+	keyColumn := getMetadataKeyColumn(header)
+	if keyColumn < 0 {
+		util.CheckErrDontPanic(
+			fmt.Errorf("missing key column"),
+			fmt.Sprintf("The header of %s must contain a filename or checksum column.", metadataPath))
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot parse %s: %s.", metadataPath, err))
+		annotator.importRecord(header, keyColumn, record, index)
+	}
+
+	annotator.Db.SaveFingerprints()
+	annotator.Report.LogSummary()
+}
+
+// buildIndex Maps the filenames and checksums to the entries having them.
+func (annotator *Annotator) buildIndex() map[string][]*dal.Fingerprint {
+
+	index := make(map[string][]*dal.Fingerprint)
+
+	for element := annotator.Db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		filenameKey := dal.ColumnFilename + ":" + fingerprint.Filename
+		checksumKey := dal.ColumnChecksum + ":" + hex.EncodeToString(fingerprint.Checksum)
+		index[filenameKey] = append(index[filenameKey], fingerprint)
+		index[checksumKey] = append(index[checksumKey], fingerprint)
+	}
+
+	return index
+}
+
+func (annotator *Annotator) importRecord(
+	header []string, keyColumn int, record []string, index map[string][]*dal.Fingerprint) {
+
+	key := record[keyColumn]
+	if header[keyColumn] == dal.ColumnChecksum {
+		key = strings.ToLower(key)
+	}
+
+	fingerprints := index[header[keyColumn]+":"+key]
+	if len(fingerprints) == 0 {
+		annotator.Report.AddUnmatchedKey(key)
+		return
+	}
+
+	for _, fingerprint := range fingerprints {
+		if applyMetadataRecord(fingerprint, header, keyColumn, record) {
+			annotator.Report.AddUpdatedEntry(fingerprint.Filename)
+		}
+	}
+}
+
+// getMetadataKeyColumn Returns the index of the column identifying the entries, -1 if there is none. The filename is
+// preferred over the checksum.
+func getMetadataKeyColumn(header []string) int {
+
+	checksumColumn := -1
+	for index, column := range header {
+		if column == dal.ColumnFilename {
+			return index
+		}
+		if column == dal.ColumnChecksum {
+			checksumColumn = index
+		}
+	}
+
+	return checksumColumn
+}
+
+func applyAnnotation(fingerprint *dal.Fingerprint, annotation Annotation) bool {
+
+	changed := false
+
+	if annotation.Note != "" {
+		note := annotation.Note
+		if annotation.AppendNote && fingerprint.Note != "" {
+			note = fingerprint.Note + NoteSeparator + annotation.Note
+		}
+		changed = changed || note != fingerprint.Note
+		fingerprint.Note = note
+	}
+
+	for _, tag := range annotation.AddTags {
+		if !containsTag(fingerprint.Tags, tag) {
+			fingerprint.Tags = append(fingerprint.Tags, tag)
+			changed = true
+		}
+	}
+
+	for _, tag := range annotation.RemoveTags {
+		if containsTag(fingerprint.Tags, tag) {
+			fingerprint.Tags = removeTag(fingerprint.Tags, tag)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func applyMetadataRecord(fingerprint *dal.Fingerprint, header []string, keyColumn int, record []string) bool {
+
+	changed := false
+
+	for index, value := range record {
+		if index == keyColumn || value == "" {
+			continue
+		}
+
+		switch column := header[index]; column {
+		case dal.ColumnNote:
+			changed = changed || fingerprint.Note != value
+			fingerprint.Note = value
+		case dal.ColumnTags:
+			tags := dal.ParseTags(value)
+			changed = changed || strings.Join(tags, dal.TagSeparator) != strings.Join(fingerprint.Tags, dal.TagSeparator)
+			fingerprint.Tags = tags
+		case dal.ColumnFilename, dal.ColumnChecksum:
+			// Only one of them is used as key, the other one is just for information.
+		default:
+			if dal.IsStandardColumn(column) {
+				continue
+			}
+			if fingerprint.Metadata == nil {
+				fingerprint.Metadata = make(map[string]string)
+			}
+			changed = changed || fingerprint.Metadata[column] != value
+			fingerprint.Metadata[column] = value
+		}
+	}
+
+	return changed
+}
+
+func containsTag(tags []string, tag string) bool {
+
+	for _, item := range tags {
+		if item == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeTag(tags []string, tag string) []string {
+
+	result := make([]string, 0, len(tags))
+	for _, item := range tags {
+		if item != tag {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
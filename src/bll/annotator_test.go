@@ -0,0 +1,154 @@
+package bll
+
+import (
+	"fmr/bll/common"
+	"fmr/bll/testutil"
+	"fmr/dal"
+	"testing"
+)
+
+func TestAnnotator(t *testing.T) {
+
+	setupAnnotatorTests()
+
+	t.Run("Annotate", testAnnotatorAnnotate)
+	t.Run("Annotate_AppendNote", testAnnotatorAnnotateAppendNote)
+	t.Run("Annotate_RemoveTags", testAnnotatorAnnotateRemoveTags)
+	t.Run("ImportMetadata_ByFilename", testAnnotatorImportMetadataByFilename)
+	t.Run("ImportMetadata_ByChecksum", testAnnotatorImportMetadataByChecksum)
+
+	tearDownAnnotatorTests()
+}
+
+func setupAnnotatorTests() {
+
+	testHelper.CreateTestRootDirectory()
+
+	testHelper.CreateTestFileWithContent(
+		"byfilename.csv",
+		"filename,note,tags,project\n"+
+			"photos/beach.jpg,Holiday,raw;2019,apollo\n"+
+			"photos/unknown.jpg,Lost,,\n")
+	testHelper.CreateTestFileWithContent(
+		"bychecksum.csv",
+		"checksum,owner\n"+
+			"6B24CC6A,alice\n")
+}
+
+func testAnnotatorAnnotate(t *testing.T) {
+
+	// Arrange.
+	memoryDatabase := createDatabaseForAnnotation()
+	annotator := NewAnnotator(memoryDatabase)
+	annotation := Annotation{Note: "Backed up", AddTags: []string{"archived"}}
+	fpFilter := common.NewFingerprintFilter("photos/")
+
+	// Act.
+	annotator.Annotate(annotation, fpFilter)
+
+	// Assert.
+	beach := findFingerprint(memoryDatabase, "photos/beach.jpg")
+	notes := findFingerprint(memoryDatabase, "notes.txt")
+	if beach.Note != "Backed up" || !containsTag(beach.Tags, "archived") {
+		t.Errorf("The matching entry is not annotated: %s, %v.", beach.Note, beach.Tags)
+	}
+	if notes.Note != "Draft" || len(notes.Tags) != 0 {
+		t.Errorf("The entry not matching the filter is annotated: %s, %v.", notes.Note, notes.Tags)
+	}
+	if annotator.Report.CountUpdated != 2 {
+		t.Errorf("Wrong number of updated entries: %d.", annotator.Report.CountUpdated)
+	}
+}
+
+func testAnnotatorAnnotateAppendNote(t *testing.T) {
+
+	memoryDatabase := createDatabaseForAnnotation()
+	annotator := NewAnnotator(memoryDatabase)
+	annotation := Annotation{Note: "Reviewed", AppendNote: true}
+
+	annotator.Annotate(annotation, common.NewFingerprintFilter("notes.txt"))
+
+	notes := findFingerprint(memoryDatabase, "notes.txt")
+	if notes.Note != "Draft; Reviewed" {
+		t.Errorf("Wrong note: %s.", notes.Note)
+	}
+}
+
+func testAnnotatorAnnotateRemoveTags(t *testing.T) {
+
+	memoryDatabase := createDatabaseForAnnotation()
+	findFingerprint(memoryDatabase, "notes.txt").Tags = []string{"draft", "work"}
+	annotator := NewAnnotator(memoryDatabase)
+	annotation := Annotation{RemoveTags: []string{"draft"}}
+
+	annotator.Annotate(annotation, common.NewFingerprintFilter(""))
+
+	notes := findFingerprint(memoryDatabase, "notes.txt")
+	if len(notes.Tags) != 1 || notes.Tags[0] != "work" {
+		t.Errorf("Wrong tags: %v.", notes.Tags)
+	}
+	if annotator.Report.CountUpdated != 1 {
+		t.Errorf("Wrong number of updated entries: %d.", annotator.Report.CountUpdated)
+	}
+}
+
+func testAnnotatorImportMetadataByFilename(t *testing.T) {
+
+	memoryDatabase := createDatabaseForAnnotation()
+	annotator := NewAnnotator(memoryDatabase)
+
+	annotator.ImportMetadata(testHelper.GetTestPath("byfilename.csv"))
+
+	beach := findFingerprint(memoryDatabase, "photos/beach.jpg")
+	if beach.Note != "Holiday" || len(beach.Tags) != 2 || beach.Metadata["project"] != "apollo" {
+		t.Errorf("The metadata is not imported: %s, %v, %v.", beach.Note, beach.Tags, beach.Metadata)
+	}
+	if !testHelper.HasStringItems(annotator.Report.UnmatchedKeys, "photos/unknown.jpg") {
+		t.Error("Key should be reported as not found: \"photos/unknown.jpg\".")
+	}
+}
+
+func testAnnotatorImportMetadataByChecksum(t *testing.T) {
+
+	memoryDatabase := createDatabaseForAnnotation()
+	annotator := NewAnnotator(memoryDatabase)
+
+	annotator.ImportMetadata(testHelper.GetTestPath("bychecksum.csv"))
+
+	sunset := findFingerprint(memoryDatabase, "photos/sunset.jpg")
+	notes := findFingerprint(memoryDatabase, "notes.txt")
+	if sunset.Metadata["owner"] != "alice" || notes.Metadata["owner"] != "alice" {
+		t.Error("The metadata should be imported for each entry having the checksum.")
+	}
+}
+
+func tearDownAnnotatorTests() {
+
+	testHelper.CleanUp()
+}
+
+func createDatabaseForAnnotation() *dal.MemoryDatabase {
+
+	fp1 := testutil.CreateSparseFingerprint("photos/beach.jpg", "1c291ca3", "crc32")
+	fp2 := testutil.CreateSparseFingerprint("photos/sunset.jpg", "6b24cc6a", "crc32")
+	fp3 := testutil.CreateFingerprint("notes.txt", "6b24cc6a", "crc32", "", "", "Draft")
+
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprint(fp1)
+	memoryDatabase.AddFingerprint(fp2)
+	memoryDatabase.AddFingerprint(fp3)
+
+	return memoryDatabase
+}
+
+func findFingerprint(db dal.Database, filename string) *dal.Fingerprint {
+
+	for element := db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if fingerprint.Filename == filename {
+			return fingerprint
+		}
+	}
+
+	return nil
+}
@@ -5,16 +5,33 @@ import (
 	"fmr/bll/common"
 	"fmr/dal"
 	"fmr/util"
+	"fmt"
+	"os"
 	"path"
+	"sync/atomic"
+	"time"
 )
 
 // Calculator Stores settings related to checksum calculation.
 type Calculator struct {
-	Db                dal.Database
-	InputDirectory    string
-	BasePath          string
-	hasher            common.Hasher
-	effectiveBasePath string
+	Db                 dal.Database
+	InputDirectory     string
+	BasePath           string
+	hasher             common.Hasher
+	effectiveBasePath  string
+	progress           common.ProgressListener
+	checkpointInterval time.Duration
+	resume             bool
+	archives           bool
+	blockSize          int64
+	recoveryDirectory  string
+	recoveryPercent    int
+	symlinks           string
+	attributes         bool
+	matcher            util.PathMatcher
+	stopRequested      int32
+	corruptArchives    int
+	readOptions        common.ReadOptions
 }
 
 // NewCalculator Instantiates a new Calculator object.
@@ -23,55 +40,308 @@ func NewCalculator(db dal.Database, inputDirectory string, algorithm string, bas
 	hasher := common.NewHasher(algorithm)
 	effectiveBasePath := util.TrimPath(inputDirectory, basePath)
 
-	return Calculator{db, inputDirectory, basePath, hasher, effectiveBasePath}
+	return Calculator{
+		db, inputDirectory, basePath, hasher, effectiveBasePath, common.NullProgressListener{}, 0, false, false, 0, "", 0,
+		util.SymlinksFollow, false, util.NewPathMatcher(false, false), 0, 0, common.ReadOptions{}}
 }
 
-// Calculate Calculates and stores checksums for the files in the given directory.
-func (calculator *Calculator) Calculate(missingOnly bool) {
+// SetProgressListener Sets the listener that will be notified about the progress of the calculation.
+func (calculator *Calculator) SetProgressListener(listener common.ProgressListener) {
 
-	files := util.ListFilesRecursively(calculator.InputDirectory)
-	fingerprints := calculator.calculateFingerprints(files, missingOnly)
-	calculator.Db.Clear()
-	calculator.Db.AddFingerprints(fingerprints)
-	calculator.Db.SaveFingerprints()
+	calculator.progress = listener
+	calculator.hasher.SetProgressListener(listener)
 }
 
-func (calculator *Calculator) calculateFingerprints(files []string, missingOnly bool) *list.List {
+// SetCheckpointInterval Sets how often the fingerprints calculated so far are saved to the database. Zero disables
+// checkpoints.
+func (calculator *Calculator) SetCheckpointInterval(interval time.Duration) {
 
+	calculator.checkpointInterval = interval
+}
+
+// SetResume Sets whether an interrupted calculation should be continued. If set, the fingerprints are loaded from the
+// database first and the files having the same size and modification time as stored are not hashed again.
+func (calculator *Calculator) SetResume(resume bool) {
+
+	calculator.resume = resume
+}
+
+// SetArchives Sets whether the members of ZIP and TAR archives should be fingerprinted as well. The members are named
+// "<archive>!/<member>".
+func (calculator *Calculator) SetArchives(archives bool) {
+
+	calculator.archives = archives
+}
+
+// SetBlockSize Sets the size of the blocks hashed separately, so that corruption can be located within large files.
+// The block hashes are saved next to the database. Zero disables block hashes.
+func (calculator *Calculator) SetBlockSize(blockSize int64) {
+
+	calculator.blockSize = blockSize
+	calculator.hasher.SetBlockSize(blockSize)
+}
+
+// SetReadOptions Sets how the files are read, both when they are hashed and when their recovery data is created.
+func (calculator *Calculator) SetReadOptions(options common.ReadOptions) {
+
+	calculator.readOptions = options
+	options.Apply(&calculator.hasher)
+}
+
+// SetRecovery Sets the directory of the recovery files and the percent of redundancy. If set, Reed-Solomon recovery
+// data is created for each file hashed, so that damaged files can be repaired later. Zero percent disables it.
+func (calculator *Calculator) SetRecovery(recoveryDirectory string, percent int) {
+
+	calculator.recoveryDirectory = recoveryDirectory
+	calculator.recoveryPercent = percent
+}
+
+// SetSymlinkPolicy Sets how symbolic links are handled: followed (util.SymlinksFollow), fingerprinted as links with
+// their target (util.SymlinksRecord) or skipped (util.SymlinksSkip).
+func (calculator *Calculator) SetSymlinkPolicy(policy string) {
+
+	calculator.symlinks = policy
+	calculator.hasher.SetRecordSymlinks(policy == util.SymlinksRecord)
+}
+
+// SetCaptureAttributes Sets whether the permissions, ownership and extended attributes of the files are stored with
+// their checksums.
+func (calculator *Calculator) SetCaptureAttributes(attributes bool) {
+
+	calculator.attributes = attributes
+	calculator.hasher.SetCaptureAttributes(attributes)
+}
+
+// SetPathMatcher Sets how the files are matched with the stored filenames when calculating the missing ones only, so
+// that files whose name differs only in Unicode normalization or case are not hashed again.
+func (calculator *Calculator) SetPathMatcher(matcher util.PathMatcher) {
+
+	calculator.matcher = matcher
+}
+
+// RequestStop Asks the calculation to stop after the current file. The fingerprints calculated so far are saved. Safe
+// to call from another goroutine.
+func (calculator *Calculator) RequestStop() {
+
+	atomic.StoreInt32(&calculator.stopRequested, 1)
+}
+
+// Calculate Calculates and stores checksums for the files in the given directory. Returns false if the calculation was
+// stopped before all the files were processed, or if archive members were left out because their data is corrupt.
+func (calculator *Calculator) Calculate(missingOnly bool) bool {
+
+	files := util.ListFilesWithSymlinkPolicy(calculator.InputDirectory, calculator.symlinks)
 	if missingOnly {
-		return calculator.calculateFingerprintsForMissingFiles(files)
+		files = calculator.selectMissingFiles(files, calculator.loadMissingNames())
+	}
+
+	fingerprints, completed := calculator.calculateFingerprints(files)
+	calculator.saveFingerprints(fingerprints)
+	if calculator.corruptArchives > 0 {
+		util.LogError(fmt.Sprintf("%d archive(s) have corrupt members, which are not stored.", calculator.corruptArchives),
+			util.Field("archives", calculator.corruptArchives))
 	}
 
-	return calculator.hasher.CalculateFingerprints(calculator.InputDirectory, calculator.effectiveBasePath, files)
+	return completed && calculator.corruptArchives == 0
 }
 
-func (calculator *Calculator) calculateFingerprintsForMissingFiles(files []string) *list.List {
+func (calculator *Calculator) calculateFingerprints(files []string) (*list.List, bool) {
 
 	fingerprints := list.New()
-	etm := calculator.loadMissingNames()
+	filesToHash := calculator.reusePreviousFingerprints(files, fingerprints)
+	lastCheckpoint := time.Now()
+
+	calculator.progress.Start(len(filesToHash), util.GetTotalFileSize(calculator.InputDirectory, filesToHash))
+	defer calculator.progress.Finish()
+
+	for index, file := range filesToHash {
+		if calculator.isStopRequested() {
+			util.LogWarn(fmt.Sprintf("Calculation stopped, %d file(s) remaining.", len(filesToHash)-index),
+				util.Field("remaining", len(filesToHash)-index))
+			return fingerprints, false
+		}
+
+		start := time.Now()
+		fp := calculator.hasher.CalculateFingerprint(calculator.InputDirectory, calculator.effectiveBasePath, file)
+		fingerprints.PushFront(fp)
+		util.LogDebug("Hashed: "+fp.Filename, util.Field("file", fp.Filename), util.Field("algorithm", fp.Algorithm),
+			util.Field("duration", time.Since(start).Seconds()))
+		if calculator.archives && common.IsArchive(file) && fp.LinkTarget == "" {
+			calculator.calculateArchiveFingerprints(file, fingerprints)
+		}
+		if calculator.recoveryPercent > 0 && fp.LinkTarget == "" {
+			calculator.createRecoveryFile(file, fp)
+		}
+
+		if calculator.checkpointInterval > 0 && time.Since(lastCheckpoint) >= calculator.checkpointInterval {
+			calculator.saveFingerprints(fingerprints)
+			lastCheckpoint = time.Now()
+		}
+	}
+
+	return fingerprints, true
+}
+
+func (calculator *Calculator) calculateArchiveFingerprints(file string, fingerprints *list.List) {
+
+	members, err := calculator.hasher.CalculateArchiveFingerprints(
+		calculator.InputDirectory, calculator.effectiveBasePath, file)
+	if err == common.ErrCorruptMembers {
+		calculator.corruptArchives++
+	} else if err != nil {
+		util.LogWarn(fmt.Sprintf("Cannot read archive %s: %s.", file, err), util.Field("file", file))
+	}
+
+	fingerprints.PushFrontList(members)
+}
+
+// createRecoveryFile Creates the recovery file of the given file unless a file with the same checksum already has one.
+func (calculator *Calculator) createRecoveryFile(file string, fingerprint *dal.Fingerprint) {
+
+	recoveryPath := common.GetRecoveryFilePath(calculator.recoveryDirectory, fingerprint.Checksum)
+	if util.CheckIfFileExists(recoveryPath) {
+		return
+	}
+
+	err := common.CreateRecoveryFile(
+		path.Join(calculator.InputDirectory, file), recoveryPath, calculator.recoveryPercent, calculator.readOptions)
+	if err != nil {
+		util.LogWarn(fmt.Sprintf("Cannot create recovery data for %s: %s.", file, err), util.Field("file", file))
+	}
+}
+
+// reusePreviousFingerprints Adds the stored fingerprints of the unchanged files to the given list when resuming and
+// returns the files that have to be hashed.
+func (calculator *Calculator) reusePreviousFingerprints(files []string, fingerprints *list.List) []string {
+
+	if !calculator.resume {
+		return files
+	}
+
+	previousFingerprints, previousMembers := calculator.loadPreviousFingerprints()
+	filesToHash := make([]string, 0)
 
 	for _, file := range files {
-		calculator.addFingerprintIfFileIsMissing(file, etm, fingerprints)
+		effectivePath := util.NormalizePath(path.Join(calculator.effectiveBasePath, file))
+		previous := previousFingerprints[effectivePath]
+		membersMissing := calculator.archives && common.IsArchive(file) && len(previousMembers[effectivePath]) == 0
+		if previous != nil && !membersMissing && calculator.isUnchanged(file, previous) {
+			fingerprints.PushFront(previous)
+			for _, member := range previousMembers[effectivePath] {
+				fingerprints.PushFront(member)
+			}
+		} else {
+			filesToHash = append(filesToHash, file)
+		}
 	}
 
-	return fingerprints
+	util.LogInfo(fmt.Sprintf("Resuming: %d file(s) already done, %d to go.", fingerprints.Len(), len(filesToHash)),
+		util.Field("done", fingerprints.Len()), util.Field("remaining", len(filesToHash)))
+
+	return filesToHash
+}
+
+// loadPreviousFingerprints Loads the stored fingerprints of files by name and the stored fingerprints of archive members
+// by the name of the archive.
+func (calculator *Calculator) loadPreviousFingerprints() (
+	map[string]*dal.Fingerprint, map[string][]*dal.Fingerprint) {
+
+	calculator.Db.LoadFingerprints()
+	previousFingerprints := make(map[string]*dal.Fingerprint)
+	previousMembers := make(map[string][]*dal.Fingerprint)
+
+	for element := calculator.Db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		// A store may hold checksums of other algorithms too, e.g. the extended attributes of the files.
+		if fingerprint.Algorithm != calculator.hasher.GetAlgorithm() {
+			continue
+		}
+		if archivePath, _, isMember := common.SplitArchivePath(fingerprint.Filename, calculator.getFullPath); isMember {
+			if calculator.archives {
+				previousMembers[archivePath] = append(previousMembers[archivePath], fingerprint)
+			}
+		} else {
+			previousFingerprints[fingerprint.Filename] = fingerprint
+		}
+	}
+
+	return previousFingerprints, previousMembers
+}
+
+// getFullPath Returns the path of the file on disk from its stored name.
+func (calculator *Calculator) getFullPath(filename string) string {
+
+	return path.Join(calculator.BasePath, filename)
+}
+
+func (calculator *Calculator) isUnchanged(file string, fingerprint *dal.Fingerprint) bool {
+
+	// Files fingerprinted without the requested attributes are fingerprinted again.
+	if calculator.attributes && fingerprint.Mode == "" {
+		return false
+	}
+
+	fullPath := path.Join(calculator.InputDirectory, file)
+	if calculator.symlinks == util.SymlinksRecord && util.IsSymlink(fullPath) {
+		target, err := os.Readlink(fullPath)
+		return err == nil && target == fingerprint.LinkTarget
+	}
+
+	fileInfo, err := os.Stat(fullPath)
+	if err != nil || fingerprint.LinkTarget != "" {
+		return false
+	}
+
+	// Files hashed without the requested block hashes are hashed again.
+	if calculator.blockSize > 0 && (fingerprint.BlockSize != calculator.blockSize || !common.HasValidBlocks(fingerprint)) {
+		return false
+	}
+
+	return fileInfo.Size() == fingerprint.Size &&
+		common.GetModificationTimeString(fileInfo) == fingerprint.ModifiedAt
+}
+
+func (calculator *Calculator) isStopRequested() bool {
+
+	return atomic.LoadInt32(&calculator.stopRequested) != 0
+}
+
+func (calculator *Calculator) saveFingerprints(fingerprints *list.List) {
+
+	calculator.Db.Clear()
+	calculator.Db.AddFingerprints(fingerprints)
+	calculator.Db.SaveFingerprints()
 }
 
 func (calculator *Calculator) loadMissingNames() *common.EffectiveTextMemory {
 
 	etm := common.NewEffectiveTextMemory()
-	calculator.Db.LoadNamesFromFingeprints(etm)
+	calculator.Db.LoadNamesFromFingeprints(matchKeyWriter{etm, calculator.matcher})
 	etm.ClearCache()
 
 	return etm
 }
 
-func (calculator *Calculator) addFingerprintIfFileIsMissing(
-	file string, etm *common.EffectiveTextMemory, fingerprints *list.List) {
+func (calculator *Calculator) selectMissingFiles(files []string, etm *common.EffectiveTextMemory) []string {
 
-	fullPath := path.Join(calculator.effectiveBasePath, file)
-	if !etm.ContainsText(fullPath) {
-		fp := calculator.hasher.CalculateFingerprint(calculator.InputDirectory, calculator.effectiveBasePath, file)
-		fingerprints.PushFront(fp)
+	missingFiles := make([]string, 0)
+	for _, file := range files {
+		fullPath := path.Join(calculator.effectiveBasePath, file)
+		if !etm.ContainsText(calculator.matcher.GetMatchKey(fullPath)) {
+			missingFiles = append(missingFiles, file)
+		}
 	}
+
+	return missingFiles
+}
+
+// matchKeyWriter Forwards the match keys of the written paths.
+type matchKeyWriter struct {
+	writer  util.StringWriter
+	matcher util.PathMatcher
+}
+
+func (writer matchKeyWriter) Write(text string) {
+
+	writer.writer.Write(writer.matcher.GetMatchKey(text))
 }
@@ -1,9 +1,12 @@
 package bll
 
 import (
+	"encoding/hex"
+	"fmr/bll/common"
 	"fmr/bll/testutil"
 	"fmr/dal"
 	"fmr/util"
+	"os"
 	"testing"
 )
 
@@ -13,6 +16,12 @@ func TestCalculator(t *testing.T) {
 
 	t.Run("Calculate_All", testCalculatorAll)
 	t.Run("Calculate_MissingOnly", testCalculatorMissingOnly)
+	t.Run("Calculate_Resume", testCalculatorResume)
+	t.Run("Calculate_Stopped", testCalculatorStopped)
+	t.Run("Calculate_Archives", testCalculatorArchives)
+	t.Run("Calculate_CorruptArchive", testCalculatorCorruptArchive)
+	t.Run("Calculate_Symlinks", testCalculatorSymlinks)
+	t.Run("Calculate_MissingOnly_PathMatcher", testCalculatorMissingOnlyPathMatcher)
 
 	tearDownCalculatorTests()
 }
@@ -68,6 +77,177 @@ func testCalculatorMissingOnly(t *testing.T) {
 	testutil.AssertContainsFingerprints(t, actualFingerprints, expectedFingerprints, fieldsToCheck)
 }
 
+func testCalculatorResume(t *testing.T) {
+
+	// Arrange.
+	fileInfo, _ := os.Stat(testHelper.GetTestPath("test.txt"))
+	fp1 := testutil.CreateSparseFingerprint("test.txt", "00000000", "crc32")
+	fp1.Size = fileInfo.Size()
+	fp1.ModifiedAt = common.GetModificationTimeString(fileInfo)
+	fp2 := testutil.CreateSparseFingerprint("dir1/test.txt", "11111111", "crc32")
+	fp2.Size = 1
+	expectedFingerprints := testutil.CreateList(
+		fp1, testutil.CreateSparseFingerprint("dir1/test.txt", "6b24cc6a", "crc32"))
+	fieldsToCheck := testutil.NewFingerprintFieldsToCheck(false, false, false)
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprint(fp1)
+	memoryDatabase.AddFingerprint(fp2)
+	testPath := testHelper.GetTestRootDirectory()
+	calculator := NewCalculator(memoryDatabase, testPath, "crc32", testPath)
+	calculator.SetResume(true)
+
+	// Act.
+	completed := calculator.Calculate(false)
+
+	// Assert.
+	actualFingerprints := memoryDatabase.GetFingerprints()
+	if !completed {
+		t.Error("The calculation should be completed.")
+	}
+	if actualFingerprints.Len() != 2 {
+		t.Errorf("Wrong number of items in result set: %d.", actualFingerprints.Len())
+	}
+	testutil.AssertContainsFingerprints(t, actualFingerprints, expectedFingerprints, fieldsToCheck)
+}
+
+func testCalculatorStopped(t *testing.T) {
+
+	// Arrange.
+	memoryDatabase := dal.NewMemoryDatabase()
+	testPath := testHelper.GetTestRootDirectory()
+	calculator := NewCalculator(memoryDatabase, testPath, "crc32", testPath)
+	calculator.RequestStop()
+
+	// Act.
+	completed := calculator.Calculate(false)
+
+	// Assert.
+	if completed {
+		t.Error("The calculation should be stopped.")
+	}
+	if memoryDatabase.GetFingerprints().Len() != 0 {
+		t.Errorf("Wrong number of items in result set: %d.", memoryDatabase.GetFingerprints().Len())
+	}
+}
+
+func testCalculatorArchives(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("archives")
+	testutil.CreateTarGzArchive(
+		testHelper.GetTestPath("archives/members.tgz"),
+		map[string]string{"hello.txt": "Hello World!", "dir1/lorem.txt": "Lorem ipsum, dolor sit amet."})
+	fp1 := testutil.CreateSparseFingerprint("members.tgz!/hello.txt", "1c291ca3", "crc32")
+	fp2 := testutil.CreateSparseFingerprint("members.tgz!/dir1/lorem.txt", "6b24cc6a", "crc32")
+	expectedFingerprints := testutil.CreateList(fp1, fp2)
+	fieldsToCheck := testutil.NewFingerprintFieldsToCheck(false, false, false)
+	memoryDatabase := dal.NewMemoryDatabase()
+	testPath := testHelper.GetTestDirectory("archives")
+	calculator := NewCalculator(memoryDatabase, testPath, "crc32", testPath)
+	calculator.SetArchives(true)
+
+	// Act.
+	calculator.Calculate(false)
+
+	// Assert.
+	actualFingerprints := memoryDatabase.GetFingerprints()
+	if actualFingerprints.Len() != 3 {
+		t.Fatalf("Wrong number of items in result set: %d.", actualFingerprints.Len())
+	}
+	memberFingerprints := testutil.CreateList(
+		findFingerprint(memoryDatabase, "members.tgz!/hello.txt"),
+		findFingerprint(memoryDatabase, "members.tgz!/dir1/lorem.txt"))
+	testutil.AssertContainsFingerprints(t, memberFingerprints, expectedFingerprints, fieldsToCheck)
+}
+
+func testCalculatorCorruptArchive(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("corruptarchives")
+	archivePath := testHelper.GetTestPath("corruptarchives/members.zip")
+	testutil.CreateZipArchive(archivePath, map[string]string{"hello.txt": "Hello World!", "lorem.txt": "Lorem ipsum."})
+	testutil.CorruptFile(archivePath, "Hello World!", "Hello World?")
+	memoryDatabase := dal.NewMemoryDatabase()
+	testPath := testHelper.GetTestDirectory("corruptarchives")
+	calculator := NewCalculator(memoryDatabase, testPath, "crc32", testPath)
+	calculator.SetArchives(true)
+
+	// Act.
+	completed := calculator.Calculate(false)
+
+	// Assert.
+	if completed {
+		t.Error("The calculation should fail if archive members are corrupt.")
+	}
+	if memoryDatabase.GetFingerprints().Len() != 2 || findFingerprint(memoryDatabase, "members.zip!/lorem.txt") == nil {
+		t.Errorf("The archive and its intact member should be stored: %d.", memoryDatabase.GetFingerprints().Len())
+	}
+}
+
+func testCalculatorSymlinks(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("links")
+	testHelper.CreateTestFileWithContent("links/target.txt", "Hello World!")
+	if err := os.Symlink("target.txt", testHelper.GetTestPath("links/link.txt")); err != nil {
+		t.Skipf("Symbolic links are not supported: %s.", err)
+	}
+	os.Link(testHelper.GetTestPath("links/target.txt"), testHelper.GetTestPath("links/hardlink.txt"))
+	testPath := testHelper.GetTestDirectory("links")
+	followingDatabase := dal.NewMemoryDatabase()
+	followingCalculator := NewCalculator(followingDatabase, testPath, "crc32", testPath)
+	recordingDatabase := dal.NewMemoryDatabase()
+	recordingCalculator := NewCalculator(recordingDatabase, testPath, "crc32", testPath)
+	recordingCalculator.SetSymlinkPolicy(util.SymlinksRecord)
+
+	// Act.
+	followingCalculator.Calculate(false)
+	recordingCalculator.Calculate(false)
+	os.Remove(testHelper.GetTestPath("links/link.txt"))
+	os.Symlink("hardlink.txt", testHelper.GetTestPath("links/link.txt"))
+	verifier := NewVerifier(recordingDatabase, testPath)
+	verifier.Verify(false, common.NewFingerprintFilter(""))
+
+	// Assert.
+	followed := findFingerprint(followingDatabase, "link.txt")
+	recorded := findFingerprint(recordingDatabase, "link.txt")
+	hardlink := findFingerprint(followingDatabase, "hardlink.txt")
+	if followed == nil || followed.LinkTarget != "" || hex.EncodeToString(followed.Checksum) != "1c291ca3" {
+		t.Error("A followed link should be hashed through the link.")
+	}
+	if hardlink == nil || hex.EncodeToString(hardlink.Checksum) != "1c291ca3" {
+		t.Error("A hard link should have the checksum of the original file.")
+	}
+	if recorded == nil || recorded.LinkTarget != "target.txt" {
+		t.Fatal("A recorded link should store its target.")
+	}
+	if !testHelper.HasStringItems(verifier.Report.CorruptFiles, "link.txt") || verifier.Report.CorruptFiles.Len() != 1 {
+		t.Error("A link pointing elsewhere should be reported as corrupt.")
+	}
+}
+
+func testCalculatorMissingOnlyPathMatcher(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("names")
+	testHelper.CreateTestFileWithContent("names/Cafe\u0301.TXT", "Hello World!")
+	testHelper.CreateTestFileWithContent("names/new.txt", "Lorem ipsum, dolor sit amet.")
+	testPath := testHelper.GetTestDirectory("names")
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprint(testutil.CreateSparseFingerprint("caf\u00e9.txt", "1c291ca3", "crc32"))
+	calculator := NewCalculator(memoryDatabase, testPath, "crc32", testPath)
+	calculator.SetPathMatcher(util.NewPathMatcher(true, true))
+
+	// Act.
+	calculator.Calculate(true)
+
+	// Assert.
+	actualFingerprints := memoryDatabase.GetFingerprints()
+	if actualFingerprints.Len() != 1 || findFingerprint(memoryDatabase, "new.txt") == nil {
+		t.Errorf("Only the file without a matching entry should be hashed: %d.", actualFingerprints.Len())
+	}
+}
+
 func tearDownCalculatorTests() {
 
 	testHelper.CleanUp()
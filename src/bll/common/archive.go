@@ -0,0 +1,205 @@
+package common
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ArchiveSeparator Separates the path of an archive from the path of a member in fingerprint names, for example
+// "backup.zip!/docs/lease.pdf".
+const ArchiveSeparator = "!/"
+
+// ErrCorruptMembers Indicates that the data of some members does not match the CRC32 stored in the archive.
+var ErrCorruptMembers = errors.New("corrupt archive members")
+
+// ArchiveMember Describes a regular file stored in an archive. CRC32 is only known for ZIP members.
+type ArchiveMember struct {
+	Name       string
+	Size       int64
+	ModifiedAt time.Time
+	CRC32      uint32
+}
+
+// IsArchive Checks whether the given file is an archive whose members can be fingerprinted (.zip, .tar, .tar.gz or
+// .tgz).
+func IsArchive(filename string) bool {
+
+	lowerName := strings.ToLower(filename)
+
+	return strings.HasSuffix(lowerName, ".zip") || strings.HasSuffix(lowerName, ".tar") ||
+		strings.HasSuffix(lowerName, ".tar.gz") || strings.HasSuffix(lowerName, ".tgz")
+}
+
+// SplitArchivePath Splits a fingerprint name into the path of the archive and the path of the member. Returns false if
+// the name does not refer to an archive member: the part before the separator has to be an archive (see IsArchive)
+// that is a regular file on disk, getFullPath returns the path on disk of a stored name. Other names containing the
+// separator, e.g. "Wow!/pic.jpg", are plain files.
+func SplitArchivePath(name string, getFullPath func(name string) string) (string, string, bool) {
+
+	for offset := 0; ; {
+		index := strings.Index(name[offset:], ArchiveSeparator)
+		if index == -1 {
+			return name, "", false
+		}
+
+		archivePath := name[:offset+index]
+		if IsArchive(archivePath) && isRegularFile(getFullPath(archivePath)) {
+			return archivePath, name[offset+index+len(ArchiveSeparator):], true
+		}
+		offset += index + len(ArchiveSeparator)
+	}
+}
+
+func isRegularFile(p string) bool {
+
+	stat, err := os.Stat(p)
+
+	return err == nil && stat.Mode().IsRegular()
+}
+
+// JoinArchivePath Creates the fingerprint name of an archive member.
+func JoinArchivePath(archivePath string, memberName string) string {
+
+	return archivePath + ArchiveSeparator + memberName
+}
+
+// WalkArchive Calls the given function for each regular file in the archive, in the order they are stored. The reader
+// passed to the function is only valid during the call. Reading a ZIP member whose data does not match the stored
+// CRC32 returns zip.ErrChecksum. An error returned by the function stops the walk and is returned.
+func WalkArchive(archivePath string, visit func(member ArchiveMember, reader io.Reader) error) error {
+
+	lowerName := strings.ToLower(archivePath)
+	if strings.HasSuffix(lowerName, ".zip") {
+		return walkZip(archivePath, visit)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(lowerName, ".tar") {
+		return walkTar(file, visit)
+	}
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	if err := walkTar(gzipReader, visit); err != nil {
+		return err
+	}
+
+	// Reading to the end verifies the CRC32 of the whole gzip stream.
+	_, err = io.Copy(ioutil.Discard, gzipReader)
+
+	return err
+}
+
+func walkZip(archivePath string, visit func(member ArchiveMember, reader io.Reader) error) error {
+
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zipReader.Close()
+
+	for _, zipFile := range zipReader.File {
+		if !zipFile.Mode().IsRegular() {
+			continue
+		}
+		member := createZipMember(zipFile)
+		if err := visitZipMember(zipFile, member, visit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListZipMembers Returns the regular files of a ZIP archive as recorded in its central directory, including their CRC32,
+// without reading the compressed data.
+func ListZipMembers(archivePath string) ([]ArchiveMember, error) {
+
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer zipReader.Close()
+
+	members := make([]ArchiveMember, 0, len(zipReader.File))
+	for _, zipFile := range zipReader.File {
+		if zipFile.Mode().IsRegular() {
+			members = append(members, createZipMember(zipFile))
+		}
+	}
+
+	return members, nil
+}
+
+func createZipMember(zipFile *zip.File) ArchiveMember {
+
+	return ArchiveMember{
+		normalizeMemberName(zipFile.Name), int64(zipFile.UncompressedSize64), zipFile.Modified, zipFile.CRC32}
+}
+
+func visitZipMember(
+	zipFile *zip.File, member ArchiveMember, visit func(member ArchiveMember, reader io.Reader) error) error {
+
+	reader, err := zipFile.Open()
+	if err != nil {
+		return visit(member, &errorReader{err})
+	}
+	defer reader.Close()
+
+	return visit(member, reader)
+}
+
+func walkTar(reader io.Reader, visit func(member ArchiveMember, reader io.Reader) error) error {
+
+	tarReader := tar.NewReader(reader)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		member := ArchiveMember{normalizeMemberName(header.Name), header.Size, header.ModTime, 0}
+		if err := visit(member, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+func normalizeMemberName(name string) string {
+
+	return strings.TrimPrefix(path.Clean("/"+strings.Replace(name, "\\", "/", -1)), "/")
+}
+
+// errorReader Returns the same error for every read, used for members that cannot be opened.
+type errorReader struct {
+	err error
+}
+
+func (reader *errorReader) Read(buffer []byte) (int, error) {
+
+	return 0, reader.err
+}
@@ -0,0 +1,143 @@
+package common
+
+import (
+	"archive/zip"
+	"encoding/hex"
+	"fmr/bll/testutil"
+	"fmr/dal"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+
+	setupArchiveTests()
+
+	t.Run("IsArchive", testIsArchive)
+	t.Run("SplitArchivePath", testSplitArchivePath)
+	t.Run("WalkArchive_Zip", testWalkArchiveZip)
+	t.Run("WalkArchive_TarGz", testWalkArchiveTarGz)
+	t.Run("WalkArchive_ZipChecksumMismatch", testWalkArchiveZipChecksumMismatch)
+	t.Run("CalculateArchiveFingerprints", testCalculateArchiveFingerprints)
+
+	teardownTests()
+}
+
+func setupArchiveTests() {
+
+	testHelper.CreateTestRootDirectory()
+
+	members := map[string]string{"hello.txt": "Hello World!", "dir1/lorem.txt": "Lorem ipsum, dolor sit amet."}
+	testutil.CreateZipArchive(testHelper.GetTestPath("members.zip"), members)
+	testutil.CreateZipArchive(testHelper.GetTestPath("corrupt.zip"), members)
+	testutil.CorruptFile(testHelper.GetTestPath("corrupt.zip"), "Hello World!", "Hello World?")
+	testutil.CreateTarGzArchive(testHelper.GetTestPath("members.tar.gz"), members)
+}
+
+func testIsArchive(t *testing.T) {
+
+	for _, name := range []string{"a.zip", "b.TAR", "c.tar.gz", "d.tgz"} {
+		if !IsArchive(name) {
+			t.Errorf("Should be an archive: %s.", name)
+		}
+	}
+	if IsArchive("e.gz") {
+		t.Error("Should not be an archive: e.gz.")
+	}
+}
+
+func testSplitArchivePath(t *testing.T) {
+
+	testHelper.CreateTestDirectory("Wow!")
+	testHelper.CreateTestDirectory("folder.zip!")
+	testHelper.CreateTestFileWithContent("Wow!/pic.jpg", "Picture")
+	testHelper.CreateTestFileWithContent("folder.zip!/pic.jpg", "Picture")
+	getFullPath := testHelper.GetTestPath
+
+	archivePath, memberName, isMember := SplitArchivePath("members.zip!/dir1/lorem.txt", getFullPath)
+	_, _, isPlainFileMember := SplitArchivePath("members.zip", getFullPath)
+	_, _, isDirectoryMember := SplitArchivePath("Wow!/pic.jpg", getFullPath)
+	_, _, isArchiveDirectoryMember := SplitArchivePath("folder.zip!/pic.jpg", getFullPath)
+
+	if !isMember || archivePath != "members.zip" || memberName != "dir1/lorem.txt" {
+		t.Errorf("Wrong parts: %s, %s.", archivePath, memberName)
+	}
+	if isPlainFileMember || isDirectoryMember || isArchiveDirectoryMember {
+		t.Error("A plain file should not be treated as archive member.")
+	}
+}
+
+func testWalkArchiveZip(t *testing.T) {
+
+	contents := readArchive(t, "members.zip")
+
+	assertArchiveContents(t, contents)
+}
+
+func testWalkArchiveTarGz(t *testing.T) {
+
+	contents := readArchive(t, "members.tar.gz")
+
+	assertArchiveContents(t, contents)
+}
+
+func testWalkArchiveZipChecksumMismatch(t *testing.T) {
+
+	var memberErr error
+	WalkArchive(testHelper.GetTestPath("corrupt.zip"), func(member ArchiveMember, reader io.Reader) error {
+		if member.Name == "hello.txt" {
+			_, memberErr = ioutil.ReadAll(reader)
+		}
+		return nil
+	})
+
+	if memberErr != zip.ErrChecksum {
+		t.Errorf("The CRC32 mismatch should be detected, got: %v.", memberErr)
+	}
+}
+
+func testCalculateArchiveFingerprints(t *testing.T) {
+
+	hasher := NewHasher("crc32")
+
+	fingerprints, err1 := hasher.CalculateArchiveFingerprints(testHelper.GetTestRootDirectory(), "", "members.zip")
+	corruptFingerprints, err2 := hasher.CalculateArchiveFingerprints(
+		testHelper.GetTestRootDirectory(), "", "corrupt.zip")
+
+	if err1 != nil || err2 != ErrCorruptMembers {
+		t.Fatalf("Wrong errors: %v, %v.", err1, err2)
+	}
+	if fingerprints.Len() != 2 || corruptFingerprints.Len() != 1 {
+		t.Fatalf("Wrong number of fingerprints: %d, %d.", fingerprints.Len(), corruptFingerprints.Len())
+	}
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if fingerprint.Filename == "members.zip!/hello.txt" && hex.EncodeToString(fingerprint.Checksum) != "1c291ca3" {
+			t.Errorf("Wrong checksum: %x.", fingerprint.Checksum)
+		}
+	}
+}
+
+func readArchive(t *testing.T, archiveName string) map[string]string {
+
+	contents := make(map[string]string)
+	err := WalkArchive(testHelper.GetTestPath(archiveName), func(member ArchiveMember, reader io.Reader) error {
+		content, err := ioutil.ReadAll(reader)
+		contents[member.Name] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Cannot read archive %s: %s.", archiveName, err)
+	}
+
+	return contents
+}
+
+func assertArchiveContents(t *testing.T, contents map[string]string) {
+
+	if len(contents) != 2 || contents["hello.txt"] != "Hello World!" ||
+		contents["dir1/lorem.txt"] != "Lorem ipsum, dolor sit amet." {
+		t.Errorf("Wrong archive contents: %v.", contents)
+	}
+}
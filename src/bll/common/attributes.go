@@ -0,0 +1,102 @@
+package common
+
+import (
+	"fmr/dal"
+	"fmr/util"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CaptureAttributes Stores the permissions, ownership and extended attributes of the given file in the fingerprint. A
+// symbolic link is followed unless the fingerprint records the link itself.
+func CaptureAttributes(fullPath string, fingerprint *dal.Fingerprint) error {
+
+	attributes, err := util.ReadFileAttributes(fullPath, fingerprint.LinkTarget == "")
+	if err != nil {
+		return err
+	}
+
+	fingerprint.Mode = attributes.Mode
+	fingerprint.UID = attributes.UID
+	fingerprint.GID = attributes.GID
+	fingerprint.Xattrs = withoutChecksumXattrs(attributes.Xattrs)
+
+	return nil
+}
+
+// withoutChecksumXattrs Leaves out the extended attributes holding checksums (see dal.XattrPrefix), which are written
+// by fmr itself and would show as drift after each conversion.
+func withoutChecksumXattrs(xattrs map[string][]byte) map[string][]byte {
+
+	if xattrs == nil {
+		return nil
+	}
+
+	filtered := make(map[string][]byte, len(xattrs))
+	for name, value := range xattrs {
+		if !strings.HasPrefix(name, dal.XattrPrefix) {
+			filtered[name] = value
+		}
+	}
+
+	return filtered
+}
+
+// FindAttributeChanges Compares the attributes captured in the fingerprint with the current attributes of the file.
+// Returns a description of each change, e.g. "mode 0644 -> 0600"; nothing if no attributes were captured. Ownership
+// and extended attributes are only compared if they were stored and can be read on this platform, the checksum
+// attributes are ignored.
+func FindAttributeChanges(fullPath string, fingerprint *dal.Fingerprint) ([]string, error) {
+
+	changes := make([]string, 0)
+	if fingerprint.Mode == "" {
+		return changes, nil
+	}
+
+	attributes, err := util.ReadFileAttributes(fullPath, fingerprint.LinkTarget == "")
+	if err != nil {
+		return nil, err
+	}
+
+	changes = appendChange(changes, "mode", fingerprint.Mode, attributes.Mode)
+	if attributes.UID != "" && fingerprint.UID != "" {
+		changes = appendChange(changes, "uid", fingerprint.UID, attributes.UID)
+		changes = appendChange(changes, "gid", fingerprint.GID, attributes.GID)
+	}
+	if util.XattrsSupported && attributes.Xattrs != nil && fingerprint.Xattrs != nil {
+		changes = append(changes,
+			findXattrChanges(withoutChecksumXattrs(fingerprint.Xattrs), withoutChecksumXattrs(attributes.Xattrs))...)
+	}
+
+	return changes, nil
+}
+
+func appendChange(changes []string, name string, expected string, actual string) []string {
+
+	if expected == actual {
+		return changes
+	}
+
+	return append(changes, fmt.Sprintf("%s %s -> %s", name, expected, actual))
+}
+
+func findXattrChanges(expected map[string][]byte, actual map[string][]byte) []string {
+
+	changes := make([]string, 0)
+	for name, value := range expected {
+		if actualValue, found := actual[name]; !found {
+			changes = append(changes, "xattr "+name+" removed")
+		} else if !util.CompareByteSlices(value, actualValue) {
+			changes = append(changes, "xattr "+name+" changed")
+		}
+	}
+	for name := range actual {
+		if _, found := expected[name]; !found {
+			changes = append(changes, "xattr "+name+" added")
+		}
+	}
+	sort.Strings(changes)
+
+	return changes
+}
@@ -0,0 +1,178 @@
+package common
+
+import (
+	"fmr/bll/report"
+	"fmr/dal"
+	"fmr/util"
+	"hash"
+	"io"
+	"os"
+)
+
+// blockWriter Calculates the hash of each consecutive fixed-size block of the data written to it.
+type blockWriter struct {
+	hashFunc  hash.Hash
+	blockSize int64
+	written   int64
+	blocks    [][]byte
+}
+
+func newBlockWriter(algorithm string, blockSize int64) *blockWriter {
+
+	return &blockWriter{createHashFunc(algorithm), blockSize, 0, make([][]byte, 0)}
+}
+
+func (writer *blockWriter) Write(data []byte) (int, error) {
+
+	length := len(data)
+
+	for len(data) > 0 {
+		remaining := writer.blockSize - writer.written
+		if int64(len(data)) < remaining {
+			remaining = int64(len(data))
+		}
+		writer.hashFunc.Write(data[:remaining])
+		writer.written += remaining
+		data = data[remaining:]
+
+		if writer.written == writer.blockSize {
+			writer.finishBlock()
+		}
+	}
+
+	return length, nil
+}
+
+// finish Returns the hashes of the blocks, including the last, partial block.
+func (writer *blockWriter) finish() [][]byte {
+
+	if writer.written > 0 {
+		writer.finishBlock()
+	}
+
+	return writer.blocks
+}
+
+func (writer *blockWriter) finishBlock() {
+
+	writer.blocks = append(writer.blocks, writer.hashFunc.Sum(nil))
+	writer.hashFunc.Reset()
+	writer.written = 0
+}
+
+// CalculateMerkleRoot Calculates the root of the Merkle tree built from the given block hashes with the given algorithm.
+// Each level hashes the concatenation of pairs of nodes, an odd node is carried over to the next level. The root of a
+// file without blocks is the hash of no data.
+func CalculateMerkleRoot(algorithm string, blocks [][]byte) []byte {
+
+	hashFunc := createHashFunc(algorithm)
+	if len(blocks) == 0 {
+		return hashFunc.Sum(nil)
+	}
+
+	level := blocks
+	for len(level) > 1 {
+		nextLevel := make([][]byte, 0, (len(level)+1)/2)
+		for index := 0; index < len(level); index += 2 {
+			if index+1 == len(level) {
+				nextLevel = append(nextLevel, level[index])
+				continue
+			}
+			hashFunc.Reset()
+			hashFunc.Write(level[index])
+			hashFunc.Write(level[index+1])
+			nextLevel = append(nextLevel, hashFunc.Sum(nil))
+		}
+		level = nextLevel
+	}
+
+	return level[0]
+}
+
+// HasValidBlocks Checks whether the fingerprint has block hashes matching its stored Merkle root, so that they can be
+// used to locate changes.
+func HasValidBlocks(fingerprint *dal.Fingerprint) bool {
+
+	return fingerprint.BlockSize > 0 && fingerprint.Blocks != nil && fingerprint.BlockRoot != nil &&
+		util.CompareByteSlices(CalculateMerkleRoot(fingerprint.Algorithm, fingerprint.Blocks), fingerprint.BlockRoot)
+}
+
+// FindChangedRanges Compares two lists of block hashes and returns the byte ranges of the blocks that differ, adjacent
+// blocks merged. Blocks present in only one of the lists count as changed, up to the size of the longer file.
+func FindChangedRanges(
+	expected [][]byte, actual [][]byte, blockSize int64, expectedSize int64, actualSize int64) []report.ByteRange {
+
+	fileSize := expectedSize
+	if actualSize > fileSize {
+		fileSize = actualSize
+	}
+	blockCount := len(expected)
+	if len(actual) > blockCount {
+		blockCount = len(actual)
+	}
+
+	ranges := make([]report.ByteRange, 0)
+	for index := 0; index < blockCount; index++ {
+		if index < len(expected) && index < len(actual) && util.CompareByteSlices(expected[index], actual[index]) {
+			continue
+		}
+
+		start := int64(index) * blockSize
+		end := start + blockSize
+		if end > fileSize {
+			end = fileSize
+		}
+		if len(ranges) > 0 && ranges[len(ranges)-1].End == start {
+			ranges[len(ranges)-1].End = end
+		} else {
+			ranges = append(ranges, report.ByteRange{Start: start, End: end})
+		}
+	}
+
+	return ranges
+}
+
+// IsAppendedTo Checks whether the file of the new fingerprint starts with the content described by the previous one,
+// that is, data has only been appended to it. Both fingerprints must have block hashes of the same size; only the last,
+// partial block of the previous version is read from the file again.
+func IsAppendedTo(fullPath string, newFingerprint *dal.Fingerprint, previous *dal.Fingerprint) bool {
+
+	if newFingerprint.Size <= previous.Size || newFingerprint.Algorithm != previous.Algorithm ||
+		newFingerprint.BlockSize != previous.BlockSize || len(previous.Blocks) > len(newFingerprint.Blocks) {
+		return false
+	}
+
+	fullBlocks := int(previous.Size / previous.BlockSize)
+	if fullBlocks > len(previous.Blocks) {
+		return false
+	}
+	for index := 0; index < fullBlocks; index++ {
+		if !util.CompareByteSlices(previous.Blocks[index], newFingerprint.Blocks[index]) {
+			return false
+		}
+	}
+	if fullBlocks == len(previous.Blocks) {
+		return true
+	}
+
+	partialBlock, err := hashFileRange(
+		fullPath, previous.Algorithm, int64(fullBlocks)*previous.BlockSize, previous.Size%previous.BlockSize)
+
+	return err == nil && util.CompareByteSlices(partialBlock, previous.Blocks[fullBlocks])
+}
+
+func hashFileRange(fullPath string, algorithm string, offset int64, length int64) ([]byte, error) {
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hashFunc := createHashFunc(algorithm)
+	if _, err := io.Copy(hashFunc, io.NewSectionReader(file, offset, length)); err != nil {
+		return nil, err
+	}
+
+	return hashFunc.Sum(nil), nil
+}
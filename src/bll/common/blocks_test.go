@@ -0,0 +1,95 @@
+package common
+
+import (
+	"fmr/bll/report"
+	"fmr/dal"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+
+	setupBlocksTests()
+
+	t.Run("CalculateFingerprint_Blocks", testCalculateFingerprintBlocks)
+	t.Run("CalculateMerkleRoot", testCalculateMerkleRoot)
+	t.Run("FindChangedRanges", testFindChangedRanges)
+	t.Run("IsAppendedTo", testIsAppendedTo)
+
+	teardownTests()
+}
+
+func setupBlocksTests() {
+
+	testHelper.CreateTestRootDirectory()
+
+	testHelper.CreateTestFileWithContent("blocks.txt", "Hello World!")
+	testHelper.CreateTestFileWithContent("appended.txt", "Hello World! And more.")
+}
+
+func testCalculateFingerprintBlocks(t *testing.T) {
+
+	hasher := NewHasher(dal.CRC32)
+	hasher.SetBlockSize(5)
+
+	fingerprint := hasher.CalculateFingerprint(testHelper.GetTestRootDirectory(), "", "blocks.txt")
+
+	if fingerprint.BlockSize != 5 || len(fingerprint.Blocks) != 3 {
+		t.Fatalf("Wrong blocks: size %d, count %d.", fingerprint.BlockSize, len(fingerprint.Blocks))
+	}
+	if !HasValidBlocks(fingerprint) {
+		t.Error("The block hashes should match the block root.")
+	}
+	if hasher.CalculateChecksum(testHelper.GetTestPath("blocks.txt")) == nil {
+		t.Error("The hasher should be reusable after calculating block hashes.")
+	}
+
+	fingerprint.Blocks[1] = []byte{1, 2, 3, 4}
+	if HasValidBlocks(fingerprint) {
+		t.Error("Modified block hashes should not match the block root.")
+	}
+}
+
+func testCalculateMerkleRoot(t *testing.T) {
+
+	blocks := [][]byte{{1}, {2}, {3}}
+	root := CalculateMerkleRoot(dal.SHA256, blocks)
+	sameRoot := CalculateMerkleRoot(dal.SHA256, [][]byte{{1}, {2}, {3}})
+	otherRoot := CalculateMerkleRoot(dal.SHA256, [][]byte{{2}, {1}, {3}})
+
+	if len(root) != 32 || string(root) != string(sameRoot) || string(root) == string(otherRoot) {
+		t.Error("The root should depend on the content and the order of the blocks.")
+	}
+	if string(CalculateMerkleRoot(dal.SHA256, blocks[:1])) != string(blocks[0]) {
+		t.Error("The root of a single block should be its hash.")
+	}
+}
+
+func testFindChangedRanges(t *testing.T) {
+
+	expected := [][]byte{{1}, {2}, {3}, {4}, {5}}
+	actual := [][]byte{{1}, {9}, {9}, {4}}
+
+	ranges := FindChangedRanges(expected, actual, 10, 45, 40)
+
+	if report.FormatByteRanges(ranges) != "10-29, 40-44" {
+		t.Errorf("Wrong changed ranges: %s.", report.FormatByteRanges(ranges))
+	}
+}
+
+func testIsAppendedTo(t *testing.T) {
+
+	hasher := NewHasher(dal.MD5)
+	hasher.SetBlockSize(5)
+	previous := hasher.CalculateFingerprint(testHelper.GetTestRootDirectory(), "", "blocks.txt")
+	appended := hasher.CalculateFingerprint(testHelper.GetTestRootDirectory(), "", "appended.txt")
+
+	if !IsAppendedTo(testHelper.GetTestPath("appended.txt"), appended, previous) {
+		t.Error("The file should be recognized as appended.")
+	}
+
+	testHelper.CreateTestFileWithContent("appended.txt", "Hello World? And more.")
+	modified := hasher.CalculateFingerprint(testHelper.GetTestRootDirectory(), "", "appended.txt")
+	if IsAppendedTo(testHelper.GetTestPath("appended.txt"), modified, previous) {
+		t.Error("A file modified within the previous content should not be recognized as appended.")
+	}
+}
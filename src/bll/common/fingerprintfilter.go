@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmr/dal"
+	"fmr/util"
 	"strings"
 )
 
@@ -9,6 +10,7 @@ import (
 type FingerprintFilter struct {
 	filenameFilter  string
 	algorithmFilter string
+	matcher         util.PathMatcher
 }
 
 // NewFingerprintFilter Instantiates a new FingerprintFilter object.
@@ -16,16 +18,31 @@ func NewFingerprintFilter(filter string) FingerprintFilter {
 
 	filenameFilter, algorithmFilter := getFilterParts(filter)
 
-	return FingerprintFilter{filenameFilter, algorithmFilter}
+	return FingerprintFilter{filenameFilter, algorithmFilter, util.NewPathMatcher(false, false)}
+}
+
+// SetPathMatcher Sets how the filename filter is compared with the filenames, e.g. ignoring case.
+func (ff *FingerprintFilter) SetPathMatcher(matcher util.PathMatcher) {
+
+	ff.matcher = matcher
 }
 
 // FilterFingerprint Checks whether the given Fingerprint object matches the saved filters.
 func (ff *FingerprintFilter) FilterFingerprint(fingerprint *dal.Fingerprint) bool {
 
-	return (ff.filenameFilter == "" || strings.Contains(fingerprint.Filename, ff.filenameFilter)) &&
+	return (ff.filenameFilter == "" || ff.containsFilename(fingerprint.Filename)) &&
 		(ff.algorithmFilter == "" || fingerprint.Algorithm == ff.algorithmFilter)
 }
 
+func (ff *FingerprintFilter) containsFilename(filename string) bool {
+
+	if ff.matcher.IsExact() {
+		return strings.Contains(filename, ff.filenameFilter)
+	}
+
+	return strings.Contains(ff.matcher.GetMatchKey(filename), ff.matcher.GetMatchKey(ff.filenameFilter))
+}
+
 func getFilterParts(filter string) (string, string) {
 
 	if filter == "" {
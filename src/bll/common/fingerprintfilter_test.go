@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmr/dal"
+	"fmr/util"
 	"testing"
 )
 
@@ -16,6 +17,7 @@ func TestFingerprintFilter(t *testing.T) {
 	t.Run("AlgorithmFilter_NoMatch_DifferentAlgorithm", testAlgorithmFilterNoMatchDifferentAlgorithm)
 	t.Run("AlgorithmFilter_NoMatch_NotWellDefinedAlgorithm", testAlgorithmFilterNoMatchNotWellDefinedAlgorithm)
 	t.Run("AllFilters", testAllFilters)
+	t.Run("FilenameFilter_Match_PathMatcher", testFilenameFilterMatchPathMatcher)
 }
 
 func testEmptyFilter(t *testing.T) {
@@ -119,7 +121,7 @@ func testAllFilters(t *testing.T) {
 
 func createFingerprintWithNameAndAlg(filename string, algorithm string) *dal.Fingerprint {
 
-	return &dal.Fingerprint{filename, nil, algorithm, "", "", ""}
+	return &dal.Fingerprint{Filename: filename, Algorithm: algorithm}
 }
 
 func assertMatch(t *testing.T, filteredText string, filter string, shouldMatch bool, match bool) {
@@ -130,3 +132,18 @@ func assertMatch(t *testing.T, filteredText string, filter string, shouldMatch b
 		t.Errorf("\"%s\" should not match filter \"%s\".", filteredText, filter)
 	}
 }
+
+func testFilenameFilterMatchPathMatcher(t *testing.T) {
+
+	fp := createFingerprintWithNameAndAlg("Fotos/Cafe\u0301.JPG", "crc32")
+	filter := "caf\u00e9.jpg"
+	exactFilter := NewFingerprintFilter(filter)
+	fpFilter := NewFingerprintFilter(filter)
+	fpFilter.SetPathMatcher(util.NewPathMatcher(true, true))
+
+	exactResult := exactFilter.FilterFingerprint(fp)
+	result := fpFilter.FilterFingerprint(fp)
+
+	assertMatch(t, fp.Filename, filter, false, exactResult)
+	assertMatch(t, fp.Filename, filter, true, result)
+}
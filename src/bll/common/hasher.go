@@ -1,6 +1,7 @@
 package common
 
 import (
+	"archive/zip"
 	"container/list"
 	"crypto/md5"
 	"crypto/sha1"
@@ -13,13 +14,27 @@ import (
 	"io"
 	"os"
 	"path"
+	"strings"
 	"time"
 )
 
 // Hasher Logic for calculating checksums.
 type Hasher struct {
-	algorithm string
-	hashFunc  hash.Hash
+	algorithm   string
+	hashFunc    hash.Hash
+	progress    ProgressListener
+	blockSize   int64
+	recordLinks bool
+	attributes  bool
+	hardlinks   map[util.FileID]hashedContent
+	limiter     *RateLimiter
+	dropCache   func(file *os.File) error
+}
+
+// hashedContent Stores the checksum and block hashes of a file with several hard links.
+type hashedContent struct {
+	checksum []byte
+	blocks   [][]byte
 }
 
 // NewHasher Instantiates a new Hasher object.
@@ -27,12 +42,91 @@ func NewHasher(algorithm string) Hasher {
 
 	hashFunc := createHashFunc(algorithm)
 
-	return Hasher{algorithm, hashFunc}
+	return Hasher{algorithm, hashFunc, NullProgressListener{}, 0, false, false, make(map[util.FileID]hashedContent),
+		nil, nil}
+}
+
+// GetAlgorithm Returns the name of the algorithm used for hashing.
+func (hasher *Hasher) GetAlgorithm() string {
+
+	return hasher.algorithm
+}
+
+// SetProgressListener Sets the listener that will be notified about the files and bytes processed.
+func (hasher *Hasher) SetProgressListener(listener ProgressListener) {
+
+	hasher.progress = listener
+}
+
+// SetBlockSize Sets the size of the blocks hashed separately, so that changes can be located within large files. Zero
+// disables block hashes.
+func (hasher *Hasher) SetBlockSize(blockSize int64) {
+
+	hasher.blockSize = blockSize
+	hasher.hardlinks = make(map[util.FileID]hashedContent)
+}
+
+// SetRecordSymlinks Sets whether symbolic links are fingerprinted as links: the fingerprint stores the target of the
+// link and its checksum is calculated from the target path instead of the content.
+func (hasher *Hasher) SetRecordSymlinks(recordLinks bool) {
+
+	hasher.recordLinks = recordLinks
+}
+
+// SetRateLimiter Sets the limiter of the rate at which the files are read, which can be shared by several hashers. Nil
+// removes the limit.
+func (hasher *Hasher) SetRateLimiter(limiter *RateLimiter) {
+
+	hasher.limiter = limiter
+}
+
+// SetDropPageCache Sets whether the kernel is advised to drop each file from the page cache once it is hashed, so that
+// a scrub does not evict the data cached for other processes.
+func (hasher *Hasher) SetDropPageCache(drop bool) {
+
+	hasher.dropCache = nil
+	if drop {
+		hasher.dropCache = util.DropFileCache
+	}
+}
+
+// SetCaptureAttributes Sets whether the permissions, ownership and extended attributes of the files are stored in the
+// fingerprints.
+func (hasher *Hasher) SetCaptureAttributes(attributes bool) {
+
+	hasher.attributes = attributes
 }
 
 // CalculateChecksum Calculates the checksum of the given file.
 func (hasher *Hasher) CalculateChecksum(filename string) []byte {
 
+	checksum, _ := hasher.calculateChecksum(filename)
+
+	return checksum
+}
+
+// TryCalculateChecksum Calculates the checksum of the given file, returning an error instead of stopping if the file
+// cannot be read.
+func (hasher *Hasher) TryCalculateChecksum(filename string) ([]byte, error) {
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	checksum, _, err := hasher.hashFile(filename, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return checksum, nil
+}
+
+// CalculateChecksumWithBlocks Calculates the checksum of the given file and the hashes of its blocks, reading the file
+// once. The block hashes are nil if no block size is set.
+func (hasher *Hasher) CalculateChecksumWithBlocks(filename string) ([]byte, [][]byte) {
+
 	return hasher.calculateChecksum(filename)
 }
 
@@ -59,31 +153,193 @@ func (hasher *Hasher) CalculateFingerprints(basePath string, effectiveBasePath s
 	return fingerprints
 }
 
-func (hasher *Hasher) calculateChecksum(filename string) []byte {
+// CalculateArchiveFingerprints Calculates fingerprint for each regular file in the given archive. The members are named
+// "<archive>!/<member>". Members whose data does not match the CRC32 stored in the archive are logged and skipped, the
+// fingerprints of the other members are returned with ErrCorruptMembers.
+func (hasher *Hasher) CalculateArchiveFingerprints(
+	basePath string, effectiveBasePath string, file string) (*list.List, error) {
+
+	currentTime := getCurrentTimeString()
+	archivePath := util.NormalizePath(path.Join(effectiveBasePath, file))
+	fingerprints := list.New()
+	corruptMembers := false
+
+	err := WalkArchive(path.Join(basePath, file), func(member ArchiveMember, reader io.Reader) error {
+		checksum, err := hasher.hashReader(newThrottledReader(reader, hasher.limiter))
+		if err == zip.ErrChecksum {
+			memberPath := JoinArchivePath(archivePath, member.Name)
+			util.LogError("Corrupt archive member: "+memberPath+".", util.Field("file", memberPath),
+				util.Field("outcome", "corrupt"))
+			corruptMembers = true
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		fp := hasher.createFingerprint(JoinArchivePath(archivePath, member.Name), checksum, currentTime, nil)
+		fp.Size = member.Size
+		fp.ModifiedAt = member.ModifiedAt.UTC().Format(time.RFC3339Nano)
+		fingerprints.PushFront(fp)
+
+		return nil
+	})
+	if err == nil && corruptMembers {
+		err = ErrCorruptMembers
+	}
+
+	return fingerprints, err
+}
+
+// CalculateArchiveChecksums Calculates the checksum of each regular file in the given archive, the keys are the paths
+// of the members. Members whose data does not match the CRC32 stored in the archive are mapped to nil.
+func (hasher *Hasher) CalculateArchiveChecksums(archivePath string) (map[string][]byte, error) {
+
+	checksums := make(map[string][]byte)
+
+	err := WalkArchive(archivePath, func(member ArchiveMember, reader io.Reader) error {
+		checksum, err := hasher.hashReader(newThrottledReader(reader, hasher.limiter))
+		if err != nil && err != zip.ErrChecksum {
+			return err
+		}
+		checksums[member.Name] = checksum
+
+		return nil
+	})
+
+	return checksums, err
+}
+
+// hashReader Calculates the checksum of the data read from the given reader. Returns nil and the error if reading
+// fails.
+func (hasher *Hasher) hashReader(reader io.Reader) ([]byte, error) {
+
+	defer hasher.hashFunc.Reset()
+	if _, err := io.Copy(hasher.hashFunc, reader); err != nil {
+		return nil, err
+	}
+
+	return hasher.hashFunc.Sum(nil), nil
+}
+
+func (hasher *Hasher) calculateChecksum(filename string) ([]byte, [][]byte) {
 
 	file, err := os.Open(filename)
 	util.CheckErr(err, "Cannot read file "+filename+".")
 	defer file.Close()
 
-	io.Copy(hasher.hashFunc, file)
+	checksum, blocks, _ := hasher.hashFile(filename, file)
+
+	return checksum, blocks
+}
+
+// hashFile Calculates the checksum and block hashes of an open file. The checksum of the content read so far is
+// returned along with the error if reading fails.
+func (hasher *Hasher) hashFile(filename string, file *os.File) ([]byte, [][]byte, error) {
+
+	var writer io.Writer = hasher.hashFunc
+	var blocks *blockWriter
+	if hasher.blockSize > 0 {
+		blocks = newBlockWriter(hasher.algorithm, hasher.blockSize)
+		writer = io.MultiWriter(hasher.hashFunc, blocks)
+	}
+
+	_, err := io.Copy(writer, &progressReader{newThrottledReader(file, hasher.limiter), hasher.progress})
+	if hasher.dropCache != nil {
+		if err := hasher.dropCache(file); err != nil {
+			util.LogWarn("Cannot drop "+filename+" from the page cache.", util.Field("file", filename))
+		}
+	}
 	checksum := hasher.hashFunc.Sum(nil)[:]
 	hasher.hashFunc.Reset()
+	hasher.progress.FinishFile()
 
-	return checksum
+	if blocks == nil {
+		return checksum, nil, err
+	}
+
+	return checksum, blocks.finish(), err
 }
 
 func (hasher *Hasher) calculateFingerprint(
 	basePath string, effectiveBasePath string, file string, currentTime string) *dal.Fingerprint {
 
 	fullPath := path.Join(basePath, file)
-	checksum := hasher.calculateChecksum(fullPath)
 	effectivePath := util.NormalizePath(path.Join(effectiveBasePath, file))
-	fingerprint := hasher.createFingerprint(effectivePath, checksum, currentTime)
+	if hasher.recordLinks && util.IsSymlink(fullPath) {
+		return hasher.calculateLinkFingerprint(fullPath, effectivePath, currentTime)
+	}
+
+	fileInfo, err := os.Stat(fullPath)
+	util.CheckErr(err, "Cannot read file "+fullPath+".")
+
+	checksum, blocks := hasher.calculateChecksumOnce(fullPath, fileInfo)
+	fingerprint := hasher.createFingerprint(effectivePath, checksum, currentTime, fileInfo)
+	if blocks != nil {
+		fingerprint.BlockSize = hasher.blockSize
+		fingerprint.Blocks = blocks
+		fingerprint.BlockRoot = CalculateMerkleRoot(hasher.algorithm, blocks)
+	}
+	hasher.captureAttributes(fullPath, fingerprint)
 
 	return fingerprint
 }
 
-func (hasher *Hasher) createFingerprint(file string, checksum []byte, currentTime string) *dal.Fingerprint {
+// calculateChecksumOnce Calculates the checksum and block hashes of the given file. The content of a file with several
+// hard links is hashed only for the first of its links.
+func (hasher *Hasher) calculateChecksumOnce(fullPath string, fileInfo os.FileInfo) ([]byte, [][]byte) {
+
+	fileID, isHardlink := util.GetHardlinkID(fileInfo)
+	if content, isHashed := hasher.hardlinks[fileID]; isHardlink && isHashed {
+		hasher.progress.AddBytes(fileInfo.Size())
+		hasher.progress.FinishFile()
+		return content.checksum, content.blocks
+	}
+
+	checksum, blocks := hasher.calculateChecksum(fullPath)
+	if isHardlink {
+		hasher.hardlinks[fileID] = hashedContent{checksum, blocks}
+	}
+
+	return checksum, blocks
+}
+
+// calculateLinkFingerprint Creates the fingerprint of a symbolic link, its checksum calculated from the link target.
+func (hasher *Hasher) calculateLinkFingerprint(
+	fullPath string, effectivePath string, currentTime string) *dal.Fingerprint {
+
+	fileInfo, err := os.Lstat(fullPath)
+	util.CheckErr(err, "Cannot read file "+fullPath+".")
+	target, err := os.Readlink(fullPath)
+	util.CheckErr(err, "Cannot read symbolic link "+fullPath+".")
+
+	fingerprint := hasher.createFingerprint(effectivePath, hasher.CalculateLinkChecksum(target), currentTime, fileInfo)
+	fingerprint.LinkTarget = target
+	hasher.captureAttributes(fullPath, fingerprint)
+	hasher.progress.FinishFile()
+
+	return fingerprint
+}
+
+// captureAttributes Stores the attributes of the file in the fingerprint if requested. A file whose attributes cannot
+// be read is still fingerprinted.
+func (hasher *Hasher) captureAttributes(fullPath string, fingerprint *dal.Fingerprint) {
+
+	if hasher.attributes {
+		err := CaptureAttributes(fullPath, fingerprint)
+		util.CheckErrDontPanic(err, "Cannot read the attributes of "+fullPath+".")
+	}
+}
+
+// CalculateLinkChecksum Calculates the checksum stored for a symbolic link with the given target.
+func (hasher *Hasher) CalculateLinkChecksum(target string) []byte {
+
+	checksum, _ := hasher.hashReader(strings.NewReader(target))
+
+	return checksum
+}
+
+func (hasher *Hasher) createFingerprint(
+	file string, checksum []byte, currentTime string, fileInfo os.FileInfo) *dal.Fingerprint {
 
 	fp := new(dal.Fingerprint)
 	fp.Filename = file
@@ -92,10 +348,20 @@ func (hasher *Hasher) createFingerprint(file string, checksum []byte, currentTim
 	fp.CreatedAt = currentTime
 	fp.Creator = util.RuntimeVersion
 	fp.Note = ""
+	if fileInfo != nil {
+		fp.Size = fileInfo.Size()
+		fp.ModifiedAt = GetModificationTimeString(fileInfo)
+	}
 
 	return fp
 }
 
+// GetModificationTimeString Returns the modification time of the given file in the format stored in fingerprints.
+func GetModificationTimeString(fileInfo os.FileInfo) string {
+
+	return fileInfo.ModTime().UTC().Format(time.RFC3339Nano)
+}
+
 func createHashFunc(algorithm string) hash.Hash {
 
 	if algorithm == dal.CRC32 {
@@ -19,8 +19,10 @@ func TestHasher(t *testing.T) {
 	t.Run("CalculateChecksum_Sha1", testCalculateChecksumSha1)
 	t.Run("CalculateChecksum_Sha256", testCalculateChecksumSha256)
 	t.Run("CalculateChecksum_Sha512", testCalculateChecksumSha512)
+	t.Run("TryCalculateChecksum_Unreadable", testTryCalculateChecksumUnreadable)
 	t.Run("CalculateFingerprint", testCalculateFingerprint)
 	t.Run("CalculateFingerprints", testCalculateFingerprints)
+	t.Run("CalculateFingerprints_Progress", testCalculateFingerprintsProgress)
 
 	teardownTests()
 }
@@ -65,6 +67,25 @@ func testCalculateChecksumSha512(t *testing.T) {
 		"861844d6704e8573fec34d967e20bcfef3d424cf48be04e6dc08f2bd58c729743371015ead891cc3cf1c9d34b49264b510751b1ff9e537937bc46b5d6ff4ecc8")
 }
 
+func testTryCalculateChecksumUnreadable(t *testing.T) {
+
+	// Arrange
+	hasher := NewHasher("sha256")
+
+	// Act.
+	missingChecksum, missingErr := hasher.TryCalculateChecksum(testHelper.GetTestPath("missing.txt"))
+	directoryChecksum, directoryErr := hasher.TryCalculateChecksum(testHelper.GetTestRootDirectory())
+	checksum, err := hasher.TryCalculateChecksum(testHelper.GetTestPath("test.txt"))
+
+	// Assert.
+	if missingErr == nil || missingChecksum != nil || directoryErr == nil || directoryChecksum != nil {
+		t.Error("Files that cannot be read should return an error instead of a checksum.")
+	}
+	if err != nil || hex.EncodeToString(checksum) != "7f83b1657ff1fc53b92dc18148a1d65dfc2d4b1fa3d677284addd200126d9069" {
+		t.Errorf("Wrong checksum of a readable file: %x (%v).", checksum, err)
+	}
+}
+
 func testCalculateFingerprint(t *testing.T) {
 
 	// Arrange
@@ -119,6 +140,25 @@ func testCalculateFingerprints(t *testing.T) {
 	testutil.AssertContainsFingerprints(t, fingerprints, expectedFingerprints, fieldsToCheck)
 }
 
+func testCalculateFingerprintsProgress(t *testing.T) {
+
+	// Arrange.
+	hasher := NewHasher("crc32")
+	recorder := &progressRecorder{}
+	hasher.SetProgressListener(recorder)
+
+	// Act.
+	hasher.CalculateFingerprints(testHelper.GetTestRootDirectory(), "", []string{"test.txt", "dir1/test.txt"})
+
+	// Assert.
+	if recorder.files != 2 {
+		t.Errorf("Wrong number of files reported: %d.", recorder.files)
+	}
+	if recorder.bytes != 40 {
+		t.Errorf("Wrong number of bytes reported: %d.", recorder.bytes)
+	}
+}
+
 func teardownTests() {
 
 	testHelper.CleanUp()
@@ -134,3 +174,19 @@ func testChecksumCalculation(t *testing.T, algorithm string, expectedChecksum st
 		t.Errorf("Wrong %s checksum: %s.", algorithm, checksum)
 	}
 }
+
+type progressRecorder struct {
+	NullProgressListener
+	files int
+	bytes int64
+}
+
+func (recorder *progressRecorder) AddBytes(count int64) {
+
+	recorder.bytes += count
+}
+
+func (recorder *progressRecorder) FinishFile() {
+
+	recorder.files++
+}
@@ -0,0 +1,46 @@
+package common
+
+import "io"
+
+// ProgressListener Receives notifications about the progress of a long running process.
+type ProgressListener interface {
+	Start(totalFiles int, totalBytes int64)
+	AddBytes(count int64)
+	FinishFile()
+	Finish()
+}
+
+// NullProgressListener A ProgressListener that ignores every notification.
+type NullProgressListener struct{}
+
+// Start Does nothing.
+func (listener NullProgressListener) Start(totalFiles int, totalBytes int64) {
+}
+
+// AddBytes Does nothing.
+func (listener NullProgressListener) AddBytes(count int64) {
+}
+
+// FinishFile Does nothing.
+func (listener NullProgressListener) FinishFile() {
+}
+
+// Finish Does nothing.
+func (listener NullProgressListener) Finish() {
+}
+
+// progressReader Forwards the number of bytes read to a ProgressListener.
+type progressReader struct {
+	reader   io.Reader
+	listener ProgressListener
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+
+	n, err := pr.reader.Read(p)
+	if n > 0 {
+		pr.listener.AddBytes(int64(n))
+	}
+
+	return n, err
+}
@@ -0,0 +1,285 @@
+package common
+
+import (
+	"encoding/hex"
+	"fmr/dal"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query operators.
+const (
+	OperatorEqual        = "="
+	OperatorNotEqual     = "!="
+	OperatorContains     = "~"
+	OperatorPrefix       = "^"
+	OperatorLess         = "<"
+	OperatorLessEqual    = "<="
+	OperatorGreater      = ">"
+	OperatorGreaterEqual = ">="
+)
+
+// queryFieldTag The field matching any of the tags of a fingerprint.
+const queryFieldTag = "tag"
+
+// operators Lists the operators, the two-character ones first so they are found before their prefixes.
+var operators = []string{
+	OperatorNotEqual, OperatorLessEqual, OperatorGreaterEqual,
+	OperatorEqual, OperatorContains, OperatorPrefix, OperatorLess, OperatorGreater}
+
+// Query Stores conditions on the fields of fingerprints. A fingerprint matches if it satisfies every condition.
+type Query struct {
+	conditions []queryCondition
+}
+
+type queryCondition struct {
+	field    string
+	operator string
+	value    string
+	number   int64
+}
+
+// ParseQuery Parses an expression consisting of whitespace separated "<field><operator><value>" conditions, for example
+// `tag=contract created<2020`. Values containing whitespace can be put between double quotes.
+//
+// Fields: filename, checksum, algorithm, created, creator, note, tag, size, modified and meta.<name> (any other name
+// refers to a metadata column as well). Operators: = (equal), != (not equal), ~ (contains, case-insensitive), ^ (starts
+// with), <, <=, >, >=. Dates are compared on the length of the given value, so created<2020 means "created before
+// 2020" and created=2019-05 means "created in May 2019".
+func ParseQuery(expression string) (*Query, error) {
+
+	terms, err := splitQueryTerms(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &Query{make([]queryCondition, 0, len(terms))}
+	for _, term := range terms {
+		condition, err := parseQueryCondition(term)
+		if err != nil {
+			return nil, err
+		}
+		query.conditions = append(query.conditions, condition)
+	}
+
+	return query, nil
+}
+
+// AddChecksumPrefix Adds a condition requiring the checksum to start with the given hexadecimal prefix.
+func (query *Query) AddChecksumPrefix(prefix string) {
+
+	query.conditions = append(
+		query.conditions, queryCondition{dal.ColumnChecksum, OperatorPrefix, strings.ToLower(prefix), 0})
+}
+
+// Match Checks whether the given fingerprint satisfies every condition.
+func (query *Query) Match(fingerprint *dal.Fingerprint) bool {
+
+	for _, condition := range query.conditions {
+		if !condition.match(fingerprint) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (condition *queryCondition) match(fingerprint *dal.Fingerprint) bool {
+
+	switch condition.field {
+	case queryFieldTag:
+		return condition.matchTags(fingerprint.Tags)
+	case dal.ColumnSize:
+		return compareNumbers(fingerprint.Size, condition.operator, condition.number)
+	case dal.ColumnCreatedAt:
+		return condition.matchDate(fingerprint.CreatedAt)
+	case dal.ColumnModifiedAt:
+		return condition.matchDate(fingerprint.ModifiedAt)
+	case dal.ColumnFilename:
+		return compareStrings(fingerprint.Filename, condition.operator, condition.value)
+	case dal.ColumnChecksum:
+		return compareStrings(hex.EncodeToString(fingerprint.Checksum), condition.operator, condition.value)
+	case dal.ColumnAlgorithm:
+		return compareStrings(fingerprint.Algorithm, condition.operator, condition.value)
+	case dal.ColumnCreator:
+		return compareStrings(fingerprint.Creator, condition.operator, condition.value)
+	case dal.ColumnNote:
+		return compareStrings(fingerprint.Note, condition.operator, condition.value)
+	}
+
+	return compareStrings(fingerprint.Metadata[condition.field], condition.operator, condition.value)
+}
+
+func (condition *queryCondition) matchTags(tags []string) bool {
+
+	if condition.operator == OperatorNotEqual {
+		for _, tag := range tags {
+			if tag == condition.value {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, tag := range tags {
+		if compareStrings(tag, condition.operator, condition.value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchDate Compares the date (converted to UTC if possible) truncated to the length of the value. Missing dates only
+// match the != operator.
+func (condition *queryCondition) matchDate(date string) bool {
+
+	if date == "" {
+		return condition.operator == OperatorNotEqual
+	}
+	if parsedDate, err := time.Parse(time.RFC3339Nano, date); err == nil {
+		date = parsedDate.UTC().Format(time.RFC3339Nano)
+	}
+	if len(date) > len(condition.value) && condition.operator != OperatorContains {
+		date = date[:len(condition.value)]
+	}
+
+	return compareStrings(date, condition.operator, condition.value)
+}
+
+func splitQueryTerms(expression string) ([]string, error) {
+
+	terms := make([]string, 0)
+	term := new(strings.Builder)
+	quoted := false
+
+	for _, character := range expression {
+		switch {
+		case character == '"':
+			quoted = !quoted
+		case !quoted && (character == ' ' || character == '\t'):
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(character)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in query: %s", expression)
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+
+	return terms, nil
+}
+
+func parseQueryCondition(term string) (queryCondition, error) {
+
+	index := strings.IndexAny(term, "=!~^<>")
+	if index <= 0 {
+		return queryCondition{}, fmt.Errorf("invalid condition: %s", term)
+	}
+
+	field := normalizeQueryField(term[:index])
+	operator := ""
+	for _, candidate := range operators {
+		if strings.HasPrefix(term[index:], candidate) {
+			operator = candidate
+			break
+		}
+	}
+	if operator == "" {
+		return queryCondition{}, fmt.Errorf("invalid operator in condition: %s", term)
+	}
+
+	condition := queryCondition{field, operator, term[index+len(operator):], 0}
+
+	return condition, validateQueryCondition(&condition)
+}
+
+func normalizeQueryField(field string) string {
+
+	switch field {
+	case "created":
+		return dal.ColumnCreatedAt
+	case "modified":
+		return dal.ColumnModifiedAt
+	case dal.ColumnTags:
+		return queryFieldTag
+	}
+
+	return strings.TrimPrefix(field, "meta.")
+}
+
+func validateQueryCondition(condition *queryCondition) error {
+
+	switch condition.field {
+	case dal.ColumnSize:
+		if condition.operator == OperatorContains || condition.operator == OperatorPrefix {
+			return fmt.Errorf("operator %s cannot be used for %s", condition.operator, condition.field)
+		}
+		number, err := strconv.ParseInt(condition.value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid size: %s", condition.value)
+		}
+		condition.number = number
+	case queryFieldTag:
+		if condition.operator != OperatorEqual && condition.operator != OperatorNotEqual &&
+			condition.operator != OperatorContains && condition.operator != OperatorPrefix {
+			return fmt.Errorf("operator %s cannot be used for tags", condition.operator)
+		}
+	case dal.ColumnChecksum:
+		condition.value = strings.ToLower(condition.value)
+	}
+
+	return nil
+}
+
+func compareStrings(actual string, operator string, expected string) bool {
+
+	switch operator {
+	case OperatorEqual:
+		return actual == expected
+	case OperatorNotEqual:
+		return actual != expected
+	case OperatorContains:
+		return strings.Contains(strings.ToLower(actual), strings.ToLower(expected))
+	case OperatorPrefix:
+		return strings.HasPrefix(actual, expected)
+	case OperatorLess:
+		return actual < expected
+	case OperatorLessEqual:
+		return actual <= expected
+	case OperatorGreater:
+		return actual > expected
+	case OperatorGreaterEqual:
+		return actual >= expected
+	}
+
+	return false
+}
+
+func compareNumbers(actual int64, operator string, expected int64) bool {
+
+	switch operator {
+	case OperatorEqual:
+		return actual == expected
+	case OperatorNotEqual:
+		return actual != expected
+	case OperatorLess:
+		return actual < expected
+	case OperatorLessEqual:
+		return actual <= expected
+	case OperatorGreater:
+		return actual > expected
+	case OperatorGreaterEqual:
+		return actual >= expected
+	}
+
+	return false
+}
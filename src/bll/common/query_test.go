@@ -0,0 +1,104 @@
+package common
+
+import (
+	"fmr/dal"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+
+	t.Run("Query_Empty", testQueryEmpty)
+	t.Run("Query_TagAndDate", testQueryTagAndDate)
+	t.Run("Query_ChecksumPrefix", testQueryChecksumPrefix)
+	t.Run("Query_Size", testQuerySize)
+	t.Run("Query_QuotedValue", testQueryQuotedValue)
+	t.Run("Query_Metadata", testQueryMetadata)
+	t.Run("Query_Invalid", testQueryInvalid)
+}
+
+func testQueryEmpty(t *testing.T) {
+
+	query := parseTestQuery(t, "")
+
+	assertQueryMatch(t, query, createQueryTestFingerprint(), true)
+}
+
+func testQueryTagAndDate(t *testing.T) {
+
+	fingerprint := createQueryTestFingerprint()
+
+	assertQueryMatch(t, parseTestQuery(t, "tag=contract created<2020"), fingerprint, true)
+	assertQueryMatch(t, parseTestQuery(t, "tag=contract created<2019"), fingerprint, false)
+	assertQueryMatch(t, parseTestQuery(t, "created=2019-05"), fingerprint, true)
+	assertQueryMatch(t, parseTestQuery(t, "created<=2019"), fingerprint, true)
+	assertQueryMatch(t, parseTestQuery(t, "tag!=contract"), fingerprint, false)
+	assertQueryMatch(t, parseTestQuery(t, "tag!=invoice"), fingerprint, true)
+}
+
+func testQueryChecksumPrefix(t *testing.T) {
+
+	query := parseTestQuery(t, "")
+	query.AddChecksumPrefix("0C17")
+
+	assertQueryMatch(t, query, createQueryTestFingerprint(), true)
+	assertQueryMatch(t, parseTestQuery(t, "checksum^0d"), createQueryTestFingerprint(), false)
+}
+
+func testQuerySize(t *testing.T) {
+
+	fingerprint := createQueryTestFingerprint()
+
+	assertQueryMatch(t, parseTestQuery(t, "size>=1024"), fingerprint, true)
+	assertQueryMatch(t, parseTestQuery(t, "size>2048"), fingerprint, false)
+}
+
+func testQueryQuotedValue(t *testing.T) {
+
+	query := parseTestQuery(t, "note~\"SIGNED BY\" filename^docs/")
+
+	assertQueryMatch(t, query, createQueryTestFingerprint(), true)
+}
+
+func testQueryMetadata(t *testing.T) {
+
+	fingerprint := createQueryTestFingerprint()
+
+	assertQueryMatch(t, parseTestQuery(t, "meta.owner=alice"), fingerprint, true)
+	assertQueryMatch(t, parseTestQuery(t, "owner=bob"), fingerprint, false)
+}
+
+func testQueryInvalid(t *testing.T) {
+
+	expressions := []string{"tag", "=contract", "size~12", "size>big", "tag<a", "note=\"unterminated"}
+
+	for _, expression := range expressions {
+		if _, err := ParseQuery(expression); err == nil {
+			t.Errorf("Invalid expression should be rejected: %s.", expression)
+		}
+	}
+}
+
+func parseTestQuery(t *testing.T, expression string) *Query {
+
+	query, err := ParseQuery(expression)
+	if err != nil {
+		t.Fatalf("Cannot parse query %s: %s.", expression, err)
+	}
+
+	return query
+}
+
+func createQueryTestFingerprint() *dal.Fingerprint {
+
+	return &dal.Fingerprint{
+		Filename: "docs/lease.pdf", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha256",
+		CreatedAt: "2019-05-14T20:31:32+03:00", Note: "Signed by both parties", Size: 1536,
+		Tags: []string{"contract", "2019"}, Metadata: map[string]string{"owner": "alice"}}
+}
+
+func assertQueryMatch(t *testing.T, query *Query, fingerprint *dal.Fingerprint, expected bool) {
+
+	if query.Match(fingerprint) != expected {
+		t.Errorf("Wrong result for %s: %t.", fingerprint.Filename, !expected)
+	}
+}
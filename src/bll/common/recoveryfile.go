@@ -0,0 +1,502 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmr/util"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+)
+
+// RecoveryDirectorySuffix The suffix of the directory storing the recovery files of a database
+// ("<database>.recovery").
+const RecoveryDirectorySuffix = ".recovery"
+
+// RecoveryFileExtension The extension of a recovery file. Recovery files are named after the checksum of the file they
+// protect, so renaming or moving the file does not affect them.
+const RecoveryFileExtension = ".rs"
+
+// ErrNoDamagedShards Indicates that every block of the file matches the recovery file, so there is nothing to
+// reconstruct.
+var ErrNoDamagedShards = errors.New("no damaged blocks found")
+
+const (
+	recoveryMagic        = "FMRRS001"
+	recoveryHeaderSize   = 36
+	recoveryHashSize     = sha256.Size
+	maxRecoveryShards    = 1024
+	maxRecoveryGroupSize = 128
+	minRecoveryShardSize = 4096
+	recoveryChunkSize    = 64 * 1024
+)
+
+// recoveryLayout Describes how a file is split into shards. The data shards are distributed among the groups in turn
+// (shard i belongs to group i % groups), so damage affecting consecutive shards is spread over the groups. Each group
+// has the same number of parity shards.
+type recoveryLayout struct {
+	fileSize     int64
+	shardSize    int64
+	dataShards   int
+	groups       int
+	parityShards int
+}
+
+// GetRecoveryFilePath Returns the path of the recovery file of the file having the given checksum.
+func GetRecoveryFilePath(recoveryDirectory string, checksum []byte) string {
+
+	return path.Join(recoveryDirectory, hex.EncodeToString(checksum)+RecoveryFileExtension)
+}
+
+// CreateRecoveryFile Calculates Reed-Solomon parity data for the given file and saves it along with the hashes of the
+// blocks. With the given percent of redundancy, damaged blocks amounting to about the same percent of the file can be
+// reconstructed. Empty files need no recovery data, nothing is created for them. The source is read with the given
+// options, like a file being hashed.
+func CreateRecoveryFile(sourcePath string, recoveryPath string, percent int, options ReadOptions) error {
+
+	fileInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return err
+	}
+	if fileInfo.Size() == 0 {
+		return nil
+	}
+	layout := newRecoveryLayout(fileInfo.Size(), percent)
+
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	if err := os.MkdirAll(path.Dir(recoveryPath), 0755); err != nil {
+		return err
+	}
+	output, err := util.CreateAtomicFile(recoveryPath, false)
+	if err != nil {
+		return err
+	}
+	defer output.Abort()
+
+	if _, err := output.WriteAt(layout.encodeHeader(), 0); err != nil {
+		return err
+	}
+	reader := newThrottledReaderAt(source, options.Limiter)
+	for group := 0; group < layout.groups; group++ {
+		if err := layout.encodeGroup(reader, output.File, group); err != nil {
+			return err
+		}
+	}
+	if options.DropPageCache {
+		if err := util.DropFileCache(source); err != nil {
+			util.LogWarn("Cannot drop "+sourcePath+" from the page cache.", util.Field("file", sourcePath))
+		}
+	}
+
+	return output.Commit()
+}
+
+// RepairFile Reconstructs the damaged blocks of the given file from its recovery file. The repaired content is
+// written to a temporary file which replaces the original one when committed; the caller should verify its checksum
+// first. Returns the number of blocks reconstructed.
+func RepairFile(targetPath string, recoveryPath string) (*util.AtomicFile, int, error) {
+
+	recovery, err := os.Open(recoveryPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer recovery.Close()
+
+	layout, err := readRecoveryLayout(recovery)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	target, err := os.Open(targetPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer target.Close()
+
+	damaged, err := layout.findDamagedShards(target, recovery)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(damaged) == 0 {
+		return nil, 0, ErrNoDamagedShards
+	}
+
+	output, err := layout.copyTarget(target, targetPath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for group := 0; group < layout.groups; group++ {
+		if err := layout.repairGroup(target, recovery, output.File, group, damaged); err != nil {
+			output.Abort()
+			return nil, 0, err
+		}
+	}
+
+	return output, len(damaged), nil
+}
+
+func newRecoveryLayout(fileSize int64, percent int) recoveryLayout {
+
+	shardSize := int64(minRecoveryShardSize)
+	if fileSize > shardSize*maxRecoveryShards {
+		shardSize = (fileSize + maxRecoveryShards - 1) / maxRecoveryShards
+		shardSize = (shardSize + minRecoveryShardSize - 1) / minRecoveryShardSize * minRecoveryShardSize
+	}
+
+	dataShards := int((fileSize + shardSize - 1) / shardSize)
+	groups := (dataShards + maxRecoveryGroupSize - 1) / maxRecoveryGroupSize
+	groupSize := (dataShards + groups - 1) / groups
+	parityShards := (groupSize*percent + 99) / 100
+
+	return recoveryLayout{fileSize, shardSize, dataShards, groups, parityShards}
+}
+
+func readRecoveryLayout(recovery *os.File) (recoveryLayout, error) {
+
+	header := make([]byte, recoveryHeaderSize)
+	if _, err := io.ReadFull(recovery, header); err != nil || string(header[:8]) != recoveryMagic {
+		return recoveryLayout{}, fmt.Errorf("invalid recovery file: %s", recovery.Name())
+	}
+
+	layout := recoveryLayout{
+		fileSize:     int64(binary.BigEndian.Uint64(header[8:])),
+		shardSize:    int64(binary.BigEndian.Uint64(header[16:])),
+		dataShards:   int(binary.BigEndian.Uint32(header[24:])),
+		groups:       int(binary.BigEndian.Uint32(header[28:])),
+		parityShards: int(binary.BigEndian.Uint32(header[32:]))}
+	if layout.shardSize <= 0 || layout.groups <= 0 || layout.parityShards <= 0 ||
+		int64(layout.dataShards) != (layout.fileSize+layout.shardSize-1)/layout.shardSize {
+		return recoveryLayout{}, fmt.Errorf("invalid recovery file: %s", recovery.Name())
+	}
+
+	return layout, nil
+}
+
+func (layout *recoveryLayout) encodeHeader() []byte {
+
+	header := make([]byte, recoveryHeaderSize)
+	copy(header, recoveryMagic)
+	binary.BigEndian.PutUint64(header[8:], uint64(layout.fileSize))
+	binary.BigEndian.PutUint64(header[16:], uint64(layout.shardSize))
+	binary.BigEndian.PutUint32(header[24:], uint32(layout.dataShards))
+	binary.BigEndian.PutUint32(header[28:], uint32(layout.groups))
+	binary.BigEndian.PutUint32(header[32:], uint32(layout.parityShards))
+
+	return header
+}
+
+// getGroupShards Returns the indices of the data shards belonging to the given group.
+func (layout *recoveryLayout) getGroupShards(group int) []int {
+
+	shards := make([]int, 0, maxRecoveryGroupSize)
+	for shard := group; shard < layout.dataShards; shard += layout.groups {
+		shards = append(shards, shard)
+	}
+
+	return shards
+}
+
+func (layout *recoveryLayout) getDataHashOffset(shard int) int64 {
+
+	return recoveryHeaderSize + int64(shard)*recoveryHashSize
+}
+
+func (layout *recoveryLayout) getParityHashOffset(group int, parity int) int64 {
+
+	return layout.getDataHashOffset(layout.dataShards) + int64(group*layout.parityShards+parity)*recoveryHashSize
+}
+
+func (layout *recoveryLayout) getParityOffset(group int, parity int) int64 {
+
+	return layout.getParityHashOffset(layout.groups, 0) + int64(group*layout.parityShards+parity)*layout.shardSize
+}
+
+// getShardLength Returns the number of bytes of the file in the given data shard; the last one may be shorter.
+func (layout *recoveryLayout) getShardLength(shard int) int64 {
+
+	if remaining := layout.fileSize - int64(shard)*layout.shardSize; remaining < layout.shardSize {
+		return remaining
+	}
+
+	return layout.shardSize
+}
+
+// encodeGroup Calculates the parity shards of a group, processing the shards in chunks to limit memory usage.
+func (layout *recoveryLayout) encodeGroup(source io.ReaderAt, output *os.File, group int) error {
+
+	shards := layout.getGroupShards(group)
+	rs, err := newReedSolomon(len(shards), layout.parityShards)
+	if err != nil {
+		return err
+	}
+
+	data := allocateChunks(len(shards))
+	parity := allocateChunks(layout.parityShards)
+	dataHashes := createShardHashes(len(shards))
+	parityHashes := createShardHashes(layout.parityShards)
+
+	for offset := int64(0); offset < layout.shardSize; offset += recoveryChunkSize {
+		length := layout.getChunkLength(offset)
+		for index, shard := range shards {
+			valid, err := layout.readShardChunk(source, shard, offset, data[index][:length])
+			if err != nil {
+				return err
+			}
+			dataHashes[index].Write(data[index][:valid])
+		}
+
+		rs.encode(trimChunks(data, length), trimChunks(parity, length))
+		for index := range parity {
+			parityHashes[index].Write(parity[index][:length])
+			_, err := output.WriteAt(parity[index][:length], layout.getParityOffset(group, index)+offset)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for index, shard := range shards {
+		if _, err := output.WriteAt(dataHashes[index].Sum(nil), layout.getDataHashOffset(shard)); err != nil {
+			return err
+		}
+	}
+	for index := range parity {
+		if _, err := output.WriteAt(parityHashes[index].Sum(nil), layout.getParityHashOffset(group, index)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findDamagedShards Returns the data shards of the target whose hash does not match the recovery file.
+func (layout *recoveryLayout) findDamagedShards(target *os.File, recovery *os.File) (map[int]bool, error) {
+
+	damaged := make(map[int]bool)
+	expected := make([]byte, recoveryHashSize)
+	chunk := make([]byte, recoveryChunkSize)
+
+	for shard := 0; shard < layout.dataShards; shard++ {
+		if _, err := recovery.ReadAt(expected, layout.getDataHashOffset(shard)); err != nil {
+			return nil, err
+		}
+
+		hashFunc := sha256.New()
+		complete, err := layout.hashTargetShard(target, shard, chunk, hashFunc)
+		if err != nil {
+			return nil, err
+		}
+		if !complete || !bytes.Equal(hashFunc.Sum(nil), expected) {
+			damaged[shard] = true
+		}
+	}
+
+	return damaged, nil
+}
+
+// hashTargetShard Hashes the given data shard of the target. Returns false if the target is too short.
+func (layout *recoveryLayout) hashTargetShard(
+	target *os.File, shard int, chunk []byte, hashFunc hash.Hash) (bool, error) {
+
+	shardLength := layout.getShardLength(shard)
+	for offset := int64(0); offset < shardLength; offset += recoveryChunkSize {
+		length := shardLength - offset
+		if length > recoveryChunkSize {
+			length = recoveryChunkSize
+		}
+		read, err := target.ReadAt(chunk[:length], int64(shard)*layout.shardSize+offset)
+		hashFunc.Write(chunk[:read])
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// copyTarget Copies the target to a temporary file having the original size, the damaged shards are overwritten later.
+func (layout *recoveryLayout) copyTarget(target *os.File, targetPath string) (*util.AtomicFile, error) {
+
+	output, err := util.CreateAtomicFile(targetPath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(output, io.NewSectionReader(target, 0, layout.fileSize)); err != nil {
+		output.Abort()
+		return nil, err
+	}
+	if err := output.Truncate(layout.fileSize); err != nil {
+		output.Abort()
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// repairGroup Reconstructs the damaged data shards of a group from its intact data and parity shards.
+func (layout *recoveryLayout) repairGroup(
+	target *os.File, recovery *os.File, output *os.File, group int, damaged map[int]bool) error {
+
+	shards := layout.getGroupShards(group)
+	intact := make([]bool, len(shards)+layout.parityShards)
+	damagedCount := 0
+	for index, shard := range shards {
+		intact[index] = !damaged[shard]
+		if damaged[shard] {
+			damagedCount++
+		}
+	}
+	if damagedCount == 0 {
+		return nil
+	}
+
+	for parity := 0; parity < layout.parityShards; parity++ {
+		valid, err := layout.checkParityShard(recovery, group, parity)
+		if err != nil {
+			return err
+		}
+		intact[len(shards)+parity] = valid
+	}
+
+	rs, err := newReedSolomon(len(shards), layout.parityShards)
+	if err != nil {
+		return err
+	}
+	decoder, err := rs.newDecoder(intact)
+	if err != nil {
+		return fmt.Errorf("%d damaged block(s) in group %d: %s", damagedCount, group+1, err)
+	}
+
+	sources := allocateChunks(len(decoder.sources))
+	repaired := make([]byte, recoveryChunkSize)
+	for offset := int64(0); offset < layout.shardSize; offset += recoveryChunkSize {
+		length := layout.getChunkLength(offset)
+		for index, source := range decoder.sources {
+			err := layout.readSourceChunk(target, recovery, shards, group, source, offset, sources[index][:length])
+			if err != nil {
+				return err
+			}
+		}
+
+		for index, shard := range shards {
+			if !damaged[shard] || offset >= layout.getShardLength(shard) {
+				continue
+			}
+			decoder.reconstruct(index, trimChunks(sources, length), repaired[:length])
+			validLength := layout.getShardLength(shard) - offset
+			if validLength > int64(length) {
+				validLength = int64(length)
+			}
+			if _, err := output.WriteAt(repaired[:validLength], int64(shard)*layout.shardSize+offset); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (layout *recoveryLayout) checkParityShard(recovery *os.File, group int, parity int) (bool, error) {
+
+	expected := make([]byte, recoveryHashSize)
+	if _, err := recovery.ReadAt(expected, layout.getParityHashOffset(group, parity)); err != nil {
+		return false, err
+	}
+
+	hashFunc := sha256.New()
+	section := io.NewSectionReader(recovery, layout.getParityOffset(group, parity), layout.shardSize)
+	if written, err := io.Copy(hashFunc, section); err != nil || written != layout.shardSize {
+		return false, err
+	}
+
+	return bytes.Equal(hashFunc.Sum(nil), expected), nil
+}
+
+// readSourceChunk Reads a chunk of an intact shard: data shards from the target, parity shards from the recovery file.
+func (layout *recoveryLayout) readSourceChunk(
+	target *os.File, recovery *os.File, shards []int, group int, source int, offset int64, chunk []byte) error {
+
+	if source < len(shards) {
+		_, err := layout.readShardChunk(target, shards[source], offset, chunk)
+		return err
+	}
+
+	_, err := recovery.ReadAt(chunk, layout.getParityOffset(group, source-len(shards))+offset)
+
+	return err
+}
+
+// readShardChunk Reads a chunk of a data shard, padding it with zeros beyond the end of the file. Returns the number of
+// bytes belonging to the file.
+func (layout *recoveryLayout) readShardChunk(file io.ReaderAt, shard int, offset int64, chunk []byte) (int, error) {
+
+	valid := 0
+	if shardLength := layout.getShardLength(shard); offset < shardLength {
+		valid = len(chunk)
+		if int64(valid) > shardLength-offset {
+			valid = int(shardLength - offset)
+		}
+		if _, err := file.ReadAt(chunk[:valid], int64(shard)*layout.shardSize+offset); err != nil {
+			return 0, err
+		}
+	}
+
+	for index := valid; index < len(chunk); index++ {
+		chunk[index] = 0
+	}
+
+	return valid, nil
+}
+
+func (layout *recoveryLayout) getChunkLength(offset int64) int {
+
+	if layout.shardSize-offset < recoveryChunkSize {
+		return int(layout.shardSize - offset)
+	}
+
+	return recoveryChunkSize
+}
+
+func allocateChunks(count int) [][]byte {
+
+	chunks := make([][]byte, count)
+	for index := range chunks {
+		chunks[index] = make([]byte, recoveryChunkSize)
+	}
+
+	return chunks
+}
+
+func trimChunks(chunks [][]byte, length int) [][]byte {
+
+	trimmed := make([][]byte, len(chunks))
+	for index, chunk := range chunks {
+		trimmed[index] = chunk[:length]
+	}
+
+	return trimmed
+}
+
+func createShardHashes(count int) []hash.Hash {
+
+	hashes := make([]hash.Hash, count)
+	for index := range hashes {
+		hashes[index] = sha256.New()
+	}
+
+	return hashes
+}
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestRecoveryFile(t *testing.T) {
+
+	setupRecoveryFileTests()
+
+	t.Run("RepairFile", testRepairFile)
+	t.Run("RepairFile_Groups", testRepairFileGroups)
+	t.Run("RepairFile_Truncated", testRepairFileTruncated)
+	t.Run("RepairFile_TooMuchDamage", testRepairFileTooMuchDamage)
+	t.Run("RepairFile_Intact", testRepairFileIntact)
+
+	teardownTests()
+}
+
+func setupRecoveryFileTests() {
+
+	testHelper.CreateTestRootDirectory()
+}
+
+func testRepairFile(t *testing.T) {
+
+	original := createRecoveryTestFile("damaged.bin", 100000, 10)
+	damaged := append([]byte{}, original...)
+	for index := 5000; index < 13000; index++ {
+		damaged[index] ^= 0xff
+	}
+	ioutil.WriteFile(testHelper.GetTestPath("damaged.bin"), damaged, 0644)
+
+	assertRepairedContent(t, "damaged.bin", original)
+}
+
+func testRepairFileGroups(t *testing.T) {
+
+	// 367 blocks in 3 groups; consecutive damaged blocks are spread over the groups.
+	original := createRecoveryTestFile("groups.bin", 1500000, 10)
+	damaged := append([]byte{}, original...)
+	copy(damaged[200000:], make([]byte, 100000))
+	ioutil.WriteFile(testHelper.GetTestPath("groups.bin"), damaged, 0644)
+
+	assertRepairedContent(t, "groups.bin", original)
+}
+
+func testRepairFileTruncated(t *testing.T) {
+
+	original := createRecoveryTestFile("truncated.bin", 50000, 20)
+	ioutil.WriteFile(testHelper.GetTestPath("truncated.bin"), original[:45000], 0644)
+
+	assertRepairedContent(t, "truncated.bin", original)
+}
+
+func testRepairFileTooMuchDamage(t *testing.T) {
+
+	original := createRecoveryTestFile("lost.bin", 100000, 5)
+	ioutil.WriteFile(testHelper.GetTestPath("lost.bin"), make([]byte, len(original)), 0644)
+
+	if _, _, err := RepairFile(testHelper.GetTestPath("lost.bin"), testHelper.GetTestPath("lost.bin.rs")); err == nil {
+		t.Error("A file damaged beyond the redundancy should not be repaired.")
+	}
+}
+
+func testRepairFileIntact(t *testing.T) {
+
+	createRecoveryTestFile("intact.bin", 1000, 10)
+
+	_, _, err := RepairFile(testHelper.GetTestPath("intact.bin"), testHelper.GetTestPath("intact.bin.rs"))
+	if err != ErrNoDamagedShards {
+		t.Errorf("Wrong error: %v.", err)
+	}
+}
+
+func createRecoveryTestFile(name string, size int, percent int) []byte {
+
+	content := make([]byte, size)
+	for index := range content {
+		content[index] = byte(index*7 + index/251)
+	}
+	ioutil.WriteFile(testHelper.GetTestPath(name), content, 0644)
+	CreateRecoveryFile(testHelper.GetTestPath(name), testHelper.GetTestPath(name+".rs"), percent, ReadOptions{})
+
+	return content
+}
+
+func assertRepairedContent(t *testing.T, name string, expectedContent []byte) {
+
+	output, repairedCount, err := RepairFile(testHelper.GetTestPath(name), testHelper.GetTestPath(name+".rs"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s.", err)
+	}
+	if err := output.Commit(); err != nil {
+		t.Fatalf("Unexpected error: %s.", err)
+	}
+
+	content, _ := ioutil.ReadFile(testHelper.GetTestPath(name))
+	if repairedCount == 0 || !bytes.Equal(content, expectedContent) {
+		t.Errorf("The content of %s is not restored (%d block(s) repaired).", name, repairedCount)
+	}
+}
@@ -0,0 +1,184 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooFewShards Indicates that not enough shards are intact to reconstruct the data.
+var ErrTooFewShards = errors.New("too few intact shards")
+
+// gfPolynomial The irreducible polynomial generating GF(2^8): x^8 + x^4 + x^3 + x^2 + 1.
+const gfPolynomial = 0x11d
+
+var (
+	gfExp      [510]byte
+	gfLog      [256]byte
+	gfMulTable [256][256]byte
+)
+
+func init() {
+
+	value := 1
+	for exponent := 0; exponent < 255; exponent++ {
+		gfExp[exponent] = byte(value)
+		gfExp[exponent+255] = byte(value)
+		gfLog[value] = byte(exponent)
+		value <<= 1
+		if value&0x100 != 0 {
+			value ^= gfPolynomial
+		}
+	}
+
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			gfMulTable[a][b] = gfExp[int(gfLog[a])+int(gfLog[b])]
+		}
+	}
+}
+
+func gfInverse(value byte) byte {
+
+	return gfExp[255-int(gfLog[value])]
+}
+
+// reedSolomon A systematic Reed-Solomon erasure code over GF(2^8). The parity shards are calculated with a Cauchy
+// matrix, so any dataShards of the dataShards+parityShards shards are enough to reconstruct the data.
+type reedSolomon struct {
+	dataShards   int
+	parityShards int
+	parityMatrix [][]byte
+}
+
+func newReedSolomon(dataShards int, parityShards int) (*reedSolomon, error) {
+
+	if dataShards < 1 || parityShards < 1 || dataShards+parityShards > 256 {
+		return nil, fmt.Errorf("invalid number of shards: %d data, %d parity", dataShards, parityShards)
+	}
+
+	// Rows are indexed by x = dataShards + i, columns by y = j; the sets are disjoint, so x + y is never zero.
+	parityMatrix := make([][]byte, parityShards)
+	for i := range parityMatrix {
+		parityMatrix[i] = make([]byte, dataShards)
+		for j := range parityMatrix[i] {
+			parityMatrix[i][j] = gfInverse(byte(dataShards+i) ^ byte(j))
+		}
+	}
+
+	return &reedSolomon{dataShards, parityShards, parityMatrix}, nil
+}
+
+// encode Calculates the parity shards from the data shards. All shards must have the same length.
+func (rs *reedSolomon) encode(data [][]byte, parity [][]byte) {
+
+	for i, parityShard := range parity {
+		for index := range parityShard {
+			parityShard[index] = 0
+		}
+		for j, dataShard := range data {
+			mulSliceXor(rs.parityMatrix[i][j], dataShard, parityShard)
+		}
+	}
+}
+
+// decoder Reconstructs the data shards from the given intact shards (indices below dataShards are data shards, the
+// others parity shards).
+type decoder struct {
+	sources []int
+	matrix  [][]byte
+}
+
+// newDecoder Prepares the reconstruction from the first dataShards intact shards.
+func (rs *reedSolomon) newDecoder(intact []bool) (*decoder, error) {
+
+	sources := make([]int, 0, rs.dataShards)
+	for index, ok := range intact {
+		if ok && len(sources) < rs.dataShards {
+			sources = append(sources, index)
+		}
+	}
+	if len(sources) < rs.dataShards {
+		return nil, ErrTooFewShards
+	}
+
+	encoding := make([][]byte, rs.dataShards)
+	for row, source := range sources {
+		if source < rs.dataShards {
+			encoding[row] = make([]byte, rs.dataShards)
+			encoding[row][source] = 1
+		} else {
+			encoding[row] = append([]byte{}, rs.parityMatrix[source-rs.dataShards]...)
+		}
+	}
+
+	matrix, err := invertMatrix(encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decoder{sources, matrix}, nil
+}
+
+// reconstruct Calculates the given data shard from the source shards, in the order of decoder.sources.
+func (d *decoder) reconstruct(dataShard int, sourceShards [][]byte, output []byte) {
+
+	for index := range output {
+		output[index] = 0
+	}
+	for column, sourceShard := range sourceShards {
+		mulSliceXor(d.matrix[dataShard][column], sourceShard, output)
+	}
+}
+
+func mulSliceXor(coefficient byte, input []byte, output []byte) {
+
+	if coefficient == 0 {
+		return
+	}
+
+	table := &gfMulTable[coefficient]
+	for index, value := range input {
+		output[index] ^= table[value]
+	}
+}
+
+// invertMatrix Inverts a square matrix over GF(2^8) with Gauss-Jordan elimination.
+func invertMatrix(matrix [][]byte) ([][]byte, error) {
+
+	size := len(matrix)
+	work := make([][]byte, size)
+	for row := range matrix {
+		work[row] = make([]byte, 2*size)
+		copy(work[row], matrix[row])
+		work[row][size+row] = 1
+	}
+
+	for column := 0; column < size; column++ {
+		pivot := column
+		for pivot < size && work[pivot][column] == 0 {
+			pivot++
+		}
+		if pivot == size {
+			return nil, errors.New("singular matrix")
+		}
+		work[column], work[pivot] = work[pivot], work[column]
+
+		scale := gfInverse(work[column][column])
+		for index := range work[column] {
+			work[column][index] = gfMulTable[scale][work[column][index]]
+		}
+
+		for row := 0; row < size; row++ {
+			if row != column && work[row][column] != 0 {
+				mulSliceXor(work[row][column], work[column], work[row])
+			}
+		}
+	}
+
+	inverse := make([][]byte, size)
+	for row := range work {
+		inverse[row] = work[row][size:]
+	}
+
+	return inverse, nil
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+
+	t.Run("Reconstruct", testReedSolomonReconstruct)
+	t.Run("Reconstruct_TooFewShards", testReedSolomonReconstructTooFewShards)
+}
+
+func testReedSolomonReconstruct(t *testing.T) {
+
+	data := [][]byte{[]byte("abcd"), []byte("efgh"), []byte("ijkl"), []byte("mnop")}
+	parity := [][]byte{make([]byte, 4), make([]byte, 4)}
+	rs, _ := newReedSolomon(len(data), len(parity))
+	rs.encode(data, parity)
+
+	// Data shards 1 and 3 are lost.
+	intact := []bool{true, false, true, false, true, true}
+	decoder, err := rs.newDecoder(intact)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s.", err)
+	}
+	shards := append(append([][]byte{}, data...), parity...)
+	sources := make([][]byte, 0, len(decoder.sources))
+	for _, source := range decoder.sources {
+		sources = append(sources, shards[source])
+	}
+
+	for _, lost := range []int{1, 3} {
+		output := make([]byte, 4)
+		decoder.reconstruct(lost, sources, output)
+		if !bytes.Equal(output, data[lost]) {
+			t.Errorf("Wrong reconstructed shard %d: %s.", lost, output)
+		}
+	}
+}
+
+func testReedSolomonReconstructTooFewShards(t *testing.T) {
+
+	rs, _ := newReedSolomon(3, 1)
+
+	if _, err := rs.newDecoder([]bool{true, false, false, true}); err != ErrTooFewShards {
+		t.Errorf("Wrong error: %v.", err)
+	}
+}
@@ -0,0 +1,123 @@
+package common
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// ReadOptions Stores how the hashers of a task read the files: the limiter of the read rate they share (nil for no
+// limit) and whether each file is dropped from the page cache once it is hashed.
+type ReadOptions struct {
+	Limiter       *RateLimiter
+	DropPageCache bool
+}
+
+// Apply Sets the options on the given hasher.
+func (options ReadOptions) Apply(hasher *Hasher) {
+
+	hasher.SetRateLimiter(options.Limiter)
+	hasher.SetDropPageCache(options.DropPageCache)
+}
+
+// RateLimiter Delays the readers so that the bytes read do not exceed the rate on average. Unused time is not saved
+// up, a reader resuming after a pause is not allowed a burst.
+type RateLimiter struct {
+	mutex sync.Mutex
+	rate  int64
+	next  time.Time
+	sleep func(time.Duration)
+}
+
+// NewRateLimiter Instantiates a new RateLimiter object allowing the given number of bytes per second. Zero means no
+// limit.
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+
+	limiter := &RateLimiter{sleep: time.Sleep}
+	limiter.setRate(bytesPerSecond)
+
+	return limiter
+}
+
+func (limiter *RateLimiter) setRate(bytesPerSecond int64) {
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.rate = bytesPerSecond
+	limiter.next = time.Time{}
+}
+
+// wait Accounts for the given number of bytes read and sleeps until reading them is within the rate.
+func (limiter *RateLimiter) wait(count int) {
+
+	limiter.mutex.Lock()
+	if limiter.rate <= 0 {
+		limiter.mutex.Unlock()
+		return
+	}
+
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+	limiter.next = limiter.next.Add(time.Duration(int64(count) * int64(time.Second) / limiter.rate))
+	delay := limiter.next.Sub(now)
+	limiter.mutex.Unlock()
+
+	if delay > 0 {
+		limiter.sleep(delay)
+	}
+}
+
+// throttledReader Reads no faster than the rate of its limiter.
+type throttledReader struct {
+	reader  io.Reader
+	limiter *RateLimiter
+}
+
+// newThrottledReader Returns a reader limited by the given limiter, the reader itself if there is no limiter.
+func newThrottledReader(reader io.Reader, limiter *RateLimiter) io.Reader {
+
+	if limiter == nil {
+		return reader
+	}
+
+	return &throttledReader{reader, limiter}
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+
+	n, err := tr.reader.Read(p)
+	if n > 0 {
+		tr.limiter.wait(n)
+	}
+
+	return n, err
+}
+
+// throttledReaderAt Reads at given offsets no faster than the rate of its limiter.
+type throttledReaderAt struct {
+	reader  io.ReaderAt
+	limiter *RateLimiter
+}
+
+// newThrottledReaderAt Returns a reader limited by the given limiter, the reader itself if there is no limiter.
+func newThrottledReaderAt(reader io.ReaderAt, limiter *RateLimiter) io.ReaderAt {
+
+	if limiter == nil {
+		return reader
+	}
+
+	return &throttledReaderAt{reader, limiter}
+}
+
+func (tr *throttledReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+
+	n, err := tr.reader.ReadAt(p, offset)
+	if n > 0 {
+		tr.limiter.wait(n)
+	}
+
+	return n, err
+}
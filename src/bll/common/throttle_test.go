@@ -0,0 +1,125 @@
+package common
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+
+	t.Run("RateLimiter_Wait", testRateLimiterWait)
+	t.Run("RateLimiter_NoLimit", testRateLimiterNoLimit)
+	t.Run("ThrottledReader", testThrottledReader)
+	t.Run("CalculateChecksum_DropPageCache", testCalculateChecksumDropPageCache)
+	t.Run("CalculateChecksum_RateLimiter", testCalculateChecksumRateLimiter)
+	t.Run("CreateRecoveryFile_RateLimiter", testCreateRecoveryFileRateLimiter)
+}
+
+func testRateLimiterWait(t *testing.T) {
+
+	var delays []time.Duration
+	limiter := &RateLimiter{sleep: func(delay time.Duration) { delays = append(delays, delay) }}
+	limiter.setRate(1000)
+
+	limiter.wait(500)
+	limiter.wait(500)
+
+	if len(delays) != 2 || delays[0] < 400*time.Millisecond || delays[1] < 900*time.Millisecond ||
+		delays[1] > time.Second {
+		t.Errorf("Wrong delays: %v.", delays)
+	}
+}
+
+func testRateLimiterNoLimit(t *testing.T) {
+
+	slept := false
+	limiter := &RateLimiter{sleep: func(delay time.Duration) { slept = true }}
+
+	limiter.wait(1 << 20)
+
+	if slept {
+		t.Error("The reader should not be delayed without a limit.")
+	}
+}
+
+func testThrottledReader(t *testing.T) {
+
+	var last time.Duration
+	limiter := &RateLimiter{sleep: func(delay time.Duration) { last = delay }}
+	limiter.setRate(1 << 20)
+	reader := &throttledReader{strings.NewReader(strings.Repeat("x", 1<<19)), limiter}
+
+	content, _ := ioutil.ReadAll(reader)
+
+	if len(content) != 1<<19 || last < 400*time.Millisecond || last > 500*time.Millisecond {
+		t.Errorf("Reading half of the rate should take about half a second: %d bytes, %v.", len(content), last)
+	}
+}
+
+func testCalculateChecksumDropPageCache(t *testing.T) {
+
+	testHelper.CreateTestRootDirectory()
+	testHelper.CreateTestFileWithContent("cached.txt", "Hello World!")
+	hasher := NewHasher("crc32")
+	hasher.SetDropPageCache(true)
+	if hasher.dropCache == nil {
+		t.Fatal("The page cache should be dropped once enabled.")
+	}
+	var dropped []string
+	hasher.dropCache = func(file *os.File) error {
+		dropped = append(dropped, file.Name())
+		return nil
+	}
+
+	checksum := hasher.CalculateChecksum(testHelper.GetTestPath("cached.txt"))
+
+	if !bytes.Equal(checksum, []byte{0x1c, 0x29, 0x1c, 0xa3}) {
+		t.Errorf("Wrong checksum: %x.", checksum)
+	}
+	if len(dropped) != 1 || dropped[0] != testHelper.GetTestPath("cached.txt") {
+		t.Errorf("The hashed file should be dropped from the page cache once: %v.", dropped)
+	}
+	testHelper.CleanUp()
+}
+
+func testCalculateChecksumRateLimiter(t *testing.T) {
+
+	testHelper.CreateTestRootDirectory()
+	testHelper.CreateTestFileWithContent("limited.txt", strings.Repeat("x", 1<<10))
+	var delays []time.Duration
+	limiter := &RateLimiter{sleep: func(delay time.Duration) { delays = append(delays, delay) }}
+	limiter.setRate(1 << 10)
+	hasher := NewHasher("crc32")
+	hasher.SetRateLimiter(limiter)
+
+	hasher.CalculateChecksum(testHelper.GetTestPath("limited.txt"))
+
+	if len(delays) == 0 || delays[len(delays)-1] < 900*time.Millisecond {
+		t.Errorf("Reading the file should be limited by the rate: %v.", delays)
+	}
+	testHelper.CleanUp()
+}
+
+func testCreateRecoveryFileRateLimiter(t *testing.T) {
+
+	testHelper.CreateTestRootDirectory()
+	testHelper.CreateTestFileWithContent("protected.txt", strings.Repeat("x", 1<<14))
+	var delays []time.Duration
+	limiter := &RateLimiter{sleep: func(delay time.Duration) { delays = append(delays, delay) }}
+	limiter.setRate(1 << 14)
+
+	err := CreateRecoveryFile(testHelper.GetTestPath("protected.txt"), testHelper.GetTestPath("protected.rs"), 10,
+		ReadOptions{Limiter: limiter, DropPageCache: true})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s.", err)
+	}
+	if len(delays) == 0 || delays[len(delays)-1] < 900*time.Millisecond {
+		t.Errorf("Reading the file should be limited by the rate: %v.", delays)
+	}
+	testHelper.CleanUp()
+}
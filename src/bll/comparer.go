@@ -7,6 +7,8 @@ import (
 	"fmr/bll/report"
 	"fmr/dal"
 	"fmr/util"
+	"path"
+	"strings"
 )
 
 // Comparer Stores settings related to comparison.
@@ -15,6 +17,11 @@ type Comparer struct {
 	InputDirectory string
 	BasePath       string
 	Report         *report.ComparisonReport
+	progress       common.ProgressListener
+	blockSize      int64
+	symlinks       string
+	matcher        util.PathMatcher
+	readOptions    common.ReadOptions
 }
 
 // NewComparer Instantiates a new Comparer object.
@@ -22,14 +29,49 @@ func NewComparer(db dal.Database, inputDirectory string, basePath string) Compar
 
 	report := report.NewComparisonReport()
 
-	return Comparer{db, inputDirectory, basePath, report}
+	return Comparer{db, inputDirectory, basePath, report, common.NullProgressListener{}, 0, util.SymlinksFollow,
+		util.NewPathMatcher(false, false), common.ReadOptions{}}
+}
+
+// SetProgressListener Sets the listener that will be notified about the progress of the checksum calculation.
+func (comparer *Comparer) SetProgressListener(listener common.ProgressListener) {
+
+	comparer.progress = listener
+}
+
+// SetBlockSize Sets the size of the blocks hashed separately. If both the stored and the new fingerprint of a file have
+// block hashes, a changed file is reported as appended or modified instead of new and missing.
+func (comparer *Comparer) SetBlockSize(blockSize int64) {
+
+	comparer.blockSize = blockSize
+}
+
+// SetSymlinkPolicy Sets how symbolic links in the input directory are handled: followed (util.SymlinksFollow),
+// fingerprinted as links with their target (util.SymlinksRecord) or skipped (util.SymlinksSkip).
+func (comparer *Comparer) SetSymlinkPolicy(policy string) {
+
+	comparer.symlinks = policy
+}
+
+// SetPathMatcher Sets how the stored filenames are matched with the names in the input directory. A file whose name
+// only differs in Unicode normalization or case is not reported as renamed.
+func (comparer *Comparer) SetPathMatcher(matcher util.PathMatcher) {
+
+	comparer.matcher = matcher
+}
+
+// SetReadOptions Sets how the files of the input directory are read, including those hashed again with the stored
+// algorithm and block size.
+func (comparer *Comparer) SetReadOptions(options common.ReadOptions) {
+
+	comparer.readOptions = options
 }
 
 // Compare Verifies and stores changes in the given directory based on the checksums calculated earlier.
 func (comparer *Comparer) Compare(algorithm string) {
 
 	oldFingerprints := comparer.loadOldFingerprints()
-	newFingerprints := comparer.calculateNewFingerprints(algorithm)
+	newFingerprints := comparer.calculateNewFingerprints(algorithm, hasAttributes(oldFingerprints))
 
 	comparer.compareWithPreviousSnapshot(oldFingerprints, newFingerprints)
 	comparer.Db.Clear()
@@ -46,12 +88,33 @@ func (comparer *Comparer) loadOldFingerprints() *list.List {
 	return oldFingerprints
 }
 
-func (comparer *Comparer) calculateNewFingerprints(algorithm string) *list.List {
+// hasAttributes Returns whether the snapshot was calculated with the attributes of the files, so that the new
+// fingerprints store them too.
+func hasAttributes(fingerprints *list.List) bool {
+
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		if element.Value.(*dal.Fingerprint).Mode != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (comparer *Comparer) calculateNewFingerprints(algorithm string, attributes bool) *list.List {
 
 	hasher := common.NewHasher(algorithm)
+	hasher.SetProgressListener(comparer.progress)
+	hasher.SetBlockSize(comparer.blockSize)
+	hasher.SetRecordSymlinks(comparer.symlinks == util.SymlinksRecord)
+	hasher.SetCaptureAttributes(attributes)
+	comparer.readOptions.Apply(&hasher)
 	effectiveBasePath := comparer.getEffectiveBasePath()
-	files := util.ListFilesRecursively(comparer.InputDirectory)
+	files := util.ListFilesWithSymlinkPolicy(comparer.InputDirectory, comparer.symlinks)
+
+	comparer.progress.Start(len(files), util.GetTotalFileSize(comparer.InputDirectory, files))
 	newFingerprints := hasher.CalculateFingerprints(comparer.InputDirectory, effectiveBasePath, files)
+	comparer.progress.Finish()
 
 	return newFingerprints
 }
@@ -61,16 +124,30 @@ func (comparer *Comparer) getEffectiveBasePath() string {
 	return util.TrimPath(comparer.InputDirectory, comparer.BasePath)
 }
 
+// getFullPath Returns the path of the file in the input directory from its stored name.
+func (comparer *Comparer) getFullPath(filename string) string {
+
+	relativePath := strings.TrimPrefix(strings.TrimPrefix(filename, comparer.getEffectiveBasePath()), "/")
+
+	return path.Join(comparer.InputDirectory, relativePath)
+}
+
 func (comparer *Comparer) compareWithPreviousSnapshot(oldFingerprints *list.List, newFingerprints *list.List) {
 
 	cache := buildFingerprintCache(oldFingerprints)
+	blockCache := buildBlockFingerprintCache(oldFingerprints, comparer.matcher)
 	foundFingerprints := make(map[string]bool)
 
 	for element := newFingerprints.Front(); element != nil; element = element.Next() {
 		fingerprint := element.Value.(*dal.Fingerprint)
 		checksum := hex.EncodeToString(fingerprint.Checksum)
 		matchingFingerprint := cache[checksum]
-		comparer.processMatch(fingerprint, checksum, matchingFingerprint, foundFingerprints)
+		previous := blockCache[comparer.matcher.GetMatchKey(fingerprint.Filename)]
+		if matchingFingerprint == nil && previous != nil && common.HasValidBlocks(fingerprint) {
+			comparer.processChange(fingerprint, previous, foundFingerprints)
+		} else {
+			comparer.processMatch(fingerprint, checksum, matchingFingerprint, foundFingerprints)
+		}
 	}
 
 	comparer.collectMissingFiles(cache, foundFingerprints)
@@ -83,14 +160,55 @@ func (comparer *Comparer) processMatch(
 	if matchingFingerprint == nil {
 		comparer.Report.AddNewFile(fingerprint.Filename)
 	} else {
-		comparer.Db.AddNamePair(&dal.NamePair{fingerprint.Filename, matchingFingerprint.Filename})
-		fingerprint.CreatedAt = matchingFingerprint.CreatedAt
-		fingerprint.Creator = matchingFingerprint.Creator
-		fingerprint.Note = matchingFingerprint.Note
+		if fingerprint.Filename == matchingFingerprint.Filename ||
+			!comparer.matcher.Matches(fingerprint.Filename, matchingFingerprint.Filename) {
+			comparer.Db.AddNamePair(&dal.NamePair{NewName: fingerprint.Filename, OldName: matchingFingerprint.Filename})
+		}
+		carryOverAnnotations(fingerprint, matchingFingerprint)
 		foundFingerprints[checksum] = true
 	}
 }
 
+// carryOverAnnotations Copies the creation, the note, the tags and the metadata of the stored version of a file to its
+// new fingerprint.
+func carryOverAnnotations(fingerprint *dal.Fingerprint, previous *dal.Fingerprint) {
+
+	fingerprint.CreatedAt = previous.CreatedAt
+	fingerprint.Creator = previous.Creator
+	fingerprint.Note = previous.Note
+	fingerprint.Tags = previous.Tags
+	fingerprint.Metadata = previous.Metadata
+}
+
+// processChange Reports a file whose stored version has block hashes as appended or modified. The blocks are compared
+// at the block size and with the algorithm of the stored version, the file is hashed again if they differ.
+func (comparer *Comparer) processChange(
+	fingerprint *dal.Fingerprint, previous *dal.Fingerprint, foundFingerprints map[string]bool) {
+
+	fullPath := comparer.getFullPath(fingerprint.Filename)
+	current := fingerprint
+	if fingerprint.BlockSize != previous.BlockSize || fingerprint.Algorithm != previous.Algorithm {
+		hasher := common.NewHasher(previous.Algorithm)
+		hasher.SetBlockSize(previous.BlockSize)
+		comparer.readOptions.Apply(&hasher)
+		rehashed := *fingerprint
+		rehashed.Algorithm = previous.Algorithm
+		rehashed.BlockSize = previous.BlockSize
+		_, rehashed.Blocks = hasher.CalculateChecksumWithBlocks(fullPath)
+		current = &rehashed
+	}
+
+	if common.IsAppendedTo(fullPath, current, previous) {
+		comparer.Report.AddAppendedFile(fingerprint.Filename)
+	} else {
+		ranges := common.FindChangedRanges(previous.Blocks, current.Blocks, previous.BlockSize, previous.Size, current.Size)
+		comparer.Report.AddModifiedFile(fingerprint.Filename, ranges)
+	}
+
+	carryOverAnnotations(fingerprint, previous)
+	foundFingerprints[hex.EncodeToString(previous.Checksum)] = true
+}
+
 func (comparer *Comparer) collectMissingFiles(oldFingerprints map[string]*dal.Fingerprint, foundFingerprints map[string]bool) {
 
 	for hash, fingerprint := range oldFingerprints {
@@ -113,3 +231,18 @@ func buildFingerprintCache(fingerprints *list.List) map[string]*dal.Fingerprint
 
 	return cache
 }
+
+// buildBlockFingerprintCache Maps the match keys of the filenames to the fingerprints having usable block hashes.
+func buildBlockFingerprintCache(fingerprints *list.List, matcher util.PathMatcher) map[string]*dal.Fingerprint {
+
+	var cache = make(map[string]*dal.Fingerprint)
+
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if common.HasValidBlocks(fingerprint) {
+			cache[matcher.GetMatchKey(fingerprint.Filename)] = fingerprint
+		}
+	}
+
+	return cache
+}
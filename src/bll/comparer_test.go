@@ -1,10 +1,14 @@
 package bll
 
 import (
+	"bytes"
 	"container/list"
+	"fmr/bll/common"
 	"fmr/bll/testutil"
 	"fmr/dal"
 	"fmr/util"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -14,6 +18,10 @@ func TestComparer(t *testing.T) {
 
 	t.Run("Compare_AllFields", testComparerCompareAllFields)
 	t.Run("Compare_NewAndMissingFiles", testComparerCompareNewAndMissingFiles)
+	t.Run("Compare_TagsAndMetadata", testComparerCompareTagsAndMetadata)
+	t.Run("Compare_Attributes", testComparerCompareAttributes)
+	t.Run("Compare_AppendedAndModified", testComparerCompareAppendedAndModified)
+	t.Run("Compare_OtherBlockSize", testComparerCompareOtherBlockSize)
 
 	tearDownComparerTests()
 }
@@ -69,6 +77,114 @@ func testComparerCompareNewAndMissingFiles(t *testing.T) {
 	assertComparerNewFiles(t, &comparer)
 }
 
+func testComparerCompareTagsAndMetadata(t *testing.T) {
+
+	// Arrange.
+	memoryDatabase := getBaselineDatabaseForNewAndMissingComparison()
+	baseline := findFingerprint(memoryDatabase, "test.txt")
+	baseline.Tags = []string{"greeting"}
+	baseline.Metadata = map[string]string{"owner": "alice"}
+	testPath := testHelper.GetTestDirectory("newandmissing")
+	comparer := NewComparer(memoryDatabase, testPath, testPath)
+
+	// Act.
+	comparer.Compare("crc32")
+
+	// Assert.
+	renamed := findFingerprint(memoryDatabase, "test2.txt")
+	if renamed == nil || len(renamed.Tags) != 1 || renamed.Metadata["owner"] != "alice" {
+		t.Error("Tags and metadata should be carried over to the renamed file.")
+	}
+}
+
+func testComparerCompareAttributes(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("attributes")
+	testHelper.CreateTestFileWithContent("attributes/config.txt", "debug=false")
+	testPath := testHelper.GetTestDirectory("attributes")
+	hasher := common.NewHasher("crc32")
+	hasher.SetCaptureAttributes(true)
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprints(hasher.CalculateFingerprints(testPath, "", []string{"config.txt"}))
+	testHelper.CreateTestFileWithContent("attributes/config.txt", "debug=true")
+	testHelper.CreateTestFileWithContent("attributes/new.txt", "Hello World!")
+	comparer := NewComparer(memoryDatabase, testPath, testPath)
+
+	// Act.
+	comparer.Compare("crc32")
+
+	// Assert.
+	for _, filename := range []string{"config.txt", "new.txt"} {
+		fingerprint := findFingerprint(memoryDatabase, filename)
+		if fingerprint == nil || fingerprint.Mode == "" {
+			t.Errorf("The attributes should be stored when the snapshot has them: %q.", filename)
+		}
+	}
+}
+
+func testComparerCompareAppendedAndModified(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("blocks")
+	testHelper.CreateTestFileWithContent("blocks/server.log", "first line\n")
+	testHelper.CreateTestFileWithContent("blocks/disk.img", "0123456789abcdefghij")
+	testPath := testHelper.GetTestDirectory("blocks")
+	hasher := common.NewHasher("sha256")
+	hasher.SetBlockSize(4)
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprints(hasher.CalculateFingerprints(testPath, "", []string{"server.log", "disk.img"}))
+	findFingerprint(memoryDatabase, "disk.img").Note = "keepme"
+	findFingerprint(memoryDatabase, "disk.img").Tags = []string{"raw"}
+	testHelper.CreateTestFileWithContent("blocks/server.log", "first line\nsecond line\n")
+	testHelper.CreateTestFileWithContent("blocks/disk.img", "0123456789abcdeXghij")
+	comparer := NewComparer(memoryDatabase, testPath, testPath)
+	comparer.SetBlockSize(4)
+
+	// Act.
+	comparer.Compare("sha256")
+
+	// Assert.
+	if !testHelper.HasStringItems(comparer.Report.AppendedFiles, "server.log") {
+		t.Error("File should be marked as appended: \"server.log\".")
+	}
+	if !testHelper.HasStringItems(comparer.Report.ModifiedFiles, "disk.img") {
+		t.Error("File should be marked as modified: \"disk.img\".")
+	}
+	if comparer.Report.NewFiles.Len() != 0 || comparer.Report.MissingFiles.Len() != 0 {
+		t.Error("Changed files should not be reported as new or missing.")
+	}
+	if modified := findFingerprint(memoryDatabase, "disk.img"); modified.Note != "keepme" || len(modified.Tags) != 1 {
+		t.Error("The note and the tags should be carried over to the modified file.")
+	}
+}
+
+func testComparerCompareOtherBlockSize(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("otherblocks")
+	testHelper.CreateTestFileWithContent("otherblocks/disk.img", "0123456789abcdefghij")
+	testPath := testHelper.GetTestDirectory("otherblocks")
+	hasher := common.NewHasher("sha256")
+	hasher.SetBlockSize(4)
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprints(hasher.CalculateFingerprints(testPath, "", []string{"disk.img"}))
+	testHelper.CreateTestFileWithContent("otherblocks/disk.img", "0123456789abcdeXghij")
+	comparer := NewComparer(memoryDatabase, testPath, testPath)
+	comparer.SetBlockSize(8)
+	output := new(bytes.Buffer)
+	util.ConfigureLog(output, util.LevelInfo, util.LogFormatText)
+	defer util.ConfigureLog(os.Stderr, util.LevelInfo, util.LogFormatText)
+
+	// Act.
+	comparer.Compare("sha1")
+
+	// Assert.
+	if comparer.Report.ModifiedFiles.Len() != 1 || !strings.Contains(output.String(), "Modified: disk.img (bytes 12-15)") {
+		t.Errorf("The changed block should be found at the stored block size: %s.", output.String())
+	}
+}
+
 func tearDownComparerTests() {
 
 	testHelper.CleanUp()
@@ -0,0 +1,45 @@
+package bll
+
+import (
+	"container/list"
+	"fmr/bll/common"
+	"fmr/dal"
+	"fmr/util"
+	"fmt"
+)
+
+// Converter Stores settings related to copying fingerprints from one database to another, e.g. from a CSV to the
+// extended attributes of the files.
+type Converter struct {
+	Source dal.Database
+	Db     dal.Database
+}
+
+// NewConverter Instantiates a new Converter object.
+func NewConverter(source dal.Database, db dal.Database) Converter {
+
+	return Converter{source, db}
+}
+
+// Convert Loads the source database and saves its entries matching the filter to the target database. Returns the
+// number of entries saved.
+func (converter *Converter) Convert(fpFilter common.FingerprintFilter) int {
+
+	converter.Source.LoadFingerprints()
+
+	fingerprints := list.New()
+	for element := converter.Source.GetFingerprints().Back(); element != nil; element = element.Prev() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if fpFilter.FilterFingerprint(fingerprint) {
+			fingerprints.PushFront(fingerprint)
+		}
+	}
+
+	converter.Db.Clear()
+	converter.Db.AddFingerprints(fingerprints)
+	converter.Db.SaveFingerprints()
+	util.LogInfo(fmt.Sprintf("Summary: %d entries converted.", fingerprints.Len()),
+		util.Field("converted", fingerprints.Len()))
+
+	return fingerprints.Len()
+}
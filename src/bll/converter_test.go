@@ -0,0 +1,79 @@
+package bll
+
+import (
+	"fmr/bll/common"
+	"fmr/bll/testutil"
+	"fmr/dal"
+	"fmr/util"
+	"os"
+	"testing"
+)
+
+func TestConverter(t *testing.T) {
+
+	setupConverterTests()
+
+	t.Run("Convert_Filtered", testConverterConvertFiltered)
+	t.Run("Convert_Xattrs_Renamed", testConverterConvertXattrsRenamed)
+
+	tearDownConverterTests()
+}
+
+func setupConverterTests() {
+
+	testHelper.CreateTestRootDirectory()
+	testHelper.CreateTestDirectory("photos")
+	testHelper.CreateTestFileWithContent("photos/a.jpg", "Hello World!")
+}
+
+func tearDownConverterTests() {
+
+	testHelper.CleanUp()
+}
+
+func testConverterConvertFiltered(t *testing.T) {
+
+	// Arrange.
+	source := dal.NewMemoryDatabase()
+	source.AddFingerprint(testutil.CreateSparseFingerprint("photos/a.jpg", "1c291ca3", "crc32"))
+	source.AddFingerprint(testutil.CreateSparseFingerprint("notes/b.txt", "6b24cc6a", "crc32"))
+	output := dal.NewMemoryDatabase()
+	converter := NewConverter(source, output)
+
+	// Act.
+	count := converter.Convert(common.NewFingerprintFilter("photos/"))
+
+	// Assert.
+	if count != 1 || output.GetFingerprints().Len() != 1 {
+		t.Errorf("Only the entry matching the filter should be converted: %d.", count)
+	}
+}
+
+func testConverterConvertXattrsRenamed(t *testing.T) {
+
+	if err := util.SetXattr(testHelper.GetTestPath("photos/a.jpg"), "user.test", []byte("1")); err != nil {
+		t.Skipf("Extended attributes are not supported: %s.", err)
+	}
+
+	// Arrange.
+	source := dal.NewMemoryDatabase()
+	source.AddFingerprint(testutil.CreateSparseFingerprint("photos/a.jpg", "1c291ca3", "crc32"))
+	directory := testHelper.GetTestPath("photos")
+	output := dal.NewMemoryDatabase()
+
+	// Act.
+	toXattrs := NewConverter(source, dal.NewXattrDatabase(directory, testHelper.GetTestRootDirectory()))
+	toXattrs.Convert(common.NewFingerprintFilter(""))
+	os.Rename(testHelper.GetTestPath("photos/a.jpg"), testHelper.GetTestPath("photos/renamed.jpg"))
+	fromXattrs := NewConverter(dal.NewXattrDatabase(directory, testHelper.GetTestRootDirectory()), output)
+	fromXattrs.Convert(common.NewFingerprintFilter(""))
+
+	// Assert.
+	if output.GetFingerprints().Len() != 1 {
+		t.Fatalf("Wrong number of converted entries: %d.", output.GetFingerprints().Len())
+	}
+	fingerprint := output.GetFingerprints().Front().Value.(*dal.Fingerprint)
+	if fingerprint.Filename != "photos/renamed.jpg" || fingerprint.Algorithm != "crc32" {
+		t.Errorf("The checksum should follow the renamed file: %s.", fingerprint.Filename)
+	}
+}
@@ -0,0 +1,130 @@
+package bll
+
+import (
+	"container/list"
+	"fmr/bll/common"
+	"fmr/bll/report"
+	"fmr/dal"
+	"fmr/util"
+	"fmt"
+)
+
+// CrossChecker Stores settings related to verifying the entries of a database on several roots.
+type CrossChecker struct {
+	Db          dal.Database
+	Roots       []string
+	Report      *report.CrossCheckReport
+	progress    common.ProgressListener
+	matcher     util.PathMatcher
+	readOptions common.ReadOptions
+}
+
+// NewCrossChecker Instantiates a new CrossChecker object. Each root holds a copy of the files listed in the database;
+// entries with fewer than minCopies good copies are flagged.
+func NewCrossChecker(db dal.Database, roots []string, minCopies int) CrossChecker {
+
+	report := report.NewCrossCheckReport(roots, minCopies)
+
+	return CrossChecker{db, roots, report, common.NullProgressListener{}, util.NewPathMatcher(false, false),
+		common.ReadOptions{}}
+}
+
+// SetProgressListener Sets the listener that will be notified about the progress of the verification of each root.
+func (checker *CrossChecker) SetProgressListener(listener common.ProgressListener) {
+
+	checker.progress = listener
+}
+
+// SetReadOptions Sets how the copies are read. The options are passed to the verification of each root, so a rate
+// limit is shared by all of them.
+func (checker *CrossChecker) SetReadOptions(options common.ReadOptions) {
+
+	checker.readOptions = options
+}
+
+// SetPathMatcher Sets how the stored filenames are matched with the names on disk on each root.
+func (checker *CrossChecker) SetPathMatcher(matcher util.PathMatcher) {
+
+	checker.matcher = matcher
+}
+
+// CrossCheck Verifies the entries matching the filter on every root and reports the status of each copy. Returns
+// false if any entry has fewer good copies than required.
+func (checker *CrossChecker) CrossCheck(fpFilter common.FingerprintFilter) bool {
+
+	checker.Db.LoadFingerprints()
+
+	statuses := make(map[string][]string)
+	for index, root := range checker.Roots {
+		util.LogInfo(fmt.Sprintf("Verifying copy %d: %s", index+1, root),
+			util.Field("copy", index+1), util.Field("root", root))
+		verifier := NewVerifier(checker.Db, root)
+		verifier.SetProgressListener(checker.progress)
+		verifier.SetPathMatcher(checker.matcher)
+		verifier.SetReadOptions(checker.readOptions)
+		verifier.verifyEntries(false, fpFilter)
+		checker.addStatuses(statuses, index, verifier.Report)
+	}
+
+	added := make(map[string]bool)
+	for element := checker.Db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if fpFilter.FilterFingerprint(fingerprint) && !added[fingerprint.Filename] {
+			checker.Report.AddEntry(fingerprint.Filename, statuses[fingerprint.Filename])
+			added[fingerprint.Filename] = true
+		}
+	}
+
+	checker.Report.LogMatrix()
+	checker.Report.LogSummary()
+
+	return checker.Report.UnderReplicatedFiles.Len() == 0
+}
+
+// addStatuses Sets the status of the copies on the root with the given index from its verification report. Copies
+// neither corrupt nor missing are good. A file stored with several algorithms has a single status per root, the worse
+// of its entries.
+func (checker *CrossChecker) addStatuses(
+	statuses map[string][]string, rootIndex int, verificationReport *report.VerificationReport) {
+
+	corruptFiles := toStringSet(verificationReport.CorruptFiles)
+	missingFiles := toStringSet(verificationReport.MissingFiles)
+
+	for element := checker.Db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		filename := element.Value.(*dal.Fingerprint).Filename
+		if _, ok := statuses[filename]; !ok {
+			statuses[filename] = make([]string, len(checker.Roots))
+		}
+
+		status := report.CopyGood
+		switch {
+		case missingFiles[filename]:
+			status = report.CopyMissing
+		case corruptFiles[filename]:
+			status = report.CopyCorrupt
+		}
+		statuses[filename][rootIndex] = worseStatus(statuses[filename][rootIndex], status)
+	}
+}
+
+// worseStatus Returns the worse of two statuses of a copy: missing, then corrupt, then good. An empty status is
+// ignored.
+func worseStatus(first string, second string) string {
+
+	rank := map[string]int{"": 0, report.CopyGood: 1, report.CopyCorrupt: 2, report.CopyMissing: 3}
+	if rank[second] > rank[first] {
+		return second
+	}
+
+	return first
+}
+
+func toStringSet(items *list.List) map[string]bool {
+
+	set := make(map[string]bool)
+	for element := items.Front(); element != nil; element = element.Next() {
+		set[element.Value.(string)] = true
+	}
+
+	return set
+}
@@ -0,0 +1,109 @@
+package bll
+
+import (
+	"fmr/bll/common"
+	"fmr/bll/report"
+	"fmr/dal"
+	"os"
+	"testing"
+)
+
+func TestCrossChecker(t *testing.T) {
+
+	setupCrossCheckerTests()
+
+	t.Run("CrossCheck", testCrossCheckerCrossCheck)
+	t.Run("CrossCheck_Algorithms", testCrossCheckerCrossCheckAlgorithms)
+
+	tearDownCrossCheckerTests()
+}
+
+func setupCrossCheckerTests() {
+
+	testHelper.CreateTestRootDirectory()
+
+	for _, root := range []string{"primary", "backup1", "backup2"} {
+		testHelper.CreateTestDirectory(root)
+		testHelper.CreateTestFileWithContent(root+"/complete.txt", "Complete")
+		testHelper.CreateTestFileWithContent(root+"/damaged.txt", "Damaged")
+		testHelper.CreateTestFileWithContent(root+"/lost.txt", "Lost")
+	}
+}
+
+func testCrossCheckerCrossCheck(t *testing.T) {
+
+	// Arrange.
+	primaryPath := testHelper.GetTestDirectory("primary")
+	memoryDatabase := dal.NewMemoryDatabase()
+	calculator := NewCalculator(memoryDatabase, primaryPath, "sha256", primaryPath)
+	calculator.Calculate(false)
+	testHelper.CreateTestFileWithContent("backup1/damaged.txt", "Dam4ged")
+	os.Remove(testHelper.GetTestPath("backup1/lost.txt"))
+	os.Remove(testHelper.GetTestPath("backup2/lost.txt"))
+	roots := []string{primaryPath, testHelper.GetTestDirectory("backup1"), testHelper.GetTestDirectory("backup2")}
+	checker := NewCrossChecker(memoryDatabase, roots, 2)
+
+	// Act.
+	completed := checker.CrossCheck(common.NewFingerprintFilter(""))
+
+	// Assert.
+	expected := map[string][]string{
+		"complete.txt": {report.CopyGood, report.CopyGood, report.CopyGood},
+		"damaged.txt":  {report.CopyGood, report.CopyCorrupt, report.CopyGood},
+		"lost.txt":     {report.CopyGood, report.CopyMissing, report.CopyMissing},
+	}
+	if len(checker.Report.Entries) != len(expected) {
+		t.Fatalf("Wrong number of entries: %d.", len(checker.Report.Entries))
+	}
+	for _, entry := range checker.Report.Entries {
+		for index, status := range expected[entry.Filename] {
+			if entry.Statuses[index] != status {
+				t.Errorf("Wrong status of copy %d of %s: %s.", index+1, entry.Filename, entry.Statuses[index])
+			}
+		}
+	}
+	if completed || checker.Report.UnderReplicatedFiles.Len() != 1 ||
+		!testHelper.HasStringItems(checker.Report.UnderReplicatedFiles, "lost.txt") {
+		t.Error("Only \"lost.txt\" should have too few good copies.")
+	}
+}
+
+func testCrossCheckerCrossCheckAlgorithms(t *testing.T) {
+
+	// Arrange.
+	for _, root := range []string{"mixed1", "mixed2"} {
+		testHelper.CreateTestDirectory(root)
+		testHelper.CreateTestFileWithContent(root+"/both.txt", "Old")
+	}
+	mixedPath := testHelper.GetTestDirectory("mixed1")
+	memoryDatabase := dal.NewMemoryDatabase()
+	hasher := common.NewHasher("md5")
+	memoryDatabase.AddFingerprint(hasher.CalculateFingerprint(mixedPath, "", "both.txt"))
+	testHelper.CreateTestFileWithContent("mixed1/both.txt", "New")
+	testHelper.CreateTestFileWithContent("mixed2/both.txt", "New")
+	hasher = common.NewHasher("sha256")
+	memoryDatabase.AddFingerprint(hasher.CalculateFingerprint(mixedPath, "", "both.txt"))
+	roots := []string{mixedPath, testHelper.GetTestDirectory("mixed2")}
+	checker := NewCrossChecker(memoryDatabase, roots, 1)
+
+	// Act.
+	completed := checker.CrossCheck(common.NewFingerprintFilter(""))
+
+	// Assert.
+	if len(checker.Report.Entries) != 1 {
+		t.Fatalf("A file stored with two algorithms should have one entry: %d.", len(checker.Report.Entries))
+	}
+	for index, status := range checker.Report.Entries[0].Statuses {
+		if status != report.CopyCorrupt {
+			t.Errorf("The worse status should win for copy %d: %s.", index+1, status)
+		}
+	}
+	if completed {
+		t.Error("\"both.txt\" should have too few good copies.")
+	}
+}
+
+func tearDownCrossCheckerTests() {
+
+	testHelper.CleanUp()
+}
@@ -1,6 +1,7 @@
 package bll
 
 import (
+	"bufio"
 	"encoding/hex"
 	"fmr/bll/common"
 	"fmr/dal"
@@ -8,6 +9,9 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // Exporter Exports checksums from CSV.
@@ -16,6 +20,7 @@ type Exporter struct {
 	OutputDirectory string
 	BasePath        string
 	fileWriters     fileHandlers
+	mtree           bool
 }
 
 type fileHandlers struct {
@@ -32,13 +37,24 @@ func NewExporter(db dal.Database, outputDirectory string, basePath string) Expor
 	basePath = util.NormalizePath(basePath)
 	fileHandlers := fileHandlers{nil, nil, nil, nil, nil}
 
-	return Exporter{db, outputDirectory, basePath, fileHandlers}
+	return Exporter{db, outputDirectory, basePath, fileHandlers, false}
+}
+
+// SetMtree Sets whether an mtree specification is written instead of the checksum files.
+func (exporter *Exporter) SetMtree(mtree bool) {
+
+	exporter.mtree = mtree
 }
 
 // Convert Converts checksum data to formats that third party utilities understand.
 func (exporter *Exporter) Convert(fpFilter common.FingerprintFilter) {
 
 	exporter.Db.LoadFingerprints()
+	if exporter.mtree {
+		exporter.exportMtree(fpFilter)
+		return
+	}
+
 	defer exporter.closeFiles()
 	exporter.exportChecksums(fpFilter)
 }
@@ -112,3 +128,100 @@ func (exporter *Exporter) openOutputFile(writer **os.File, extension string) {
 		*writer = newWriter
 	}
 }
+
+// exportMtree Writes the entries matching the filter to an mtree specification in full path format, one entry per file
+// with all of its digests. The paths are relative to the base path of the database, the directory to check with
+// "mtree -f <spec> -p <directory>"; the parent directories are listed before the files. Archive members are skipped.
+func (exporter *Exporter) exportMtree(fpFilter common.FingerprintFilter) {
+
+	entries := make(map[string][]*dal.Fingerprint)
+	skippedMembers := 0
+	for element := exporter.Db.GetFingerprints().Back(); element != nil; element = element.Prev() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if !fpFilter.FilterFingerprint(fingerprint) {
+			continue
+		}
+		if _, _, isMember := common.SplitArchivePath(fingerprint.Filename, exporter.getFullPath); isMember {
+			skippedMembers++
+			continue
+		}
+		name := strings.TrimPrefix(fingerprint.Filename, "/")
+		entries[name] = append(entries[name], fingerprint)
+	}
+	if skippedMembers > 0 {
+		util.LogInfo(fmt.Sprintf("Skipped %d archive member(s), mtree cannot check them.", skippedMembers),
+			util.Field("skipped", skippedMembers))
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fullPath := path.Join(exporter.OutputDirectory, "Checksum"+dal.MTREEEXT)
+	file, err := os.Create(fullPath)
+	util.CheckErr(err, "Failed to open output file "+fullPath)
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	writer.WriteString("#mtree v2.0\n")
+	writeMtreeEntry(writer, "", []string{"type=dir"})
+	directories := map[string]bool{".": true}
+	for _, name := range names {
+		writeMtreeDirectories(writer, path.Dir(name), directories)
+		writeMtreeEntry(writer, name, createMtreeKeywords(entries[name]))
+	}
+	util.CheckErr(writer.Flush(), "Failed to write output file "+fullPath)
+}
+
+// getFullPath Returns the path of the file on disk from its stored name.
+func (exporter *Exporter) getFullPath(filename string) string {
+
+	return path.Join(exporter.BasePath, filename)
+}
+
+// writeMtreeDirectories Writes the entries of the given directory and its parents unless they are already written.
+func writeMtreeDirectories(writer *bufio.Writer, directory string, directories map[string]bool) {
+
+	if directories[directory] {
+		return
+	}
+
+	writeMtreeDirectories(writer, path.Dir(directory), directories)
+	writeMtreeEntry(writer, directory, []string{"type=dir"})
+	directories[directory] = true
+}
+
+// createMtreeKeywords Describes a file with the fields of its fingerprints. Unknown sizes and CRC32 checksums, which
+// mtree cannot check, are left out.
+func createMtreeKeywords(fingerprints []*dal.Fingerprint) []string {
+
+	first := fingerprints[0]
+	keywords := []string{"type=file"}
+	if first.LinkTarget != "" {
+		keywords = []string{"type=link", "link=" + encodeMtreePath(first.LinkTarget)}
+	}
+	if mode, err := strconv.ParseUint(first.Mode, 8, 32); err == nil {
+		keywords = append(keywords, fmt.Sprintf("mode=%#o", mode))
+	}
+	if first.UID != "" {
+		keywords = append(keywords, "uid="+first.UID, "gid="+first.GID)
+	}
+	if first.Size > 0 && first.LinkTarget == "" {
+		keywords = append(keywords, fmt.Sprintf("size=%d", first.Size))
+	}
+	if modifiedAt := formatMtreeTime(first.ModifiedAt); modifiedAt != "" {
+		keywords = append(keywords, "time="+modifiedAt)
+	}
+
+	digests := make([]string, 0)
+	for _, fingerprint := range fingerprints {
+		if keyword, found := mtreeDigestKeywords[fingerprint.Algorithm]; found && fingerprint.LinkTarget == "" {
+			digests = append(digests, keyword+"="+hex.EncodeToString(fingerprint.Checksum))
+		}
+	}
+	sort.Strings(digests)
+
+	return append(keywords, digests...)
+}
@@ -5,6 +5,8 @@ import (
 	"fmr/bll/common"
 	"fmr/bll/testutil"
 	"fmr/dal"
+	"io/ioutil"
+	"strings"
 	"testing"
 )
 
@@ -29,6 +31,7 @@ func TestExporter(t *testing.T) {
 	t.Run("Convert_EmptyFilter", testExporterConvertEmptyFilter)
 	t.Run("Convert_NameFilter", testExporterConvertFilterName)
 	t.Run("Convert_NameAlgFilter", testExporterConvertFilterNameAlg)
+	t.Run("Convert_Mtree", testExporterConvertMtree)
 
 	tearDownExporterTests()
 }
@@ -68,6 +71,44 @@ func testExporterConvertFilterNameAlg(t *testing.T) {
 	testExporterWithFilter(t, fpFilter, expectedFingerprints)
 }
 
+func testExporterConvertMtree(t *testing.T) {
+
+	// Arrange.
+	fingerprint := testutil.CreateSparseFingerprint(
+		"docs/2019/a report.txt", "357ad3058f7b5b71e0488df08ed1f6dfcdde722f298bdd9a903b1c8121d9db50", "sha256")
+	fingerprint.Size = 42
+	fingerprint.ModifiedAt = "2020-01-01T00:00:00.5Z"
+	fingerprint.Mode = "0640"
+	memoryDatabase1 := dal.NewMemoryDatabase()
+	memoryDatabase1.AddFingerprints(getFingerprintsToExport())
+	memoryDatabase1.AddFingerprint(fingerprint)
+	memoryDatabase2 := dal.NewMemoryDatabase()
+	testPath := testHelper.GetTestPath("tmp")
+	exporter := NewExporter(memoryDatabase1, testPath, "")
+	exporter.SetMtree(true)
+	importer := NewImporter(memoryDatabase2, testPath, testHelper.GetTestPath("out.csv"))
+
+	// Act.
+	exporter.Convert(common.NewFingerprintFilter(""))
+	importer.Convert()
+	content, _ := ioutil.ReadFile(testHelper.GetTestPath("tmp/Checksum.mtree"))
+	testHelper.RemoveTestDirectory("tmp")
+	testHelper.CreateTestDirectory("tmp")
+
+	// Assert.
+	expectedLine := "./docs/2019/a\\040report.txt type=file mode=0640 size=42 time=1577836800.500000000 sha256digest="
+	if !strings.Contains(string(content), "./docs type=dir\n./docs/2019 type=dir\n"+expectedLine) {
+		t.Errorf("Wrong mtree specification:\n%s", content)
+	}
+	if memoryDatabase2.GetFingerprints().Len() != 5 {
+		t.Errorf("Wrong number of database entries: %d (expected: %d).", memoryDatabase2.GetFingerprints().Len(), 5)
+	}
+	imported := findFingerprint(memoryDatabase2, "docs/2019/a report.txt")
+	if imported == nil || imported.Size != 42 || imported.ModifiedAt != fingerprint.ModifiedAt || imported.Mode != "0640" {
+		t.Errorf("The entry is not exported and imported correctly: %v.", imported)
+	}
+}
+
 func tearDownExporterTests() {
 
 	testHelper.CleanUp()
@@ -2,7 +2,9 @@ package bll
 
 import (
 	"bufio"
+	"encoding/binary"
 	"encoding/hex"
+	"fmr/bll/common"
 	"fmr/bll/report"
 	"fmr/dal"
 	"fmr/util"
@@ -10,6 +12,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -25,17 +28,18 @@ type Importer struct {
 }
 
 type importEntryPatterns struct {
-	patternCrc32  *regexp.Regexp
-	patternMd5    *regexp.Regexp
-	patternSha1   *regexp.Regexp
-	patternSha256 *regexp.Regexp
-	patternSha512 *regexp.Regexp
+	patternCrc32   *regexp.Regexp
+	patternMd5     *regexp.Regexp
+	patternSha1    *regexp.Regexp
+	patternSha256  *regexp.Regexp
+	patternSha512  *regexp.Regexp
+	patternRpmDump *regexp.Regexp
 }
 
 // NewImporter Instantiates a new Importer object.
 func NewImporter(db dal.Database, inputDirectory string, outputChecksums string) Importer {
 
-	patterns := importEntryPatterns{nil, nil, nil, nil, nil}
+	patterns := importEntryPatterns{nil, nil, nil, nil, nil, nil}
 	fingerprintProto := new(dal.Fingerprint)
 	report := report.NewImportReport()
 
@@ -67,7 +71,20 @@ func (importer *Importer) loadDataFromFile(filePath string) {
 
 	extension := path.Ext(filePath)
 
-	if extension == dal.CRC32EXT {
+	if strings.ToLower(extension) == dal.ZIPEXT {
+		importer.fingerprintProto.Algorithm = dal.CRC32
+		importer.parseZipDirectory(filePath)
+	} else if path.Base(filePath) == dal.DEBMD5SUMS || extension == dal.DEBMD5SUMSEXT {
+		importer.fingerprintProto.Algorithm = dal.MD5
+		compilePattern(&importer.patterns.patternMd5, dal.PATTERNCOMMON, dal.MD5LEN)
+		importer.parseFile(filePath, importer.patterns.patternMd5, '*')
+	} else if extension == dal.MTREEEXT {
+		importer.parseMtreeFile(filePath)
+	} else if extension == dal.RPMDUMPEXT {
+		importer.fingerprintProto.Algorithm = dal.SHA256
+		compilePattern(&importer.patterns.patternRpmDump, dal.PATTERNRPMDUMP, dal.SHA256LEN)
+		importer.parseRpmDumpFile(filePath)
+	} else if extension == dal.CRC32EXT {
 		importer.fingerprintProto.Algorithm = dal.CRC32
 		compilePattern(&importer.patterns.patternCrc32, dal.PATTERNCRC32, dal.CRC32LEN)
 		importer.parseFile(filePath, importer.patterns.patternCrc32, ';')
@@ -92,15 +109,178 @@ func (importer *Importer) loadDataFromFile(filePath string) {
 
 func (importer *Importer) parseFile(filePath string, pattern *regexp.Regexp, commentChar byte) {
 
+	idxFilename, idxChecksum := getFilenameChecksumIndices(pattern.SubexpNames())
+
+	importer.parseLines(filePath, func(line string) bool {
+		return importer.parseLine(line, pattern, commentChar, idxFilename, idxChecksum)
+	})
+}
+
+// parseRpmDumpFile Imports the output of "rpm -q --dump". Directories, symbolic links and other entries without
+// content have a digest of zeros and are skipped. The leading slash is removed from the paths.
+func (importer *Importer) parseRpmDumpFile(filePath string) {
+
+	pattern := importer.patterns.patternRpmDump
+
+	importer.parseLines(filePath, func(line string) bool {
+		if len(strings.TrimSpace(line)) <= 0 {
+			return true
+		}
+
+		matches := pattern.FindStringSubmatch(line)
+		if matches == nil {
+			return false
+		}
+		if strings.Trim(matches[4], "0") == "" {
+			return true
+		}
+
+		checksum, err := hex.DecodeString(matches[4])
+		if err != nil {
+			return false
+		}
+		size, errSize := strconv.ParseInt(matches[2], 10, 64)
+		modifiedAt, errTime := strconv.ParseInt(matches[3], 10, 64)
+		if errSize != nil || errTime != nil {
+			return false
+		}
+
+		fingerprint := importer.cloneFingerprintProto(strings.TrimPrefix(matches[1], "/"), checksum)
+		fingerprint.Size = size
+		fingerprint.ModifiedAt = time.Unix(modifiedAt, 0).UTC().Format(time.RFC3339Nano)
+		importer.Db.AddFingerprint(fingerprint)
+
+		return true
+	})
+}
+
+// parseMtreeFile Imports an mtree specification: a fingerprint is added for each digest of a file, along with its size,
+// modification time, mode and ownership. Symbolic links are imported with their target, other types are skipped.
+func (importer *Importer) parseMtreeFile(filePath string) {
+
 	file, err := os.Open(filePath)
 	util.CheckErr(err, "Cannot open file "+filePath+".")
 	defer file.Close()
 
-	idxFilename, idxChecksum := getFilenameChecksumIndices(pattern.SubexpNames())
+	err = parseMtreeSpec(file, func(entry mtreeEntry) {
+		if !importer.addMtreeEntry(entry) {
+			importer.Report.IncreaseInvalidEntryCount(filePath)
+		}
+	}, func(line string) {
+		importer.Report.IncreaseInvalidEntryCount(filePath)
+	})
+	util.CheckErr(err, "Error reading file "+filePath+".")
+
+	importer.Report.LogSummaryForFile(filePath)
+}
+
+func (importer *Importer) addMtreeEntry(entry mtreeEntry) bool {
+
+	switch entry.keywords["type"] {
+	case "link":
+		target, err := decodeMtreePath(entry.keywords["link"])
+		if err != nil || target == "" {
+			return false
+		}
+		hasher := common.NewHasher(dal.SHA256)
+		fingerprint := importer.createMtreeFingerprint(entry, dal.SHA256, hasher.CalculateLinkChecksum(target))
+		if fingerprint == nil {
+			return false
+		}
+		fingerprint.LinkTarget = target
+		importer.Db.AddFingerprint(fingerprint)
+		return true
+	case "file", "":
+	default:
+		return true
+	}
+
+	digests := make(map[string]string)
+	for keyword, value := range entry.keywords {
+		if algorithm, isDigest := mtreeDigestAliases[keyword]; isDigest {
+			digests[algorithm] = value
+		}
+	}
+
+	for algorithm, digest := range digests {
+		checksum, err := hex.DecodeString(digest)
+		if err != nil {
+			return false
+		}
+		fingerprint := importer.createMtreeFingerprint(entry, algorithm, checksum)
+		if fingerprint == nil {
+			return false
+		}
+		importer.Db.AddFingerprint(fingerprint)
+	}
+
+	return true
+}
+
+// createMtreeFingerprint Creates a fingerprint from the keywords of an mtree entry. Returns nil if a keyword has an
+// invalid value.
+func (importer *Importer) createMtreeFingerprint(entry mtreeEntry, algorithm string, checksum []byte) *dal.Fingerprint {
+
+	fingerprint := importer.cloneFingerprintProto(entry.path, checksum)
+	fingerprint.Algorithm = algorithm
+
+	var err error
+	if value, found := entry.keywords["size"]; found {
+		if fingerprint.Size, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil
+		}
+	}
+	if value, found := entry.keywords["time"]; found {
+		if fingerprint.ModifiedAt, err = parseMtreeTime(value); err != nil {
+			return nil
+		}
+	}
+	if value, found := entry.keywords["mode"]; found {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return nil
+		}
+		fingerprint.Mode = fmt.Sprintf("%04o", mode&07777)
+	}
+	fingerprint.UID = entry.keywords["uid"]
+	fingerprint.GID = entry.keywords["gid"]
+
+	return fingerprint
+}
+
+// parseZipDirectory Imports the CRC32 of the members stored in the central directory of a ZIP archive, so that the
+// extracted files can be verified. The entries are named after the members, the data of the archive is not read.
+func (importer *Importer) parseZipDirectory(filePath string) {
+
+	members, err := common.ListZipMembers(filePath)
+	if err != nil {
+		util.LogWarn(fmt.Sprintf("Cannot read the central directory of %s: %s.", filePath, err), util.Field("file", filePath))
+		importer.Report.IncreaseInvalidEntryCount(filePath)
+		return
+	}
+
+	for _, member := range members {
+		checksum := make([]byte, 4)
+		binary.BigEndian.PutUint32(checksum, member.CRC32)
+
+		fingerprint := importer.cloneFingerprintProto(member.Name, checksum)
+		fingerprint.Size = member.Size
+		if !member.ModifiedAt.IsZero() {
+			fingerprint.ModifiedAt = member.ModifiedAt.UTC().Format(time.RFC3339Nano)
+		}
+		importer.Db.AddFingerprint(fingerprint)
+	}
+}
+
+func (importer *Importer) parseLines(filePath string, parseLine func(line string) bool) {
+
+	file, err := os.Open(filePath)
+	util.CheckErr(err, "Cannot open file "+filePath+".")
+	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		if !importer.parseLine(scanner.Text(), pattern, commentChar, idxFilename, idxChecksum) {
+		if !parseLine(scanner.Text()) {
 			importer.Report.IncreaseInvalidEntryCount(filePath)
 		}
 	}
@@ -12,6 +12,7 @@ func TestImporter(t *testing.T) {
 	setupImporterTests()
 
 	t.Run("Convert", testImporterConvert)
+	t.Run("Convert_Mtree", testImporterConvertMtree)
 
 	tearDownImporterTests()
 }
@@ -41,6 +42,16 @@ func setupImporterTests() {
 			"\r\n3c3581a742881b03a7b8f4311a67744e36152a6494806046154e005cd4230a9c7c439e273c4ab811e897f97bf92fa4136bab895b101c8792a7f0e05ecf5 *wrongentry.7z"+
 			"\r\nsomething"+
 			"\r\n312s3581a742881b03a7b8f4311a67744e36152a6494806046154e005cd4230a9c7c439e273c4ab811e897f97bf92fa4136bab895b101c8792a7f0e05ecf5d41 *another_wrong_entry.bad")
+	testHelper.CreateTestDirectory("package")
+	testHelper.CreateTestDirectory("package/DEBIAN")
+	testHelper.CreateTestFileWithContent(
+		"package/DEBIAN/md5sums", "a7b3c9f0a3d1e2f4b5c6d7e8f9a0b1c2  usr/bin/tool\n")
+	testHelper.CreateTestFileWithContent(
+		"package/tool.rpmdump",
+		"/usr/lib/libtool.so 11 1577836800 f7d4c1c7b7a1e8f05c2a8bd2c5e9c2fd4ad4c7e6c1d1f2b4d2ab2f1a9e9c3b1f 0100755 root root 0 0 0 X\n"+
+			"/usr/share/doc/tool 4096 1577836800 0000000000000000000000000000000000000000000000000000000000000000 040755 root root 0 0 0 X\n"+
+			"/usr/broken entry\n")
+	testutil.CreateZipArchive(testHelper.GetTestPath("package/docs.zip"), map[string]string{"docs/hello.txt": "Hello World!"})
 }
 
 func testImporterConvert(t *testing.T) {
@@ -57,16 +68,79 @@ func testImporterConvert(t *testing.T) {
 	importer.Convert()
 
 	// Assert.
-	if memoryDatabase.GetFingerprints().Len() != 8 {
-		t.Errorf("Wrong number of database entries: %d (expected: %d).", memoryDatabase.GetFingerprints().Len(), 8)
+	if memoryDatabase.GetFingerprints().Len() != 11 {
+		t.Errorf("Wrong number of database entries: %d (expected: %d).", memoryDatabase.GetFingerprints().Len(), 11)
 	}
 	testFile := testHelper.GetTestPath("subdir/sh.sha512")
 	if importer.Report.GetInvalidEntryCount(testFile) != 3 {
 		t.Errorf("Wrong number of invalid entries for file \"%s\".", testFile)
 	}
+	testFile = testHelper.GetTestPath("package/tool.rpmdump")
+	if importer.Report.GetInvalidEntryCount(testFile) != 1 {
+		t.Errorf("Wrong number of invalid entries for file \"%s\".", testFile)
+	}
+	rpmFingerprint := findFingerprint(memoryDatabase, "usr/lib/libtool.so")
+	if rpmFingerprint == nil || rpmFingerprint.Size != 11 || rpmFingerprint.ModifiedAt != "2020-01-01T00:00:00Z" {
+		t.Errorf("Wrong size or modification time imported from the RPM file list: %v.", rpmFingerprint)
+	}
+	zipFingerprint := findFingerprint(memoryDatabase, "docs/hello.txt")
+	if zipFingerprint == nil || zipFingerprint.Size != 12 {
+		t.Errorf("Wrong size imported from the ZIP central directory: %v.", zipFingerprint)
+	}
 	testutil.AssertContainsFingerprints(t, memoryDatabase.GetFingerprints(), expectedFingerprints, fieldsToCheck)
 }
 
+func testImporterConvertMtree(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("mtree")
+	testHelper.CreateTestFileWithContent("mtree/tree.mtree", "#mtree v2.0\n"+
+		"/set type=file uid=0 gid=0 mode=0644\n"+
+		". type=dir\n"+
+		"    README size=12 time=1577836800.5 \\\n"+
+		"        sha256digest=7f83b1657ff1fc53b92dc18148a1d65dfc2d4b1fa3d677284addd200126d9069 \\\n"+
+		"        md5digest=ed076287532e86365e841e92bfc50d8c\n"+
+		"bin type=dir mode=0755\n"+
+		"    tool mode=04755 size=3 sha256digest=98ea6e4f216f2fb4b69fff9b3a44842c38686ca685f3f55dc48c5d3fb1107be4\n"+
+		"    latest type=link link=tool\n"+
+		"..\n"+
+		"docs type=dir\n"+
+		"    my\\040notes.txt sha256=87428fc522803d31065e7bce3cf03fe475096631e5e07bbd7a0fde60c4cf25c7\n"+
+		"..\n"+
+		"./full/path.txt sha1digest=15dfaa952a85ad9a458013fa2fc3bdc807d34e7f\n"+
+		"broken size=abc sha256digest=00\n")
+	memoryDatabase := dal.NewMemoryDatabase()
+	importer := NewImporter(memoryDatabase, testHelper.GetTestPath("mtree"), testHelper.GetTestPath("out.csv"))
+
+	// Act.
+	importer.Convert()
+	testHelper.RemoveTestDirectory("mtree")
+
+	// Assert.
+	if memoryDatabase.GetFingerprints().Len() != 6 {
+		t.Errorf("Wrong number of database entries: %d (expected: %d).", memoryDatabase.GetFingerprints().Len(), 6)
+	}
+	if importer.Report.GetInvalidEntryCount(testHelper.GetTestPath("mtree/tree.mtree")) != 1 {
+		t.Error("The entry with an invalid size should be counted as invalid.")
+	}
+	readme := findFingerprint(memoryDatabase, "README")
+	if readme == nil || readme.Size != 12 || readme.ModifiedAt != "2020-01-01T00:00:00.5Z" || readme.Mode != "0644" {
+		t.Errorf("Wrong fields imported from the mtree keywords: %v.", readme)
+	}
+	tool := findFingerprint(memoryDatabase, "bin/tool")
+	if tool == nil || tool.Mode != "4755" || tool.UID != "0" {
+		t.Errorf("Wrong mode or owner imported from the mtree keywords: %v.", tool)
+	}
+	link := findFingerprint(memoryDatabase, "bin/latest")
+	if link == nil || link.LinkTarget != "tool" {
+		t.Errorf("The symbolic link should be imported with its target: %v.", link)
+	}
+	escaped := findFingerprint(memoryDatabase, "docs/my notes.txt")
+	if escaped == nil || findFingerprint(memoryDatabase, "full/path.txt") == nil {
+		t.Error("The escaped and the full paths should be imported.")
+	}
+}
+
 func tearDownImporterTests() {
 
 	testHelper.CleanUp()
@@ -85,7 +159,11 @@ func getExpectedFingerprintsForImport() *list.List {
 		"important.odt",
 		"312c3581a742881b03a7b8f4311a67744e36152a6494806046154e005cd4230a9c7c439e273c4ab811e897f97bf92fa4136bab895b101c8792a7f0e05ecf5d41",
 		"sha512")
-	expectedFingerprints := testutil.CreateList(fp1, fp2, fp3, fp4, fp5, fp6, fp7, fp8)
+	fp9 := testutil.CreateSparseFingerprint("usr/bin/tool", "a7b3c9f0a3d1e2f4b5c6d7e8f9a0b1c2", "md5")
+	fp10 := testutil.CreateSparseFingerprint(
+		"usr/lib/libtool.so", "f7d4c1c7b7a1e8f05c2a8bd2c5e9c2fd4ad4c7e6c1d1f2b4d2ab2f1a9e9c3b1f", "sha256")
+	fp11 := testutil.CreateSparseFingerprint("docs/hello.txt", "1c291ca3", "crc32")
+	expectedFingerprints := testutil.CreateList(fp1, fp2, fp3, fp4, fp5, fp6, fp7, fp8, fp9, fp10, fp11)
 
 	return expectedFingerprints
 }
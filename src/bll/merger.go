@@ -0,0 +1,152 @@
+package bll
+
+import (
+	"container/list"
+	"encoding/hex"
+	"fmr/bll/report"
+	"fmr/dal"
+	"fmr/util"
+	"time"
+)
+
+// Conflict resolution policies of the merge.
+const (
+	// MergeNewest Keeps the entry created later.
+	MergeNewest = "newest"
+	// MergeKeepBoth Keeps both entries.
+	MergeKeepBoth = "keepboth"
+	// MergeFail Stops without saving anything.
+	MergeFail = "fail"
+)
+
+// Merger Stores settings related to merging databases.
+type Merger struct {
+	Db     dal.Database
+	Policy string
+	Report *report.MergeReport
+}
+
+// mergeKey Identifies the entries that should have the same checksum.
+type mergeKey struct {
+	filename  string
+	algorithm string
+}
+
+// NewMerger Instantiates a new Merger object. Conflicts are resolved according to the given policy.
+func NewMerger(db dal.Database, policy string) Merger {
+
+	report := report.NewMergeReport()
+
+	return Merger{db, policy, report}
+}
+
+// Merge Loads the given databases and saves the union of their entries. Entries having the same path, algorithm and
+// checksum are stored once, their tags and metadata are combined. Entries having the same path and algorithm, but a
+// different checksum are conflicts resolved by the policy. Returns false if nothing has been saved because of a
+// conflict.
+func (merger *Merger) Merge(sources []dal.Database) bool {
+
+	merged := make(map[mergeKey][]*dal.Fingerprint)
+	order := make([]mergeKey, 0)
+
+	for _, source := range sources {
+		source.LoadFingerprints()
+		for element := source.GetFingerprints().Back(); element != nil; element = element.Prev() {
+			fingerprint := element.Value.(*dal.Fingerprint)
+			key := mergeKey{fingerprint.Filename, fingerprint.Algorithm}
+			if _, ok := merged[key]; !ok {
+				order = append(order, key)
+			}
+			merged[key] = merger.mergeFingerprint(merged[key], fingerprint)
+		}
+	}
+
+	if merger.Policy == MergeFail && merger.Report.Conflicts.Len() > 0 {
+		merger.Report.LogSummary(0)
+		return false
+	}
+
+	fingerprints := list.New()
+	for _, key := range order {
+		for _, fingerprint := range merged[key] {
+			fingerprints.PushFront(fingerprint)
+		}
+	}
+
+	merger.Db.Clear()
+	merger.Db.AddFingerprints(fingerprints)
+	merger.Db.SaveFingerprints()
+	merger.Report.LogSummary(fingerprints.Len())
+
+	return true
+}
+
+// mergeFingerprint Adds the given fingerprint to the entries stored for the same path and algorithm.
+func (merger *Merger) mergeFingerprint(stored []*dal.Fingerprint, fingerprint *dal.Fingerprint) []*dal.Fingerprint {
+
+	for _, storedFingerprint := range stored {
+		if util.CompareByteSlices(storedFingerprint.Checksum, fingerprint.Checksum) {
+			combineFingerprints(storedFingerprint, fingerprint)
+			merger.Report.AddDuplicate(fingerprint.Filename)
+			return stored
+		}
+	}
+
+	if len(stored) == 0 {
+		return append(stored, fingerprint)
+	}
+
+	storedChecksum := hex.EncodeToString(stored[0].Checksum)
+	checksum := hex.EncodeToString(fingerprint.Checksum)
+
+	switch merger.Policy {
+	case MergeKeepBoth:
+		merger.Report.AddConflict(fingerprint.Filename, storedChecksum, checksum, "both kept")
+		return append(stored, fingerprint)
+	case MergeNewest:
+		if isNewer(fingerprint, stored[0]) {
+			merger.Report.AddConflict(fingerprint.Filename, storedChecksum, checksum, "newer kept: "+checksum)
+			return []*dal.Fingerprint{fingerprint}
+		}
+		merger.Report.AddConflict(fingerprint.Filename, storedChecksum, checksum, "newer kept: "+storedChecksum)
+		return stored
+	}
+
+	merger.Report.AddConflict(fingerprint.Filename, storedChecksum, checksum, "unresolved")
+
+	return stored
+}
+
+// combineFingerprints Completes the stored fingerprint with the note, tags and metadata of its duplicate.
+func combineFingerprints(stored *dal.Fingerprint, duplicate *dal.Fingerprint) {
+
+	if stored.Note == "" {
+		stored.Note = duplicate.Note
+	}
+	for _, tag := range duplicate.Tags {
+		if !containsTag(stored.Tags, tag) {
+			stored.Tags = append(stored.Tags, tag)
+		}
+	}
+	for key, value := range duplicate.Metadata {
+		if stored.Metadata == nil {
+			stored.Metadata = make(map[string]string)
+		}
+		if _, ok := stored.Metadata[key]; !ok {
+			stored.Metadata[key] = value
+		}
+	}
+}
+
+// isNewer Checks whether the first fingerprint has been created later than the second one. In case of equal (or
+// unknown) creation times the first one (coming from a later source) is considered newer.
+func isNewer(fingerprint *dal.Fingerprint, other *dal.Fingerprint) bool {
+
+	createdAt, err1 := time.Parse(time.RFC3339Nano, fingerprint.CreatedAt)
+	otherCreatedAt, err2 := time.Parse(time.RFC3339Nano, other.CreatedAt)
+	if err1 != nil || err2 != nil {
+		return err1 == nil || err2 != nil
+	}
+
+	return !createdAt.Before(otherCreatedAt)
+}
@@ -0,0 +1,137 @@
+package bll
+
+import (
+	"encoding/hex"
+	"fmr/bll/report"
+	"fmr/bll/testutil"
+	"fmr/dal"
+	"testing"
+)
+
+func TestMerger(t *testing.T) {
+
+	t.Run("Merge_Duplicates", testMergerMergeDuplicates)
+	t.Run("Merge_Newest", testMergerMergeNewest)
+	t.Run("Merge_KeepBoth", testMergerMergeKeepBoth)
+	t.Run("Merge_Fail", testMergerMergeFail)
+}
+
+func testMergerMergeDuplicates(t *testing.T) {
+
+	// Arrange.
+	source1 := createDatabaseForMerge(
+		testutil.CreateFingerprint("a.txt", "1c291ca3", "crc32", "2018-01-01T00:00:00Z", "", "Original"),
+		testutil.CreateSparseFingerprint("b.txt", "6b24cc6a", "crc32"))
+	source2 := createDatabaseForMerge(
+		testutil.CreateFingerprint("a.txt", "1c291ca3", "crc32", "2019-01-01T00:00:00Z", "", ""),
+		testutil.CreateSparseFingerprint("a.txt", "9f3a", "md5"),
+		testutil.CreateSparseFingerprint("c.txt", "1881d07b", "crc32"))
+	findFingerprintWithAlgorithm(source1, "a.txt", "crc32").Tags = []string{"scanned"}
+	findFingerprintWithAlgorithm(source2, "a.txt", "crc32").Tags = []string{"scanned", "signed"}
+	output := dal.NewMemoryDatabase()
+	merger := NewMerger(output, MergeFail)
+
+	// Act.
+	merged := merger.Merge([]dal.Database{source1, source2})
+
+	// Assert.
+	if !merged {
+		t.Fatal("Merge without conflicts should succeed.")
+	}
+	if output.GetFingerprints().Len() != 4 {
+		t.Errorf("Wrong number of merged entries: %d.", output.GetFingerprints().Len())
+	}
+	if merger.Report.CountDuplicates != 1 {
+		t.Errorf("Wrong number of duplicates: %d.", merger.Report.CountDuplicates)
+	}
+	original := findFingerprintWithAlgorithm(output, "a.txt", "crc32")
+	if original.Note != "Original" || original.CreatedAt != "2018-01-01T00:00:00Z" {
+		t.Error("The first occurrence of a duplicate should be kept.")
+	}
+	if len(original.Tags) != 2 {
+		t.Errorf("The tags of the duplicates should be combined: %v.", original.Tags)
+	}
+}
+
+func testMergerMergeNewest(t *testing.T) {
+
+	source1 := createDatabaseForMerge(
+		testutil.CreateFingerprint("a.txt", "1c291ca3", "crc32", "2019-01-01T00:00:00Z", "", ""))
+	source2 := createDatabaseForMerge(
+		testutil.CreateFingerprint("a.txt", "6b24cc6a", "crc32", "2018-01-01T00:00:00Z", "", ""))
+	output := dal.NewMemoryDatabase()
+	merger := NewMerger(output, MergeNewest)
+
+	merger.Merge([]dal.Database{source1, source2})
+
+	assertMergedChecksums(t, output, "1c291ca3")
+	if merger.Report.Conflicts.Len() != 1 {
+		t.Errorf("Wrong number of conflicts: %d.", merger.Report.Conflicts.Len())
+	}
+}
+
+func testMergerMergeKeepBoth(t *testing.T) {
+
+	source1 := createDatabaseForMerge(testutil.CreateSparseFingerprint("a.txt", "1c291ca3", "crc32"))
+	source2 := createDatabaseForMerge(testutil.CreateSparseFingerprint("a.txt", "6b24cc6a", "crc32"))
+	output := dal.NewMemoryDatabase()
+	merger := NewMerger(output, MergeKeepBoth)
+
+	merger.Merge([]dal.Database{source1, source2})
+
+	assertMergedChecksums(t, output, "1c291ca3", "6b24cc6a")
+}
+
+func testMergerMergeFail(t *testing.T) {
+
+	source1 := createDatabaseForMerge(testutil.CreateSparseFingerprint("a.txt", "1c291ca3", "crc32"))
+	source2 := createDatabaseForMerge(testutil.CreateSparseFingerprint("a.txt", "6b24cc6a", "crc32"))
+	output := dal.NewMemoryDatabase()
+	merger := NewMerger(output, MergeFail)
+
+	merged := merger.Merge([]dal.Database{source1, source2})
+
+	if merged || output.GetFingerprints().Len() != 0 {
+		t.Error("Nothing should be saved in case of a conflict.")
+	}
+	conflict := merger.Report.Conflicts.Front().Value.(*report.MergeConflict)
+	if conflict.Filename != "a.txt" {
+		t.Errorf("Wrong conflict: %s.", conflict.Filename)
+	}
+}
+
+func createDatabaseForMerge(fingerprints ...*dal.Fingerprint) *dal.MemoryDatabase {
+
+	memoryDatabase := dal.NewMemoryDatabase()
+	for _, fingerprint := range fingerprints {
+		memoryDatabase.AddFingerprint(fingerprint)
+	}
+
+	return memoryDatabase
+}
+
+func findFingerprintWithAlgorithm(db dal.Database, filename string, algorithm string) *dal.Fingerprint {
+
+	for element := db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if fingerprint.Filename == filename && fingerprint.Algorithm == algorithm {
+			return fingerprint
+		}
+	}
+
+	return nil
+}
+
+func assertMergedChecksums(t *testing.T, db dal.Database, expectedChecksums ...string) {
+
+	fingerprints := db.GetFingerprints()
+	if fingerprints.Len() != len(expectedChecksums) {
+		t.Fatalf("Wrong number of merged entries: %d.", fingerprints.Len())
+	}
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		checksum := hex.EncodeToString(element.Value.(*dal.Fingerprint).Checksum)
+		if !containsTag(expectedChecksums, checksum) {
+			t.Errorf("Unexpected checksum: %s.", checksum)
+		}
+	}
+}
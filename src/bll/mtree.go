@@ -0,0 +1,226 @@
+package bll
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mtreeDigestKeywords Maps the algorithms to the mtree keywords of their digests. CRC32 has no keyword, the cksum
+// keyword of mtree is a different CRC.
+var mtreeDigestKeywords = map[string]string{
+	"md5":    "md5digest",
+	"sha1":   "sha1digest",
+	"sha256": "sha256digest",
+	"sha512": "sha512digest",
+}
+
+// mtreeDigestAliases Maps the alternative names of the digest keywords to the algorithms.
+var mtreeDigestAliases = map[string]string{
+	"md5": "md5", "md5digest": "md5",
+	"sha1": "sha1", "sha1digest": "sha1",
+	"sha256": "sha256", "sha256digest": "sha256",
+	"sha512": "sha512", "sha512digest": "sha512",
+}
+
+// mtreeEntry Stores a path of an mtree specification, relative to the root of the tree, and its keywords, including the
+// ones set by /set.
+type mtreeEntry struct {
+	path     string
+	keywords map[string]string
+}
+
+// parseMtreeSpec Reads an mtree specification in either relative or full path format and passes each entry to the
+// given function. Lines that cannot be parsed are passed to onInvalid.
+func parseMtreeSpec(reader io.Reader, onEntry func(entry mtreeEntry), onInvalid func(line string)) error {
+
+	defaults := make(map[string]string)
+	directory := ""
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := ""
+
+	for scanner.Scan() {
+		line += strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasSuffix(line, "\\") && !strings.HasSuffix(line, "\\\\") {
+			line = strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+
+		fields := strings.Fields(line)
+		current := line
+		line = ""
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "/set":
+			for key, value := range parseMtreeKeywords(fields[1:]) {
+				defaults[key] = value
+			}
+			continue
+		case "/unset":
+			for _, key := range fields[1:] {
+				delete(defaults, key)
+			}
+			continue
+		case "..":
+			directory = path.Dir(directory)
+			if directory == "." {
+				directory = ""
+			}
+			continue
+		}
+
+		name, err := decodeMtreePath(fields[0])
+		if err != nil {
+			onInvalid(current)
+			continue
+		}
+
+		keywords := make(map[string]string)
+		for key, value := range defaults {
+			keywords[key] = value
+		}
+		for key, value := range parseMtreeKeywords(fields[1:]) {
+			keywords[key] = value
+		}
+
+		entryPath := path.Clean(name)
+		if !strings.Contains(name, "/") {
+			entryPath = path.Join(directory, name)
+			if keywords["type"] == "dir" {
+				directory = entryPath
+			}
+		}
+		if entryPath == "." {
+			entryPath = ""
+		}
+		onEntry(mtreeEntry{entryPath, keywords})
+	}
+
+	return scanner.Err()
+}
+
+func parseMtreeKeywords(fields []string) map[string]string {
+
+	keywords := make(map[string]string)
+	for _, field := range fields {
+		if index := strings.Index(field, "="); index > 0 {
+			keywords[field[:index]] = field[index+1:]
+		} else {
+			keywords[field] = ""
+		}
+	}
+
+	return keywords
+}
+
+// writeMtreeEntry Writes an entry in full path format.
+func writeMtreeEntry(writer io.Writer, entryPath string, keywords []string) error {
+
+	name := "."
+	if entryPath != "" {
+		name = "./" + encodeMtreePath(entryPath)
+	}
+	_, err := fmt.Fprintf(writer, "%s %s\n", name, strings.Join(keywords, " "))
+
+	return err
+}
+
+// encodeMtreePath Escapes whitespace, non-printable and glob characters in octal, like strsvis(3) does for mtree.
+func encodeMtreePath(p string) string {
+
+	var builder strings.Builder
+	for _, c := range []byte(p) {
+		if c <= ' ' || c >= 0x7f || strings.IndexByte("\\#*?[", c) >= 0 {
+			builder.WriteString(fmt.Sprintf("\\%03o", c))
+		} else {
+			builder.WriteByte(c)
+		}
+	}
+
+	return builder.String()
+}
+
+// decodeMtreePath Decodes the octal and C style escapes of a path.
+func decodeMtreePath(p string) (string, error) {
+
+	var builder strings.Builder
+	for index := 0; index < len(p); index++ {
+		if p[index] != '\\' {
+			builder.WriteByte(p[index])
+			continue
+		}
+		if index+3 < len(p) && isOctalDigits(p[index+1:index+4]) {
+			value, _ := strconv.ParseUint(p[index+1:index+4], 8, 8)
+			builder.WriteByte(byte(value))
+			index += 3
+			continue
+		}
+		if index+1 >= len(p) {
+			return "", fmt.Errorf("incomplete escape in %s", p)
+		}
+		index++
+		switch p[index] {
+		case 's':
+			builder.WriteByte(' ')
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		default:
+			builder.WriteByte(p[index])
+		}
+	}
+
+	return builder.String(), nil
+}
+
+func isOctalDigits(s string) bool {
+
+	for _, c := range []byte(s) {
+		if c < '0' || c > '7' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// formatMtreeTime Formats a modification time stored in a fingerprint as seconds and nanoseconds. Returns "" if the
+// time is not set.
+func formatMtreeTime(modifiedAt string) string {
+
+	t, err := time.Parse(time.RFC3339Nano, modifiedAt)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// parseMtreeTime Parses the seconds and optional fraction of a second of an mtree time into the format stored in
+// fingerprints.
+func parseMtreeTime(value string) (string, error) {
+
+	parts := strings.SplitN(value, ".", 2)
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", err
+	}
+	nanoseconds := int64(0)
+	if len(parts) == 2 {
+		fraction := (parts[1] + "000000000")[:9]
+		if nanoseconds, err = strconv.ParseInt(fraction, 10, 64); err != nil {
+			return "", err
+		}
+	}
+
+	return time.Unix(seconds, nanoseconds).UTC().Format(time.RFC3339Nano), nil
+}
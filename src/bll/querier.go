@@ -0,0 +1,35 @@
+package bll
+
+import (
+	"fmr/bll/common"
+	"fmr/bll/report"
+	"fmr/dal"
+)
+
+// Querier Searches the stored entries.
+type Querier struct {
+	Db     dal.Database
+	Report *report.QueryReport
+}
+
+// NewQuerier Instantiates a new Querier object. The results are collected in the given report.
+func NewQuerier(db dal.Database, queryReport *report.QueryReport) Querier {
+
+	return Querier{db, queryReport}
+}
+
+// Query Adds the entries matching the given query to the report and prints them. Returns the number of matching
+// entries.
+func (querier *Querier) Query(query *common.Query) (int, error) {
+
+	querier.Db.LoadFingerprints()
+
+	for element := querier.Db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if query.Match(fingerprint) {
+			querier.Report.AddFingerprint(fingerprint)
+		}
+	}
+
+	return querier.Report.Count(), querier.Report.Write()
+}
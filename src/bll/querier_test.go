@@ -0,0 +1,71 @@
+package bll
+
+import (
+	"bytes"
+	"fmr/bll/common"
+	"fmr/bll/report"
+	"fmr/bll/testutil"
+	"fmr/dal"
+	"testing"
+)
+
+func TestQuerier(t *testing.T) {
+
+	t.Run("Query", testQuerierQuery)
+	t.Run("Query_NoMatch", testQuerierQueryNoMatch)
+}
+
+func testQuerierQuery(t *testing.T) {
+
+	// Arrange.
+	memoryDatabase := createDatabaseForQuery()
+	output := new(bytes.Buffer)
+	queryReport, _ := report.NewQueryReport(output, "{{.Filename}}")
+	querier := NewQuerier(memoryDatabase, queryReport)
+	query, _ := common.ParseQuery("tag=contract")
+
+	// Act.
+	count, err := querier.Query(query)
+
+	// Assert.
+	if err != nil {
+		t.Fatalf("Query failed: %s.", err)
+	}
+	if count != 2 {
+		t.Errorf("Wrong number of matching entries: %d.", count)
+	}
+	if output.String() != "docs/lease.pdf\ndocs/loan.pdf\n" {
+		t.Errorf("Wrong output: %q.", output.String())
+	}
+}
+
+func testQuerierQueryNoMatch(t *testing.T) {
+
+	memoryDatabase := createDatabaseForQuery()
+	queryReport, _ := report.NewQueryReport(new(bytes.Buffer), report.QueryFormatTable)
+	querier := NewQuerier(memoryDatabase, queryReport)
+	query, _ := common.ParseQuery("")
+	query.AddChecksumPrefix("ffff")
+
+	count, _ := querier.Query(query)
+
+	if count != 0 {
+		t.Errorf("Wrong number of matching entries: %d.", count)
+	}
+}
+
+func createDatabaseForQuery() *dal.MemoryDatabase {
+
+	fp1 := testutil.CreateSparseFingerprint("docs/loan.pdf", "1c291ca3", "crc32")
+	fp2 := testutil.CreateSparseFingerprint("photos/beach.jpg", "6b24cc6a", "crc32")
+	fp3 := testutil.CreateSparseFingerprint("docs/lease.pdf", "1881d07b", "crc32")
+	fp1.Tags = []string{"contract"}
+	fp3.Tags = []string{"contract", "2019"}
+
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprint(fp1)
+	memoryDatabase.AddFingerprint(fp2)
+	memoryDatabase.AddFingerprint(fp3)
+
+	return memoryDatabase
+}
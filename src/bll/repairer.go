@@ -0,0 +1,253 @@
+package bll
+
+import (
+	"errors"
+	"fmr/bll/common"
+	"fmr/bll/report"
+	"fmr/dal"
+	"fmr/util"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// Repairer Stores settings related to repairing damaged files.
+type Repairer struct {
+	Db                dal.Database
+	BasePath          string
+	RecoveryDirectory string
+	Report            *report.RepairReport
+	replicas          *replicaSet
+	resolver          *util.PathResolver
+	dryRun            bool
+	readOptions       common.ReadOptions
+}
+
+// NewRepairer Instantiates a new Repairer object. The recovery files are looked up in the given directory.
+func NewRepairer(db dal.Database, basePath string, recoveryDirectory string) Repairer {
+
+	basePath = util.NormalizePath(basePath)
+	report := report.NewRepairReport()
+
+	resolver := util.NewPathResolver(basePath, util.NewPathMatcher(false, false))
+
+	return Repairer{db, basePath, recoveryDirectory, report, newReplicaSet(nil, common.ReadOptions{}), resolver, false,
+		common.ReadOptions{}}
+}
+
+// SetReadOptions Sets how the repaired files and the replica candidates are read when their checksum is checked.
+func (repairer *Repairer) SetReadOptions(options common.ReadOptions) {
+
+	repairer.readOptions = options
+	repairer.replicas.readOptions = options
+}
+
+// SetDryRun Sets whether the repaired files are only checked, without replacing the damaged ones.
+func (repairer *Repairer) SetDryRun(dryRun bool) {
+
+	repairer.dryRun = dryRun
+}
+
+// SetPathMatcher Sets how the stored filenames are matched with the names on disk, so that a file whose name differs
+// only in Unicode normalization or case is repaired in place.
+func (repairer *Repairer) SetPathMatcher(matcher util.PathMatcher) {
+
+	repairer.resolver = util.NewPathResolver(repairer.BasePath, matcher)
+}
+
+// SetReplicas Sets the roots of the replicas holding other copies of the files under the base path. A corrupt or
+// missing file without usable recovery data is restored from a copy with the same checksum.
+func (repairer *Repairer) SetReplicas(roots []string) {
+
+	repairer.replicas = newReplicaSet(roots, repairer.readOptions)
+}
+
+// Repair Verifies the files listed in the database and reconstructs the corrupt ones from their recovery data, or
+// restores them from a replica. A repaired file replaces the damaged one only if its checksum matches the stored one.
+// Returns false if any corrupt or missing file could not be repaired.
+func (repairer *Repairer) Repair(fpFilter common.FingerprintFilter) bool {
+
+	repairer.Db.LoadFingerprints()
+
+	skippedMembers := 0
+	for element := repairer.Db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if !fpFilter.FilterFingerprint(fingerprint) {
+			continue
+		}
+		if _, _, isMember := common.SplitArchivePath(fingerprint.Filename, repairer.getFullPath); isMember {
+			skippedMembers++
+		} else {
+			repairer.repairEntry(fingerprint)
+		}
+	}
+
+	if skippedMembers > 0 {
+		util.LogInfo(fmt.Sprintf("Skipped %d archive member(s), only whole files can be repaired.", skippedMembers),
+			util.Field("skipped", skippedMembers))
+	}
+	repairer.Report.LogSummary()
+
+	return repairer.Report.UnrepairableFiles.Len() == 0
+}
+
+// getFullPath Returns the path of the file on disk from its stored name.
+func (repairer *Repairer) getFullPath(filename string) string {
+
+	fullPath, _ := repairer.resolver.Resolve(filename)
+
+	return fullPath
+}
+
+func (repairer *Repairer) repairEntry(fingerprint *dal.Fingerprint) {
+
+	fullPath, _ := repairer.resolver.Resolve(fingerprint.Filename)
+	if fingerprint.LinkTarget != "" {
+		repairer.checkLink(fullPath, fingerprint)
+		return
+	}
+
+	reason := "missing"
+	if util.CheckIfFileExists(fullPath) {
+		if repairer.checkFileChecksum(fullPath, fingerprint) {
+			repairer.Report.AddValidFile(fingerprint.Filename)
+			return
+		}
+		if reason = repairer.reconstructFile(fullPath, fingerprint); reason == "" {
+			return
+		}
+	}
+
+	if len(repairer.replicas.roots) == 0 {
+		repairer.Report.AddUnrepairableFile(fingerprint.Filename, reason)
+		return
+	}
+	if err := repairer.restoreFile(fullPath, fingerprint); err != nil {
+		repairer.Report.AddUnrepairableFile(fingerprint.Filename, reason+", "+err.Error())
+	}
+}
+
+// reconstructFile Repairs the file from its recovery data. Returns the reason of the failure, or "" on success.
+func (repairer *Repairer) reconstructFile(fullPath string, fingerprint *dal.Fingerprint) string {
+
+	recoveryPath := common.GetRecoveryFilePath(repairer.RecoveryDirectory, fingerprint.Checksum)
+	if !util.CheckIfFileExists(recoveryPath) {
+		return "no recovery data"
+	}
+
+	output, repairedCount, err := common.RepairFile(fullPath, recoveryPath)
+	if err != nil {
+		return err.Error()
+	}
+	defer output.Abort()
+
+	if !repairer.checkFileChecksum(output.Name(), fingerprint) {
+		return "checksum mismatch after reconstruction"
+	}
+	if err := repairer.replaceFile(output, fullPath, fingerprint); err != nil {
+		return err.Error()
+	}
+
+	repairer.addRepairedFile(fingerprint, fmt.Sprintf("%d block(s) reconstructed", repairedCount))
+
+	return ""
+}
+
+// restoreFile Replaces the file with a copy from a replica. The copy is hashed again after it is written, so that a
+// copy damaged in transit never replaces the file.
+func (repairer *Repairer) restoreFile(fullPath string, fingerprint *dal.Fingerprint) error {
+
+	sourcePath := repairer.replicas.findCopy(fingerprint)
+	if sourcePath == "" {
+		return errors.New("no matching replica")
+	}
+	// The checksum of the copy has been checked already, there is nothing to write in dry-run mode.
+	if repairer.dryRun {
+		repairer.addRepairedFile(fingerprint, "restored from "+sourcePath)
+		return nil
+	}
+
+	if err := os.MkdirAll(path.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	output, err := util.CreateAtomicFile(fullPath, false)
+	if err != nil {
+		return err
+	}
+	defer output.Abort()
+
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(output, source)
+	source.Close()
+	if err != nil {
+		return err
+	}
+
+	if !repairer.checkFileChecksum(output.Name(), fingerprint) {
+		return errors.New("checksum mismatch after copying " + sourcePath)
+	}
+	if err := repairer.replaceFile(output, fullPath, fingerprint); err != nil {
+		return err
+	}
+
+	repairer.addRepairedFile(fingerprint, "restored from "+sourcePath)
+
+	return nil
+}
+
+// replaceFile Replaces the damaged file with the repaired one and restores its modification time. In dry-run mode the
+// repaired file is discarded.
+func (repairer *Repairer) replaceFile(output *util.AtomicFile, fullPath string, fingerprint *dal.Fingerprint) error {
+
+	if repairer.dryRun {
+		return nil
+	}
+	if err := output.Commit(); err != nil {
+		return err
+	}
+	restoreModificationTime(fullPath, fingerprint)
+
+	return nil
+}
+
+func (repairer *Repairer) addRepairedFile(fingerprint *dal.Fingerprint, source string) {
+
+	if repairer.dryRun {
+		source += ", dry run, not written"
+	}
+	repairer.Report.AddRepairedFile(fingerprint.Filename, source)
+}
+
+// checkLink Checks a symbolic link recorded with its target. Links are not restored, a changed link is reported.
+func (repairer *Repairer) checkLink(fullPath string, fingerprint *dal.Fingerprint) {
+
+	target, err := os.Readlink(fullPath)
+	hasher := common.NewHasher(fingerprint.Algorithm)
+	if err == nil && util.CompareByteSlices(hasher.CalculateLinkChecksum(target), fingerprint.Checksum) {
+		repairer.Report.AddValidFile(fingerprint.Filename)
+	} else {
+		repairer.Report.AddUnrepairableFile(fingerprint.Filename, "symbolic link to "+fingerprint.LinkTarget)
+	}
+}
+
+func (repairer *Repairer) checkFileChecksum(fullPath string, fingerprint *dal.Fingerprint) bool {
+
+	hasher := common.NewHasher(fingerprint.Algorithm)
+	repairer.readOptions.Apply(&hasher)
+
+	return util.CompareByteSlices(hasher.CalculateChecksum(fullPath), fingerprint.Checksum)
+}
+
+// restoreModificationTime Sets the stored modification time on the repaired file, so that it is not considered changed
+// when resuming a calculation.
+func restoreModificationTime(fullPath string, fingerprint *dal.Fingerprint) {
+
+	if modifiedAt, err := time.Parse(time.RFC3339Nano, fingerprint.ModifiedAt); err == nil {
+		os.Chtimes(fullPath, time.Now(), modifiedAt)
+	}
+}
@@ -0,0 +1,210 @@
+package bll
+
+import (
+	"fmr/bll/common"
+	"fmr/dal"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRepairer(t *testing.T) {
+
+	setupRepairerTests()
+
+	t.Run("Repair", testRepairerRepair)
+	t.Run("Repair_Replicas", testRepairerRepairReplicas)
+	t.Run("Repair_UnreadableReplica", testRepairerRepairUnreadableReplica)
+	t.Run("Repair_SizelessReplica", testRepairerRepairSizelessReplica)
+	t.Run("Repair_DryRun", testRepairerRepairDryRun)
+
+	tearDownRepairerTests()
+}
+
+func setupRepairerTests() {
+
+	testHelper.CreateTestRootDirectory()
+
+	testHelper.CreateTestDirectory("data")
+	testHelper.CreateTestFileWithContent("data/document.txt", strings.Repeat("Lorem ipsum, dolor sit amet. ", 1000))
+	testHelper.CreateTestFileWithContent("data/valid.txt", "Hello World!")
+}
+
+func testRepairerRepair(t *testing.T) {
+
+	// Arrange.
+	dataPath := testHelper.GetTestDirectory("data")
+	recoveryDirectory := testHelper.GetTestPath("checksums.csv" + common.RecoveryDirectorySuffix)
+	memoryDatabase := dal.NewMemoryDatabase()
+	calculator := NewCalculator(memoryDatabase, dataPath, "sha256", dataPath)
+	calculator.SetRecovery(recoveryDirectory, 25)
+	calculator.Calculate(false)
+	original, _ := ioutil.ReadFile(testHelper.GetTestPath("data/document.txt"))
+	testHelper.CreateTestFileWithContent("data/document.txt", "Damaged"+string(original[7:]))
+	testHelper.CreateTestFileWithContent("data/unprotected.txt", "Lorem ipsum")
+	hasher := common.NewHasher("sha256")
+	memoryDatabase.AddFingerprint(hasher.CalculateFingerprint(dataPath, "", "unprotected.txt"))
+	testHelper.CreateTestFileWithContent("data/unprotected.txt", "Lorem ipsun")
+	verifier := NewVerifier(memoryDatabase, dataPath)
+	verifier.SetRecoveryDirectory(recoveryDirectory)
+	verifier.Verify(false, common.NewFingerprintFilter(""))
+	repairer := NewRepairer(memoryDatabase, dataPath, recoveryDirectory)
+
+	// Act.
+	completed := repairer.Repair(common.NewFingerprintFilter(""))
+
+	// Assert.
+	if verifier.CountRepairableFiles() != 1 {
+		t.Errorf("Wrong number of repairable files: %d.", verifier.CountRepairableFiles())
+	}
+	if completed || !testHelper.HasStringItems(repairer.Report.UnrepairableFiles, "unprotected.txt") {
+		t.Error("File without recovery data should not be repaired: \"unprotected.txt\".")
+	}
+	if !testHelper.HasStringItems(repairer.Report.RepairedFiles, "document.txt") || repairer.Report.CountValid != 1 {
+		t.Error("File should be repaired: \"document.txt\".")
+	}
+	repaired, _ := ioutil.ReadFile(testHelper.GetTestPath("data/document.txt"))
+	if string(repaired) != string(original) {
+		t.Error("The content of the repaired file does not match the original one.")
+	}
+}
+
+func testRepairerRepairReplicas(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("primary")
+	testHelper.CreateTestDirectory("primary/album")
+	testHelper.CreateTestFileWithContent("primary/album/cover.jpg", "Cover")
+	testHelper.CreateTestFileWithContent("primary/notes.txt", "Notes")
+	testHelper.CreateTestFileWithContent("primary/lost.txt", "Lost")
+	primaryPath := testHelper.GetTestDirectory("primary")
+	memoryDatabase := dal.NewMemoryDatabase()
+	calculator := NewCalculator(memoryDatabase, primaryPath, "sha256", primaryPath)
+	calculator.Calculate(false)
+	testHelper.CreateTestDirectory("replica1")
+	testHelper.CreateTestDirectory("replica1/album")
+	testHelper.CreateTestFileWithContent("replica1/album/cover.jpg", "Cov3r")
+	testHelper.CreateTestFileWithContent("replica1/notes.txt", "Notes")
+	testHelper.CreateTestDirectory("replica2")
+	testHelper.CreateTestDirectory("replica2/moved")
+	testHelper.CreateTestFileWithContent("replica2/moved/picture.jpg", "Cover")
+	testHelper.CreateTestFileWithContent("primary/album/cover.jpg", "C0ver")
+	os.Remove(testHelper.GetTestPath("primary/notes.txt"))
+	os.Remove(testHelper.GetTestPath("primary/lost.txt"))
+	repairer := NewRepairer(memoryDatabase, primaryPath, testHelper.GetTestPath("none.recovery"))
+	repairer.SetReplicas([]string{testHelper.GetTestDirectory("replica1"), testHelper.GetTestDirectory("replica2")})
+
+	// Act.
+	completed := repairer.Repair(common.NewFingerprintFilter(""))
+
+	// Assert.
+	if completed || !testHelper.HasStringItems(repairer.Report.UnrepairableFiles, "lost.txt") {
+		t.Error("File without a matching replica should not be repaired: \"lost.txt\".")
+	}
+	if !testHelper.HasStringItems(repairer.Report.RepairedFiles, "album/cover.jpg", "notes.txt") {
+		t.Errorf("Files should be restored from the replicas: %v.", repairer.Report.RepairedFiles)
+	}
+	cover, _ := ioutil.ReadFile(testHelper.GetTestPath("primary/album/cover.jpg"))
+	notes, _ := ioutil.ReadFile(testHelper.GetTestPath("primary/notes.txt"))
+	if string(cover) != "Cover" || string(notes) != "Notes" {
+		t.Error("The content of the restored files does not match the original one.")
+	}
+}
+
+func testRepairerRepairUnreadableReplica(t *testing.T) {
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("File permissions cannot make a file unreadable here.")
+	}
+
+	// Arrange.
+	testHelper.CreateTestDirectory("locked")
+	testHelper.CreateTestFileWithContent("locked/notes.txt", "Notes")
+	lockedPath := testHelper.GetTestDirectory("locked")
+	memoryDatabase := dal.NewMemoryDatabase()
+	calculator := NewCalculator(memoryDatabase, lockedPath, "sha256", lockedPath)
+	calculator.Calculate(false)
+	testHelper.CreateTestDirectory("locked-replica")
+	testHelper.CreateTestFileWithContent("locked-replica/notes.txt", "Sec3t")
+	testHelper.CreateTestFileWithContent("locked-replica/renamed.txt", "Notes")
+	os.Chmod(testHelper.GetTestPath("locked-replica/notes.txt"), 0)
+	defer os.Chmod(testHelper.GetTestPath("locked-replica/notes.txt"), 0644)
+	os.Remove(testHelper.GetTestPath("locked/notes.txt"))
+	repairer := NewRepairer(memoryDatabase, lockedPath, testHelper.GetTestPath("none.recovery"))
+	repairer.SetReplicas([]string{testHelper.GetTestDirectory("locked-replica")})
+
+	// Act.
+	completed := repairer.Repair(common.NewFingerprintFilter(""))
+
+	// Assert.
+	if !completed || !testHelper.HasStringItems(repairer.Report.RepairedFiles, "notes.txt") {
+		t.Errorf("Unreadable replica candidates should be skipped: %v.", repairer.Report.RepairedFiles)
+	}
+}
+
+func testRepairerRepairSizelessReplica(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("legacy")
+	testHelper.CreateTestFileWithContent("legacy/notes.txt", "Notes")
+	legacyPath := testHelper.GetTestDirectory("legacy")
+	hasher := common.NewHasher("sha256")
+	fingerprint := hasher.CalculateFingerprint(legacyPath, "", "notes.txt")
+	fingerprint.Size = 0
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprint(fingerprint)
+	testHelper.CreateTestDirectory("legacy-replica")
+	testHelper.CreateTestDirectory("legacy-replica/moved")
+	testHelper.CreateTestFileWithContent("legacy-replica/moved/renamed.txt", "Notes")
+	os.Remove(testHelper.GetTestPath("legacy/notes.txt"))
+	repairer := NewRepairer(memoryDatabase, legacyPath, testHelper.GetTestPath("none.recovery"))
+	repairer.SetReplicas([]string{testHelper.GetTestDirectory("legacy-replica")})
+
+	// Act.
+	completed := repairer.Repair(common.NewFingerprintFilter(""))
+
+	// Assert.
+	if !completed || !testHelper.HasStringItems(repairer.Report.RepairedFiles, "notes.txt") {
+		t.Errorf("A moved copy should be found for a fingerprint without size: %v.", repairer.Report.RepairedFiles)
+	}
+	notes, _ := ioutil.ReadFile(testHelper.GetTestPath("legacy/notes.txt"))
+	if string(notes) != "Notes" {
+		t.Error("The content of the restored file does not match the original one.")
+	}
+}
+
+func testRepairerRepairDryRun(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("dryrun")
+	testHelper.CreateTestFileWithContent("dryrun/notes.txt", "Notes")
+	dryRunPath := testHelper.GetTestDirectory("dryrun")
+	memoryDatabase := dal.NewMemoryDatabase()
+	calculator := NewCalculator(memoryDatabase, dryRunPath, "sha256", dryRunPath)
+	calculator.Calculate(false)
+	testHelper.CreateTestDirectory("dryrun-replica")
+	testHelper.CreateTestFileWithContent("dryrun-replica/notes.txt", "Notes")
+	testHelper.CreateTestFileWithContent("dryrun/notes.txt", "N0tes")
+	repairer := NewRepairer(memoryDatabase, dryRunPath, testHelper.GetTestPath("none.recovery"))
+	repairer.SetReplicas([]string{testHelper.GetTestDirectory("dryrun-replica")})
+	repairer.SetDryRun(true)
+
+	// Act.
+	completed := repairer.Repair(common.NewFingerprintFilter(""))
+
+	// Assert.
+	if !completed || !testHelper.HasStringItems(repairer.Report.RepairedFiles, "notes.txt") {
+		t.Errorf("File should be reported as repairable: %v.", repairer.Report.RepairedFiles)
+	}
+	notes, _ := ioutil.ReadFile(testHelper.GetTestPath("dryrun/notes.txt"))
+	if string(notes) != "N0tes" {
+		t.Error("The file should not be written in dry-run mode.")
+	}
+}
+
+func tearDownRepairerTests() {
+
+	testHelper.CleanUp()
+}
@@ -0,0 +1,111 @@
+package bll
+
+import (
+	"fmr/bll/common"
+	"fmr/dal"
+	"fmr/util"
+	"os"
+	"path"
+	"sort"
+)
+
+// replicaSet Looks up good copies of files on replica roots, which mirror the base path of the database. The files of
+// each root are listed and their checksums calculated only when needed, and cached for later lookups.
+type replicaSet struct {
+	roots       []string
+	filesBySize map[string]map[int64][]string
+	checksums   map[string][]byte
+	readOptions common.ReadOptions
+}
+
+func newReplicaSet(roots []string, readOptions common.ReadOptions) *replicaSet {
+
+	normalizedRoots := make([]string, len(roots))
+	for index, root := range roots {
+		normalizedRoots[index] = util.NormalizePath(root)
+	}
+
+	return &replicaSet{normalizedRoots, make(map[string]map[int64][]string), make(map[string][]byte), readOptions}
+}
+
+// findCopy Returns the path of a file on a replica root whose checksum matches the fingerprint. The file at the same
+// relative path is checked first on each root; otherwise any file of the same size is a candidate, so that renamed or
+// moved copies are found too. The size of legacy and imported entries is unknown (zero); every file is a candidate
+// then. Returns "" if there is no matching copy.
+func (replicas *replicaSet) findCopy(fingerprint *dal.Fingerprint) string {
+
+	for _, root := range replicas.roots {
+		candidate := path.Join(root, fingerprint.Filename)
+		if replicas.matches(candidate, fingerprint) {
+			return candidate
+		}
+	}
+
+	for _, root := range replicas.roots {
+		for _, candidate := range replicas.getCandidates(root, fingerprint.Size) {
+			if replicas.matches(candidate, fingerprint) {
+				return candidate
+			}
+		}
+	}
+
+	return ""
+}
+
+func (replicas *replicaSet) matches(fullPath string, fingerprint *dal.Fingerprint) bool {
+
+	key := fingerprint.Algorithm + ":" + fullPath
+	checksum, isCached := replicas.checksums[key]
+	if !isCached {
+		if !util.CheckIfFileExists(fullPath) {
+			return false
+		}
+		hasher := common.NewHasher(fingerprint.Algorithm)
+		replicas.readOptions.Apply(&hasher)
+		var err error
+		if checksum, err = hasher.TryCalculateChecksum(fullPath); err != nil {
+			util.LogWarn("Cannot read replica candidate "+fullPath+": "+err.Error()+".", util.Field("file", fullPath))
+		}
+		replicas.checksums[key] = checksum
+	}
+
+	return util.CompareByteSlices(checksum, fingerprint.Checksum)
+}
+
+// getCandidates Returns the files of the root having the given size, all of them if the size is unknown (zero), in a
+// stable order.
+func (replicas *replicaSet) getCandidates(root string, size int64) []string {
+
+	filesBySize := replicas.getFilesBySize(root)
+	if size > 0 {
+		return filesBySize[size]
+	}
+
+	candidates := make([]string, 0)
+	for _, files := range filesBySize {
+		candidates = append(candidates, files...)
+	}
+	sort.Strings(candidates)
+
+	return candidates
+}
+
+func (replicas *replicaSet) getFilesBySize(root string) map[int64][]string {
+
+	if filesBySize, isListed := replicas.filesBySize[root]; isListed {
+		return filesBySize
+	}
+
+	filesBySize := make(map[int64][]string)
+	if util.CheckIfDirectoryExists(root) {
+		for _, file := range util.ListFilesRecursively(root) {
+			fullPath := path.Join(root, file)
+			if fileInfo, err := os.Stat(fullPath); err == nil && fileInfo.Mode().IsRegular() {
+				filesBySize[fileInfo.Size()] = append(filesBySize[fileInfo.Size()], fullPath)
+			}
+		}
+	}
+	replicas.filesBySize[root] = filesBySize
+
+	return filesBySize
+}
@@ -0,0 +1,40 @@
+package report
+
+import (
+	"container/list"
+	"fmr/util"
+	"fmt"
+)
+
+// AnnotationReport Stores statistics of an annotation process.
+type AnnotationReport struct {
+	CountUpdated  int
+	UnmatchedKeys *list.List
+}
+
+// NewAnnotationReport Instantiates a new AnnotationReport object.
+func NewAnnotationReport() *AnnotationReport {
+
+	return &AnnotationReport{0, list.New()}
+}
+
+// AddUpdatedEntry Counts the given entry as updated.
+func (ar *AnnotationReport) AddUpdatedEntry(filename string) {
+
+	ar.CountUpdated++
+}
+
+// AddUnmatchedKey Adds the given key to the list of keys that do not match any entry.
+func (ar *AnnotationReport) AddUnmatchedKey(key string) {
+
+	ar.UnmatchedKeys.PushFront(key)
+	util.LogWarn(fmt.Sprintf("Not found: %s", key), util.Field("key", key), util.Field("outcome", OutcomeNotFound))
+}
+
+// LogSummary Prints a summary report to the log.
+func (ar *AnnotationReport) LogSummary() {
+
+	util.LogInfo(fmt.Sprintf(
+		"Summary: %d entries updated, %d key(s) not found.", ar.CountUpdated, ar.UnmatchedKeys.Len()),
+		util.Field("updated", ar.CountUpdated), util.Field("not_found", ar.UnmatchedKeys.Len()))
+}
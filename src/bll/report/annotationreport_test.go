@@ -0,0 +1,32 @@
+package report
+
+import "testing"
+
+func TestAnnotationReport(t *testing.T) {
+
+	t.Run("AddUpdatedEntry", testArAddUpdatedEntry)
+	t.Run("AddUnmatchedKey", testArAddUnmatchedKey)
+}
+
+func testArAddUpdatedEntry(t *testing.T) {
+
+	ar := NewAnnotationReport()
+
+	ar.AddUpdatedEntry("photos/2019/beach.jpg")
+	ar.AddUpdatedEntry("photos/2019/sunset.jpg")
+
+	if ar.CountUpdated != 2 {
+		t.Errorf("Wrong number of updated entries: %d.", ar.CountUpdated)
+	}
+}
+
+func testArAddUnmatchedKey(t *testing.T) {
+
+	ar := NewAnnotationReport()
+
+	ar.AddUnmatchedKey("0c17222d")
+
+	if ar.UnmatchedKeys.Len() != 1 || ar.UnmatchedKeys.Front().Value.(string) != "0c17222d" {
+		t.Error("The unmatched key is not stored.")
+	}
+}
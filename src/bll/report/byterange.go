@@ -0,0 +1,23 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ByteRange A range of bytes in a file, End is exclusive.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+// FormatByteRanges Formats the given ranges with inclusive ends, for example "0-4095, 8192-12287".
+func FormatByteRanges(ranges []ByteRange) string {
+
+	parts := make([]string, 0, len(ranges))
+	for _, byteRange := range ranges {
+		parts = append(parts, fmt.Sprintf("%d-%d", byteRange.Start, byteRange.End-1))
+	}
+
+	return strings.Join(parts, ", ")
+}
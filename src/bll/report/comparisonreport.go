@@ -2,32 +2,51 @@ package report
 
 import (
 	"container/list"
+	"fmr/util"
 	"fmt"
-	"log"
 )
 
 // ComparisonReport Stores statistics of a comparison process.
 type ComparisonReport struct {
-	MissingFiles *list.List
-	NewFiles     *list.List
+	MissingFiles  *list.List
+	NewFiles      *list.List
+	AppendedFiles *list.List
+	ModifiedFiles *list.List
 }
 
 // NewComparisonReport Instantiates a new ComparisonReport object.
 func NewComparisonReport() *ComparisonReport {
 
-	return &ComparisonReport{list.New(), list.New()}
+	return &ComparisonReport{list.New(), list.New(), list.New(), list.New()}
 }
 
 // AddMissingFile Adds the given file to the list of missing files.
 func (cr *ComparisonReport) AddMissingFile(filename string) {
 
 	cr.MissingFiles.PushFront(filename)
-	log.Println(fmt.Sprintf("Missing: %s", filename))
+	util.LogInfo(fmt.Sprintf("Missing: %s", filename), entryFields(filename, OutcomeMissing, nil)...)
 }
 
 // AddNewFile Adds the given file to the list of new files.
 func (cr *ComparisonReport) AddNewFile(filename string) {
 
 	cr.NewFiles.PushFront(filename)
-	log.Println(fmt.Sprintf("New: %s", filename))
+	util.LogInfo(fmt.Sprintf("New: %s", filename), entryFields(filename, OutcomeNew, nil)...)
+}
+
+// AddAppendedFile Adds the given file to the list of files whose previous content is unchanged, only data has been
+// appended to them.
+func (cr *ComparisonReport) AddAppendedFile(filename string) {
+
+	cr.AppendedFiles.PushFront(filename)
+	util.LogInfo(fmt.Sprintf("Appended: %s", filename), entryFields(filename, OutcomeAppended, nil)...)
+}
+
+// AddModifiedFile Adds the given file to the list of modified files, logging the byte ranges that have changed.
+func (cr *ComparisonReport) AddModifiedFile(filename string, ranges []ByteRange) {
+
+	cr.ModifiedFiles.PushFront(filename)
+	fields := []util.LogField{util.Field("bytes", FormatByteRanges(ranges))}
+	util.LogInfo(fmt.Sprintf("Modified: %s (bytes %s)", filename, FormatByteRanges(ranges)),
+		entryFields(filename, OutcomeModified, fields)...)
 }
@@ -11,6 +11,8 @@ func TestComparisonReport(t *testing.T) {
 
 	t.Run("AddMissingFile", testCrAddMissingFile)
 	t.Run("AddNewFile", testCrAddNewFile)
+	t.Run("AddAppendedFile", testCrAddAppendedFile)
+	t.Run("AddModifiedFile", testCrAddModifiedFile)
 }
 
 func testCrAddMissingFile(t *testing.T) {
@@ -36,3 +38,27 @@ func testCrAddNewFile(t *testing.T) {
 		t.Errorf("%s should be marked as missing.", testItem)
 	}
 }
+
+func testCrAddAppendedFile(t *testing.T) {
+
+	cr := NewComparisonReport()
+	testItem := "logs/server.log"
+
+	cr.AddAppendedFile(testItem)
+
+	if !comparisonReportTestHelper.HasStringItems(cr.AppendedFiles, testItem) {
+		t.Errorf("%s should be marked as appended.", testItem)
+	}
+}
+
+func testCrAddModifiedFile(t *testing.T) {
+
+	cr := NewComparisonReport()
+	testItem := "disk.img"
+
+	cr.AddModifiedFile(testItem, []ByteRange{{4096, 8192}})
+
+	if !comparisonReportTestHelper.HasStringItems(cr.ModifiedFiles, testItem) {
+		t.Errorf("%s should be marked as modified.", testItem)
+	}
+}
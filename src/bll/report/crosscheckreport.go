@@ -0,0 +1,96 @@
+package report
+
+import (
+	"container/list"
+	"fmr/util"
+	"fmt"
+	"strings"
+)
+
+// The status of a copy in a cross-check.
+const (
+	CopyGood    = "good"
+	CopyCorrupt = "corrupt"
+	CopyMissing = "missing"
+)
+
+// CrossCheckReport Stores the status of the copies of each entry on several roots.
+type CrossCheckReport struct {
+	Roots                []string
+	MinCopies            int
+	Entries              []CrossCheckEntry
+	UnderReplicatedFiles *list.List
+}
+
+// CrossCheckEntry Stores the status of the copies of an entry, in the order of the roots.
+type CrossCheckEntry struct {
+	Filename string
+	Statuses []string
+}
+
+// NewCrossCheckReport Instantiates a new CrossCheckReport object. Entries with fewer than minCopies good copies are
+// flagged.
+func NewCrossCheckReport(roots []string, minCopies int) *CrossCheckReport {
+
+	return &CrossCheckReport{roots, minCopies, make([]CrossCheckEntry, 0), list.New()}
+}
+
+// AddEntry Adds the statuses of the copies of the given file.
+func (cr *CrossCheckReport) AddEntry(filename string, statuses []string) {
+
+	cr.Entries = append(cr.Entries, CrossCheckEntry{filename, statuses})
+	if goodCopies := countGoodCopies(statuses); goodCopies < cr.MinCopies {
+		cr.UnderReplicatedFiles.PushBack(filename)
+	}
+}
+
+// LogMatrix Prints the status of each copy to the log, one line per entry and one column per root.
+func (cr *CrossCheckReport) LogMatrix() {
+
+	for index, root := range cr.Roots {
+		util.LogInfo(fmt.Sprintf("Copy %d: %s", index+1, root), util.Field("copy", index+1), util.Field("root", root))
+	}
+
+	for _, entry := range cr.Entries {
+		columns := make([]string, len(entry.Statuses))
+		for index, status := range entry.Statuses {
+			columns[index] = fmt.Sprintf("%-7s", status)
+		}
+		util.LogInfo(fmt.Sprintf("%s | %s", strings.Join(columns, " | "), entry.Filename),
+			util.Field("file", entry.Filename), util.Field("copies", entry.Statuses))
+	}
+}
+
+// LogSummary Prints the entries with too few good copies and a summary report to the log.
+func (cr *CrossCheckReport) LogSummary() {
+
+	countComplete := 0
+	for _, entry := range cr.Entries {
+		goodCopies := countGoodCopies(entry.Statuses)
+		if goodCopies == len(entry.Statuses) {
+			countComplete++
+		}
+		if goodCopies < cr.MinCopies {
+			util.LogError(fmt.Sprintf("Too few good copies: %s (%d of %d)", entry.Filename, goodCopies, cr.MinCopies),
+				entryFields(entry.Filename, OutcomeUnderCopied, []util.LogField{util.Field("good_copies", goodCopies)})...)
+		}
+	}
+
+	util.LogInfo(fmt.Sprintf(
+		"Summary: %d entries, %d good on every copy, %d with fewer than %d good copies.",
+		len(cr.Entries), countComplete, cr.UnderReplicatedFiles.Len(), cr.MinCopies),
+		util.Field("entries", len(cr.Entries)), util.Field("complete", countComplete),
+		util.Field("too_few_copies", cr.UnderReplicatedFiles.Len()))
+}
+
+func countGoodCopies(statuses []string) int {
+
+	count := 0
+	for _, status := range statuses {
+		if status == CopyGood {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,27 @@
+package report
+
+import (
+	"fmr/util"
+	"testing"
+)
+
+var crossCheckReportTestHelper = util.NewTestHelper()
+
+func TestCrossCheckReport(t *testing.T) {
+
+	cr := NewCrossCheckReport([]string{"/mnt/primary", "/mnt/backup"}, 2)
+
+	cr.AddEntry("complete.txt", []string{CopyGood, CopyGood})
+	cr.AddEntry("corrupt.txt", []string{CopyGood, CopyCorrupt})
+	cr.AddEntry("lost.txt", []string{CopyMissing, CopyMissing})
+	cr.LogMatrix()
+	cr.LogSummary()
+
+	if len(cr.Entries) != 3 || cr.Entries[1].Statuses[1] != CopyCorrupt {
+		t.Errorf("Wrong entries: %v.", cr.Entries)
+	}
+	if cr.UnderReplicatedFiles.Len() != 2 ||
+		!crossCheckReportTestHelper.HasStringItems(cr.UnderReplicatedFiles, "corrupt.txt", "lost.txt") {
+		t.Error("The list of files with too few good copies is wrong.")
+	}
+}
@@ -1,8 +1,8 @@
 package report
 
 import (
+	"fmr/util"
 	"fmt"
-	"log"
 )
 
 // ImportReport Stores statistics of an import process.
@@ -36,6 +36,6 @@ func (ir *ImportReport) LogSummaryForFile(filename string) {
 	numberOfInvalidLines := ir.invalidEntryCountByFile[filename]
 	if numberOfInvalidLines != 0 {
 		message := fmt.Sprintf("There is/are %d invalid line(s) in %s.", numberOfInvalidLines, filename)
-		log.Println(message)
+		util.LogWarn(message, util.Field("file", filename), util.Field("invalid_lines", numberOfInvalidLines))
 	}
 }
@@ -0,0 +1,25 @@
+package report
+
+import "fmr/util"
+
+// The outcomes logged with the entries of the reports, in the "outcome" field of the JSON log.
+const (
+	OutcomeValid        = "valid"
+	OutcomeCorrupt      = "corrupt"
+	OutcomeMissing      = "missing"
+	OutcomeDrifted      = "metadata_changed"
+	OutcomeNew          = "new"
+	OutcomeAppended     = "appended"
+	OutcomeModified     = "modified"
+	OutcomeRepaired     = "repaired"
+	OutcomeUnrepairable = "unrepairable"
+	OutcomeConflict     = "conflict"
+	OutcomeNotFound     = "not_found"
+	OutcomeUnderCopied  = "too_few_copies"
+)
+
+// entryFields Returns the fields logged with the outcome of an entry: the file, the outcome and the given fields.
+func entryFields(filename string, outcome string, fields []util.LogField) []util.LogField {
+
+	return append([]util.LogField{util.Field("file", filename), util.Field("outcome", outcome)}, fields...)
+}
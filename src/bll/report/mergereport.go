@@ -0,0 +1,51 @@
+package report
+
+import (
+	"container/list"
+	"fmr/util"
+	"fmt"
+)
+
+// MergeConflict Describes entries having the same path and algorithm, but different checksums.
+type MergeConflict struct {
+	Filename      string
+	Checksum      string
+	OtherChecksum string
+	Resolution    string
+}
+
+// MergeReport Stores statistics of a merge process.
+type MergeReport struct {
+	CountDuplicates int
+	Conflicts       *list.List
+}
+
+// NewMergeReport Instantiates a new MergeReport object.
+func NewMergeReport() *MergeReport {
+
+	return &MergeReport{0, list.New()}
+}
+
+// AddDuplicate Counts an entry that is already stored with the same checksum.
+func (mr *MergeReport) AddDuplicate(filename string) {
+
+	mr.CountDuplicates++
+}
+
+// AddConflict Adds a conflict to the list of conflicts. The resolution describes which checksum has been kept.
+func (mr *MergeReport) AddConflict(filename string, checksum string, otherChecksum string, resolution string) {
+
+	mr.Conflicts.PushBack(&MergeConflict{filename, checksum, otherChecksum, resolution})
+	util.LogWarn(fmt.Sprintf("Conflict: %s (%s, %s), %s", filename, checksum, otherChecksum, resolution),
+		entryFields(filename, OutcomeConflict, []util.LogField{util.Field("resolution", resolution)})...)
+}
+
+// LogSummary Prints a summary report to the log.
+func (mr *MergeReport) LogSummary(countMerged int) {
+
+	util.LogInfo(fmt.Sprintf(
+		"Summary: %d entries merged, %d duplicate(s) removed, %d conflict(s).",
+		countMerged, mr.CountDuplicates, mr.Conflicts.Len()),
+		util.Field("merged", countMerged), util.Field("duplicates", mr.CountDuplicates),
+		util.Field("conflicts", mr.Conflicts.Len()))
+}
@@ -0,0 +1,35 @@
+package report
+
+import "testing"
+
+func TestMergeReport(t *testing.T) {
+
+	t.Run("AddConflict", testMrAddConflict)
+	t.Run("AddDuplicate", testMrAddDuplicate)
+}
+
+func testMrAddConflict(t *testing.T) {
+
+	mr := NewMergeReport()
+
+	mr.AddConflict("photos/beach.jpg", "1c291ca3", "6b24cc6a", "both kept")
+
+	if mr.Conflicts.Len() != 1 {
+		t.Fatalf("Wrong number of conflicts: %d.", mr.Conflicts.Len())
+	}
+	conflict := mr.Conflicts.Front().Value.(*MergeConflict)
+	if conflict.Filename != "photos/beach.jpg" || conflict.OtherChecksum != "6b24cc6a" {
+		t.Errorf("Wrong conflict: %v.", conflict)
+	}
+}
+
+func testMrAddDuplicate(t *testing.T) {
+
+	mr := NewMergeReport()
+
+	mr.AddDuplicate("photos/beach.jpg")
+
+	if mr.CountDuplicates != 1 {
+		t.Errorf("Wrong number of duplicates: %d.", mr.CountDuplicates)
+	}
+}
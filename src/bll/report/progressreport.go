@@ -0,0 +1,125 @@
+package report
+
+import (
+	"fmr/util"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ProgressReport Displays the progress of a long running process: the number of files and bytes processed, the
+// throughput and the estimated time remaining. In interactive mode the status line is redrawn on the output, otherwise
+// it is written to the log periodically.
+type ProgressReport struct {
+	interactive bool
+	output      io.Writer
+	interval    time.Duration
+	totalFiles  int
+	totalBytes  int64
+	doneFiles   int
+	doneBytes   int64
+	startTime   time.Time
+	lastUpdate  time.Time
+	lastLength  int
+}
+
+// NewProgressReport Instantiates a new ProgressReport object.
+func NewProgressReport(interactive bool, output io.Writer, interval time.Duration) *ProgressReport {
+
+	return &ProgressReport{interactive, output, interval, 0, 0, 0, 0, time.Time{}, time.Time{}, 0}
+}
+
+// Start Resets the counters and stores the total amount of work.
+func (pr *ProgressReport) Start(totalFiles int, totalBytes int64) {
+
+	pr.totalFiles = totalFiles
+	pr.totalBytes = totalBytes
+	pr.doneFiles = 0
+	pr.doneBytes = 0
+	pr.startTime = time.Now()
+	pr.lastUpdate = pr.startTime
+}
+
+// AddBytes Increases the number of bytes processed.
+func (pr *ProgressReport) AddBytes(count int64) {
+
+	pr.doneBytes += count
+	pr.updateIfDue()
+}
+
+// FinishFile Increases the number of files processed.
+func (pr *ProgressReport) FinishFile() {
+
+	pr.doneFiles++
+	pr.updateIfDue()
+}
+
+// Finish Displays the final state of the process.
+func (pr *ProgressReport) Finish() {
+
+	pr.update()
+	if pr.interactive {
+		fmt.Fprintln(pr.output)
+	}
+}
+
+// GetStatus Returns a single line describing the current state of the process.
+func (pr *ProgressReport) GetStatus() string {
+
+	elapsed := time.Since(pr.startTime)
+	status := fmt.Sprintf(
+		"%d/%d files, %s/%s",
+		pr.doneFiles, pr.totalFiles, formatByteCount(pr.doneBytes), formatByteCount(pr.totalBytes))
+
+	if pr.totalBytes > 0 {
+		status += fmt.Sprintf(" (%.1f%%)", float64(pr.doneBytes)*100/float64(pr.totalBytes))
+	}
+	if elapsed.Seconds() >= 1 && pr.doneBytes > 0 {
+		rate := float64(pr.doneBytes) / elapsed.Seconds()
+		remaining := time.Duration(float64(pr.totalBytes-pr.doneBytes)/rate) * time.Second
+		status += fmt.Sprintf(", %.1f MB/s, ETA %s", rate/1000000, remaining.Round(time.Second))
+	}
+
+	return status
+}
+
+func (pr *ProgressReport) updateIfDue() {
+
+	if time.Since(pr.lastUpdate) >= pr.interval {
+		pr.update()
+	}
+}
+
+func (pr *ProgressReport) update() {
+
+	pr.lastUpdate = time.Now()
+	status := pr.GetStatus()
+
+	if pr.interactive {
+		padding := ""
+		if len(status) < pr.lastLength {
+			padding = strings.Repeat(" ", pr.lastLength-len(status))
+		}
+		fmt.Fprint(pr.output, "\r"+status+padding)
+		pr.lastLength = len(status)
+	} else {
+		util.LogInfo("Progress: "+status, util.Field("files_done", pr.doneFiles), util.Field("bytes_done", pr.doneBytes))
+	}
+}
+
+func formatByteCount(count int64) string {
+
+	const unit = 1000
+	if count < unit {
+		return fmt.Sprintf("%d B", count)
+	}
+
+	divisor, exponent := int64(unit), 0
+	for n := count / unit; n >= unit; n /= unit {
+		divisor *= unit
+		exponent++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(count)/float64(divisor), "kMGTPE"[exponent])
+}
@@ -0,0 +1,64 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressReport(t *testing.T) {
+
+	t.Run("FormatByteCount", testPrFormatByteCount)
+	t.Run("GetStatus", testPrGetStatus)
+	t.Run("Interactive", testPrInteractive)
+}
+
+func testPrFormatByteCount(t *testing.T) {
+
+	assertByteCount(t, 0, "0 B")
+	assertByteCount(t, 999, "999 B")
+	assertByteCount(t, 1500, "1.5 kB")
+	assertByteCount(t, 52400000, "52.4 MB")
+	assertByteCount(t, 200000000000, "200.0 GB")
+}
+
+func testPrGetStatus(t *testing.T) {
+
+	pr := NewProgressReport(false, nil, time.Hour)
+
+	pr.Start(4, 2000)
+	pr.AddBytes(500)
+	pr.FinishFile()
+	status := pr.GetStatus()
+
+	if status != "1/4 files, 500 B/2.0 kB (25.0%)" {
+		t.Errorf("Wrong status: \"%s\".", status)
+	}
+}
+
+func testPrInteractive(t *testing.T) {
+
+	output := new(bytes.Buffer)
+	pr := NewProgressReport(true, output, 0)
+
+	pr.Start(2, 10)
+	pr.AddBytes(10)
+	pr.FinishFile()
+	pr.FinishFile()
+	pr.Finish()
+
+	lines := strings.Split(output.String(), "\r")
+	lastLine := lines[len(lines)-1]
+	if !strings.HasPrefix(lastLine, "2/2 files, 10 B/10 B (100.0%)") || !strings.HasSuffix(lastLine, "\n") {
+		t.Errorf("Wrong final status line: \"%s\".", lastLine)
+	}
+}
+
+func assertByteCount(t *testing.T, count int64, expectedText string) {
+
+	text := formatByteCount(count)
+	if text != expectedText {
+		t.Errorf("Wrong formatted byte count for %d: \"%s\" (expected: \"%s\").", count, text, expectedText)
+	}
+}
@@ -0,0 +1,162 @@
+package report
+
+import (
+	"container/list"
+	"encoding/hex"
+	"encoding/json"
+	"fmr/dal"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// Output formats of the query results. Any other format is treated as a Go template executed for each entry.
+const (
+	QueryFormatTable = "table"
+	QueryFormatCsv   = "csv"
+	QueryFormatJSON  = "json"
+)
+
+// QueryEntry The representation of a fingerprint in JSON and template output.
+type QueryEntry struct {
+	Filename   string            `json:"filename"`
+	Checksum   string            `json:"checksum"`
+	Algorithm  string            `json:"algorithm"`
+	CreatedAt  string            `json:"created_at"`
+	Creator    string            `json:"creator"`
+	Note       string            `json:"note"`
+	Size       int64             `json:"size"`
+	ModifiedAt string            `json:"modified_at"`
+	Tags       []string          `json:"tags"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// QueryReport Collects the results of a query and prints them in the requested format.
+type QueryReport struct {
+	output       io.Writer
+	format       string
+	template     *template.Template
+	fingerprints *list.List
+}
+
+// NewQueryReport Instantiates a new QueryReport object. Returns an error if the format is neither a known one nor a
+// valid template.
+func NewQueryReport(output io.Writer, format string) (*QueryReport, error) {
+
+	var entryTemplate *template.Template
+	if format != QueryFormatTable && format != QueryFormatCsv && format != QueryFormatJSON {
+		if !strings.HasSuffix(format, "\n") {
+			format += "\n"
+		}
+		var err error
+		if entryTemplate, err = template.New("entry").Parse(format); err != nil {
+			return nil, err
+		}
+	}
+
+	return &QueryReport{output, format, entryTemplate, list.New()}, nil
+}
+
+// AddFingerprint Adds the given fingerprint to the results.
+func (qr *QueryReport) AddFingerprint(fingerprint *dal.Fingerprint) {
+
+	qr.fingerprints.PushBack(fingerprint)
+}
+
+// Count Returns the number of results.
+func (qr *QueryReport) Count() int {
+
+	return qr.fingerprints.Len()
+}
+
+// Write Prints the results ordered by filename.
+func (qr *QueryReport) Write() error {
+
+	fingerprints := qr.getSortedFingerprints()
+
+	switch qr.format {
+	case QueryFormatTable:
+		return qr.writeTable(fingerprints)
+	case QueryFormatCsv:
+		return dal.WriteFingerprints(fingerprints, nil, qr.output)
+	case QueryFormatJSON:
+		return qr.writeJSON(fingerprints)
+	}
+
+	return qr.writeTemplate(fingerprints)
+}
+
+func (qr *QueryReport) getSortedFingerprints() *list.List {
+
+	fingerprints := make([]*dal.Fingerprint, 0, qr.fingerprints.Len())
+	for element := qr.fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprints = append(fingerprints, element.Value.(*dal.Fingerprint))
+	}
+	sort.SliceStable(fingerprints, func(i, j int) bool { return fingerprints[i].Filename < fingerprints[j].Filename })
+
+	// CsvDatabase writes the list from front to back, the sorted result is pushed accordingly.
+	sorted := list.New()
+	for _, fingerprint := range fingerprints {
+		sorted.PushBack(fingerprint)
+	}
+
+	return sorted
+}
+
+func (qr *QueryReport) writeTable(fingerprints *list.List) error {
+
+	writer := tabwriter.NewWriter(qr.output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "FILENAME\tALGORITHM\tCHECKSUM\tCREATED\tTAGS")
+
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		fmt.Fprintf(
+			writer, "%s\t%s\t%s\t%s\t%s\n",
+			fingerprint.Filename, fingerprint.Algorithm, hex.EncodeToString(fingerprint.Checksum),
+			fingerprint.CreatedAt, strings.Join(fingerprint.Tags, dal.TagSeparator))
+	}
+
+	return writer.Flush()
+}
+
+func (qr *QueryReport) writeJSON(fingerprints *list.List) error {
+
+	entries := make([]QueryEntry, 0, fingerprints.Len())
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		entries = append(entries, createQueryEntry(element.Value.(*dal.Fingerprint)))
+	}
+
+	encoder := json.NewEncoder(qr.output)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(entries)
+}
+
+func (qr *QueryReport) writeTemplate(fingerprints *list.List) error {
+
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		entry := createQueryEntry(element.Value.(*dal.Fingerprint))
+		if err := qr.template.Execute(qr.output, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createQueryEntry(fingerprint *dal.Fingerprint) QueryEntry {
+
+	tags := fingerprint.Tags
+	if tags == nil {
+		tags = make([]string, 0)
+	}
+
+	return QueryEntry{
+		Filename: fingerprint.Filename, Checksum: hex.EncodeToString(fingerprint.Checksum),
+		Algorithm: fingerprint.Algorithm, CreatedAt: fingerprint.CreatedAt, Creator: fingerprint.Creator,
+		Note: fingerprint.Note, Size: fingerprint.Size, ModifiedAt: fingerprint.ModifiedAt,
+		Tags: tags, Metadata: fingerprint.Metadata}
+}
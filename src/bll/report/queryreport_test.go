@@ -0,0 +1,86 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmr/dal"
+	"strings"
+	"testing"
+)
+
+func TestQueryReport(t *testing.T) {
+
+	t.Run("Write_Table", testQrWriteTable)
+	t.Run("Write_Csv", testQrWriteCsv)
+	t.Run("Write_JSON", testQrWriteJSON)
+	t.Run("Write_Template", testQrWriteTemplate)
+	t.Run("InvalidTemplate", testQrInvalidTemplate)
+}
+
+func testQrWriteTable(t *testing.T) {
+
+	output := writeQueryReport(t, QueryFormatTable)
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "FILENAME") || !strings.HasPrefix(lines[1], "a.txt") {
+		t.Errorf("Wrong table: %q.", output)
+	}
+}
+
+func testQrWriteCsv(t *testing.T) {
+
+	output := writeQueryReport(t, QueryFormatCsv)
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "a.txt,0c17,") {
+		t.Errorf("Wrong CSV: %q.", output)
+	}
+}
+
+func testQrWriteJSON(t *testing.T) {
+
+	output := writeQueryReport(t, QueryFormatJSON)
+
+	var entries []QueryEntry
+	if err := json.Unmarshal([]byte(output), &entries); err != nil {
+		t.Fatalf("Invalid JSON: %s.", err)
+	}
+	if len(entries) != 2 || entries[0].Checksum != "0c17" || entries[1].Tags[0] != "contract" {
+		t.Errorf("Wrong entries: %v.", entries)
+	}
+}
+
+func testQrWriteTemplate(t *testing.T) {
+
+	output := writeQueryReport(t, "{{.Checksum}}  {{.Filename}}")
+
+	if output != "0c17  a.txt\n222d  b.txt\n" {
+		t.Errorf("Wrong output: %q.", output)
+	}
+}
+
+func testQrInvalidTemplate(t *testing.T) {
+
+	_, err := NewQueryReport(new(bytes.Buffer), "{{.Filename")
+
+	if err == nil {
+		t.Error("Invalid template should be rejected.")
+	}
+}
+
+func writeQueryReport(t *testing.T, format string) string {
+
+	output := new(bytes.Buffer)
+	qr, err := NewQueryReport(output, format)
+	if err != nil {
+		t.Fatalf("Cannot create report: %s.", err)
+	}
+
+	qr.AddFingerprint(&dal.Fingerprint{Filename: "b.txt", Checksum: []byte{34, 45}, Tags: []string{"contract"}})
+	qr.AddFingerprint(&dal.Fingerprint{Filename: "a.txt", Checksum: []byte{12, 23}})
+	if err := qr.Write(); err != nil {
+		t.Fatalf("Cannot write report: %s.", err)
+	}
+
+	return output.String()
+}
@@ -0,0 +1,52 @@
+package report
+
+import (
+	"container/list"
+	"fmr/util"
+	"fmt"
+)
+
+// RepairReport Stores statistics of a repair process.
+type RepairReport struct {
+	CountValid        int
+	RepairedFiles     *list.List
+	UnrepairableFiles *list.List
+}
+
+// NewRepairReport Instantiates a new RepairReport object.
+func NewRepairReport() *RepairReport {
+
+	return &RepairReport{0, list.New(), list.New()}
+}
+
+// AddValidFile Counts a file that needs no repair.
+func (rr *RepairReport) AddValidFile(filename string) {
+
+	rr.CountValid++
+}
+
+// AddRepairedFile Adds the given file to the list of repaired files.
+func (rr *RepairReport) AddRepairedFile(filename string, source string) {
+
+	rr.RepairedFiles.PushFront(filename)
+	util.LogInfo(fmt.Sprintf("Repaired: %s (%s)", filename, source),
+		entryFields(filename, OutcomeRepaired, []util.LogField{util.Field("source", source)})...)
+}
+
+// AddUnrepairableFile Adds the given file to the list of files that could not be repaired.
+func (rr *RepairReport) AddUnrepairableFile(filename string, reason string) {
+
+	rr.UnrepairableFiles.PushFront(filename)
+	util.LogError(fmt.Sprintf("Cannot repair: %s (%s)", filename, reason),
+		entryFields(filename, OutcomeUnrepairable, []util.LogField{util.Field("reason", reason)})...)
+}
+
+// LogSummary Prints a summary report to the log.
+func (rr *RepairReport) LogSummary() {
+
+	util.LogInfo(fmt.Sprintf(
+		"Summary: %d repaired, %d cannot be repaired, %d valid.",
+		rr.RepairedFiles.Len(), rr.UnrepairableFiles.Len(), rr.CountValid),
+		util.Field("repaired", rr.RepairedFiles.Len()), util.Field("unrepairable", rr.UnrepairableFiles.Len()),
+		util.Field("valid", rr.CountValid))
+}
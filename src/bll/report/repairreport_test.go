@@ -0,0 +1,27 @@
+package report
+
+import (
+	"fmr/util"
+	"testing"
+)
+
+var repairReportTestHelper = util.NewTestHelper()
+
+func TestRepairReport(t *testing.T) {
+
+	rr := NewRepairReport()
+
+	rr.AddValidFile("valid.txt")
+	rr.AddRepairedFile("disk.img", "3 block(s) reconstructed")
+	rr.AddUnrepairableFile("lost.img", "no recovery data")
+
+	if rr.CountValid != 1 {
+		t.Errorf("Wrong number of valid files: %d.", rr.CountValid)
+	}
+	if !repairReportTestHelper.HasStringItems(rr.RepairedFiles, "disk.img") {
+		t.Error("The list of repaired files is incomplete.")
+	}
+	if !repairReportTestHelper.HasStringItems(rr.UnrepairableFiles, "lost.img") {
+		t.Error("The list of files that cannot be repaired is incomplete.")
+	}
+}
@@ -2,43 +2,69 @@ package report
 
 import (
 	"container/list"
+	"fmr/util"
 	"fmt"
-	"log"
+	"strings"
 )
 
 // VerificationReport Stores statistics of a verification process.
 type VerificationReport struct {
-	CountAll     int
-	CorruptFiles *list.List
-	MissingFiles *list.List
+	CountAll      int
+	CorruptFiles  *list.List
+	MissingFiles  *list.List
+	CorruptRanges map[string][]ByteRange
+	DriftedFiles  *list.List
 }
 
 // NewVerificationReport Instantiates a new VerificationReport object.
 func NewVerificationReport() *VerificationReport {
 
-	return &VerificationReport{0, list.New(), list.New()}
+	return &VerificationReport{0, list.New(), list.New(), make(map[string][]ByteRange), list.New()}
 }
 
-// AddCorruptFile Adds the given file to the list of corrupt files.
-func (vr *VerificationReport) AddCorruptFile(filename string) {
+// AddCorruptFile Adds the given file to the list of corrupt files. The given fields are logged with it.
+func (vr *VerificationReport) AddCorruptFile(filename string, fields ...util.LogField) {
 
 	vr.CorruptFiles.PushFront(filename)
 	vr.CountAll++
-	log.Println(fmt.Sprintf("Corrupt: %s", filename))
+	util.LogError(fmt.Sprintf("Corrupt: %s", filename), entryFields(filename, OutcomeCorrupt, fields)...)
+}
+
+// AddCorruptRanges Adds the given file to the list of corrupt files along with the byte ranges that do not match the
+// stored block hashes.
+func (vr *VerificationReport) AddCorruptRanges(filename string, ranges []ByteRange, fields ...util.LogField) {
+
+	vr.CorruptFiles.PushFront(filename)
+	vr.CorruptRanges[filename] = ranges
+	vr.CountAll++
+	fields = append(fields, util.Field("bytes", FormatByteRanges(ranges)))
+	util.LogError(fmt.Sprintf("Corrupt: %s (bytes %s)", filename, FormatByteRanges(ranges)),
+		entryFields(filename, OutcomeCorrupt, fields)...)
 }
 
 // AddMissingFile Adds the given file to the list of missing files.
-func (vr *VerificationReport) AddMissingFile(filename string) {
+func (vr *VerificationReport) AddMissingFile(filename string, fields ...util.LogField) {
 
 	vr.MissingFiles.PushFront(filename)
 	vr.CountAll++
-	log.Println(fmt.Sprintf("Missing: %s", filename))
+	util.LogWarn(fmt.Sprintf("Missing: %s", filename), entryFields(filename, OutcomeMissing, fields)...)
 }
 
-// AddValidFile Logs that the given file is valid.
-func (vr *VerificationReport) AddValidFile(filename string) {
+// AddDriftedFile Adds the given file to the list of files whose permissions, ownership or extended attributes differ
+// from the stored ones. Drift is reported separately from the content, so it does not change the count of files.
+func (vr *VerificationReport) AddDriftedFile(filename string, changes []string, fields ...util.LogField) {
+
+	vr.DriftedFiles.PushFront(filename)
+	fields = append(fields, util.Field("changes", changes))
+	util.LogWarn(fmt.Sprintf("Metadata changed: %s (%s)", filename, strings.Join(changes, ", ")),
+		entryFields(filename, OutcomeDrifted, fields)...)
+}
+
+// AddValidFile Logs that the given file is valid, at debug level.
+func (vr *VerificationReport) AddValidFile(filename string, fields ...util.LogField) {
 
 	vr.CountAll++
+	util.LogDebug(fmt.Sprintf("Valid: %s", filename), entryFields(filename, OutcomeValid, fields)...)
 }
 
 // LogSummary Prints a summary report to the log.
@@ -48,13 +74,20 @@ func (vr *VerificationReport) LogSummary(displayCorruptCount bool) {
 	countMissing := vr.MissingFiles.Len()
 	countValid := vr.CountAll - countCorrupt - countMissing
 
+	fields := []util.LogField{
+		util.Field("total", vr.CountAll), util.Field("valid", countValid), util.Field("missing", countMissing)}
 	if displayCorruptCount {
-		log.Println(fmt.Sprintf(
+		util.LogInfo(fmt.Sprintf(
 			"Summary: %d/%d valid, %d missing, %d corrupt.",
-			countValid, vr.CountAll, countMissing, countCorrupt))
+			countValid, vr.CountAll, countMissing, countCorrupt), append(fields, util.Field("corrupt", countCorrupt))...)
 	} else {
-		log.Println(fmt.Sprintf(
+		util.LogInfo(fmt.Sprintf(
 			"Summary: %d/%d exist(s), %d missing.",
-			countValid, vr.CountAll, countMissing))
+			countValid, vr.CountAll, countMissing), fields...)
+	}
+
+	if vr.DriftedFiles.Len() > 0 {
+		util.LogInfo(fmt.Sprintf("Metadata changed: %d file(s).", vr.DriftedFiles.Len()),
+			util.Field("metadata_changed", vr.DriftedFiles.Len()))
 	}
 }
@@ -11,6 +11,8 @@ var verificationReportTestHelper = util.NewTestHelper()
 func TestVerificationReport(t *testing.T) {
 
 	t.Run("AddCorruptFile", testVrAddCorruptFile)
+	t.Run("AddCorruptRanges", testVrAddCorruptRanges)
+	t.Run("AddDriftedFile", testVrAddDriftedFile)
 	t.Run("AddMissingFile", testVrAddMissingFile)
 	t.Run("AddValidFile", testVrAddValidFile)
 }
@@ -31,6 +33,38 @@ func testVrAddCorruptFile(t *testing.T) {
 	}
 }
 
+func testVrAddCorruptRanges(t *testing.T) {
+
+	vr := NewVerificationReport()
+	testItem := "disk.img"
+	ranges := []ByteRange{{0, 4096}, {8192, 10000}}
+
+	vr.AddCorruptRanges(testItem, ranges)
+
+	assertAllCount(t, vr, 1)
+	if !verificationReportTestHelper.HasStringItems(vr.CorruptFiles, testItem) {
+		t.Error("The list of corrupt files is incomplete.")
+	}
+	if FormatByteRanges(vr.CorruptRanges[testItem]) != "0-4095, 8192-9999" {
+		t.Errorf("Wrong corrupt ranges: %v.", vr.CorruptRanges[testItem])
+	}
+}
+
+func testVrAddDriftedFile(t *testing.T) {
+
+	vr := NewVerificationReport()
+	testItem := "etc/shadow"
+
+	vr.AddValidFile(testItem)
+	vr.AddDriftedFile(testItem, []string{"mode 0640 -> 0644"})
+
+	assertAllCount(t, vr, 1)
+	assertListLength(t, vr.CorruptFiles, "corrupt", 0)
+	if !verificationReportTestHelper.HasStringItems(vr.DriftedFiles, testItem) {
+		t.Error("The list of files with changed metadata is incomplete.")
+	}
+}
+
 func testVrAddMissingFile(t *testing.T) {
 
 	vr := NewVerificationReport()
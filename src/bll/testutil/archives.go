@@ -0,0 +1,69 @@
+package testutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmr/util"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// CreateZipArchive Creates a ZIP archive containing the given files (name: content), stored without compression.
+func CreateZipArchive(archivePath string, files map[string]string) {
+
+	file, err := os.Create(archivePath)
+	util.CheckErr(err, "Cannot create archive "+archivePath+".")
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	for _, name := range getSortedNames(files) {
+		memberWriter, err := writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		util.CheckErr(err, "Cannot add member "+name+".")
+		memberWriter.Write([]byte(files[name]))
+	}
+
+	util.CheckErr(writer.Close(), "Cannot close archive "+archivePath+".")
+}
+
+// CreateTarGzArchive Creates a gzip compressed TAR archive containing the given files (name: content).
+func CreateTarGzArchive(archivePath string, files map[string]string) {
+
+	file, err := os.Create(archivePath)
+	util.CheckErr(err, "Cannot create archive "+archivePath+".")
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	writer := tar.NewWriter(gzipWriter)
+	for _, name := range getSortedNames(files) {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		util.CheckErr(writer.WriteHeader(header), "Cannot add member "+name+".")
+		writer.Write([]byte(files[name]))
+	}
+
+	util.CheckErr(writer.Close(), "Cannot close archive "+archivePath+".")
+	util.CheckErr(gzipWriter.Close(), "Cannot close archive "+archivePath+".")
+}
+
+// CorruptFile Replaces the first occurrence of the given text in the file with a text of the same length.
+func CorruptFile(filePath string, text string, replacement string) {
+
+	content, err := ioutil.ReadFile(filePath)
+	util.CheckErr(err, "Cannot read file "+filePath+".")
+
+	content = bytes.Replace(content, []byte(text), []byte(replacement), 1)
+	util.CheckErr(ioutil.WriteFile(filePath, content, 0644), "Cannot write file "+filePath+".")
+}
+
+func getSortedNames(files map[string]string) []string {
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -23,7 +23,9 @@ func CreateFingerprint(
 	checksumBytes, err := hex.DecodeString(checksum)
 	util.CheckErr(err, "Unable to convert checksum from string.")
 
-	return &dal.Fingerprint{filename, checksumBytes, algorithm, createdAt, creator, note}
+	return &dal.Fingerprint{
+		Filename: filename, Checksum: checksumBytes, Algorithm: algorithm,
+		CreatedAt: createdAt, Creator: creator, Note: note}
 }
 
 // CreateList Creates a list containing the given items.
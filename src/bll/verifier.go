@@ -1,18 +1,33 @@
 package bll
 
 import (
+	"container/list"
 	"fmr/bll/common"
 	"fmr/bll/report"
 	"fmr/dal"
 	"fmr/util"
-	"path"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 )
 
 // Verifier Stores settings related to verification.
 type Verifier struct {
-	Db       dal.Database
-	BasePath string
-	Report   *report.VerificationReport
+	Db                dal.Database
+	BasePath          string
+	Report            *report.VerificationReport
+	progress          common.ProgressListener
+	recoveryDirectory string
+	resolver          *util.PathResolver
+	readOptions       common.ReadOptions
+}
+
+// archiveKey Identifies the archive members that can be verified by reading the archive once.
+type archiveKey struct {
+	archivePath string
+	algorithm   string
 }
 
 // NewVerifier Instantiates a new Verifier object.
@@ -21,7 +36,35 @@ func NewVerifier(db dal.Database, basePath string) Verifier {
 	basePath = util.NormalizePath(basePath)
 	report := report.NewVerificationReport()
 
-	return Verifier{db, basePath, report}
+	resolver := util.NewPathResolver(basePath, util.NewPathMatcher(false, false))
+
+	return Verifier{db, basePath, report, common.NullProgressListener{}, "", resolver, common.ReadOptions{}}
+}
+
+// SetPathMatcher Sets how the stored filenames are matched with the names on disk, so that files whose name differs
+// only in Unicode normalization or case are found.
+func (verifier *Verifier) SetPathMatcher(matcher util.PathMatcher) {
+
+	verifier.resolver = util.NewPathResolver(verifier.BasePath, matcher)
+}
+
+// SetProgressListener Sets the listener that will be notified about the progress of the verification.
+func (verifier *Verifier) SetProgressListener(listener common.ProgressListener) {
+
+	verifier.progress = listener
+}
+
+// SetReadOptions Sets how the files are read when their checksum is verified.
+func (verifier *Verifier) SetReadOptions(options common.ReadOptions) {
+
+	verifier.readOptions = options
+}
+
+// SetRecoveryDirectory Sets the directory of the recovery files. The corrupt files having recovery data are counted
+// after the verification, as they can be repaired.
+func (verifier *Verifier) SetRecoveryDirectory(recoveryDirectory string) {
+
+	verifier.recoveryDirectory = recoveryDirectory
 }
 
 // Verify Verifies checksums in the given file.
@@ -30,41 +73,248 @@ func (verifier *Verifier) Verify(verifyNamesOnly bool, fpFilter common.Fingerpri
 	verifier.Db.LoadFingerprints()
 	verifier.verifyEntries(verifyNamesOnly, fpFilter)
 	verifier.Report.LogSummary(!verifyNamesOnly)
+	verifier.logRepairableFiles()
+}
+
+// CountRepairableFiles Returns the number of corrupt files having recovery data.
+func (verifier *Verifier) CountRepairableFiles() int {
+
+	if verifier.recoveryDirectory == "" || verifier.Report.CorruptFiles.Len() == 0 {
+		return 0
+	}
+
+	corruptFiles := toStringSet(verifier.Report.CorruptFiles)
+
+	count := 0
+	for element := verifier.Db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		recoveryPath := common.GetRecoveryFilePath(verifier.recoveryDirectory, fingerprint.Checksum)
+		if corruptFiles[fingerprint.Filename] && util.CheckIfFileExists(recoveryPath) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (verifier *Verifier) logRepairableFiles() {
+
+	if count := verifier.CountRepairableFiles(); count > 0 {
+		util.LogInfo(fmt.Sprintf("%d corrupt file(s) have recovery data and can be repaired with the repair task.", count),
+			util.Field("repairable", count))
+	}
 }
 
 func (verifier *Verifier) verifyEntries(verifyNamesOnly bool, fpFilter common.FingerprintFilter) {
 
-	fingerprints := verifier.Db.GetFingerprints()
+	fingerprints := verifier.filterFingerprints(fpFilter)
+	verifier.startProgress(fingerprints, verifyNamesOnly)
+
+	archiveMembers := make(map[archiveKey][]*dal.Fingerprint)
+	archives := make([]archiveKey, 0)
 
 	for element := fingerprints.Front(); element != nil; element = element.Next() {
 		fingerprint := element.Value.(*dal.Fingerprint)
-		if fpFilter.FilterFingerprint(fingerprint) {
+		if archivePath, _, isMember := common.SplitArchivePath(fingerprint.Filename, verifier.getFullPath); isMember {
+			key := archiveKey{archivePath, fingerprint.Algorithm}
+			if _, ok := archiveMembers[key]; !ok {
+				archives = append(archives, key)
+			}
+			archiveMembers[key] = append(archiveMembers[key], fingerprint)
+		} else {
 			verifier.verifyEntry(fingerprint, verifyNamesOnly)
 		}
 	}
+
+	for _, key := range archives {
+		verifier.verifyArchiveMembers(key, archiveMembers[key], verifyNamesOnly)
+	}
+
+	verifier.progress.Finish()
+}
+
+func (verifier *Verifier) filterFingerprints(fpFilter common.FingerprintFilter) *list.List {
+
+	fingerprints := list.New()
+
+	for element := verifier.Db.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*dal.Fingerprint)
+		if fpFilter.FilterFingerprint(fingerprint) {
+			fingerprints.PushBack(fingerprint)
+		}
+	}
+
+	return fingerprints
+}
+
+func (verifier *Verifier) startProgress(fingerprints *list.List, verifyNamesOnly bool) {
+
+	var totalBytes int64
+
+	if !verifyNamesOnly {
+		for element := fingerprints.Front(); element != nil; element = element.Next() {
+			fingerprint := element.Value.(*dal.Fingerprint)
+			if _, _, isMember := common.SplitArchivePath(fingerprint.Filename, verifier.getFullPath); isMember {
+				totalBytes += fingerprint.Size
+			} else {
+				totalBytes += util.GetFileSize(verifier.getFullPath(fingerprint.Filename))
+			}
+		}
+	}
+
+	verifier.progress.Start(fingerprints.Len(), totalBytes)
+}
+
+// getFullPath Returns the path of the file on disk from its stored name.
+func (verifier *Verifier) getFullPath(filename string) string {
+
+	fullPath, _ := verifier.resolver.Resolve(filename)
+
+	return fullPath
 }
 
 func (verifier *Verifier) verifyEntry(fingerprint *dal.Fingerprint, verifyNameOnly bool) {
 
-	fullPath := path.Join(verifier.BasePath, fingerprint.Filename)
+	fullPath := verifier.getFullPath(fingerprint.Filename)
+	verifier.verifyAttributes(fingerprint, fullPath)
 
-	if !util.CheckIfFileExists(fullPath) {
-		verifier.Report.AddMissingFile(fingerprint.Filename)
+	if fingerprint.LinkTarget != "" {
+		verifier.verifyLink(fingerprint, fullPath, verifyNameOnly)
+		verifier.progress.FinishFile()
+	} else if !util.CheckIfFileExists(fullPath) {
+		verifier.Report.AddMissingFile(fingerprint.Filename, algorithmField(fingerprint))
+		verifier.progress.FinishFile()
 	} else if !verifyNameOnly {
 		verifier.verifyChecksum(fingerprint, fullPath)
 	} else {
-		verifier.Report.AddValidFile(fingerprint.Filename)
+		verifier.Report.AddValidFile(fingerprint.Filename, algorithmField(fingerprint))
+		verifier.progress.FinishFile()
+	}
+}
+
+// verifyAttributes Reports the file if its permissions, ownership or extended attributes differ from the stored ones.
+// Missing files and entries without stored attributes are ignored.
+func (verifier *Verifier) verifyAttributes(fingerprint *dal.Fingerprint, fullPath string) {
+
+	if fingerprint.Mode == "" {
+		return
+	}
+	if _, err := os.Lstat(fullPath); err != nil {
+		return
+	}
+
+	changes, err := common.FindAttributeChanges(fullPath, fingerprint)
+	if err != nil {
+		util.CheckErrDontPanic(err, "Cannot read the attributes of "+fullPath+".")
+	} else if len(changes) > 0 {
+		verifier.Report.AddDriftedFile(fingerprint.Filename, changes, algorithmField(fingerprint))
+	}
+}
+
+// verifyLink Verifies a symbolic link recorded with its target: the link is corrupt if it has been replaced by a file
+// or points elsewhere.
+func (verifier *Verifier) verifyLink(fingerprint *dal.Fingerprint, fullPath string, verifyNameOnly bool) {
+
+	if _, err := os.Lstat(fullPath); err != nil {
+		verifier.Report.AddMissingFile(fingerprint.Filename, algorithmField(fingerprint))
+		return
+	}
+
+	target, err := os.Readlink(fullPath)
+	hasher := common.NewHasher(fingerprint.Algorithm)
+	isValid := err == nil && util.CompareByteSlices(hasher.CalculateLinkChecksum(target), fingerprint.Checksum)
+	if !verifyNameOnly && !isValid {
+		verifier.Report.AddCorruptFile(fingerprint.Filename, algorithmField(fingerprint))
+	} else {
+		verifier.Report.AddValidFile(fingerprint.Filename, algorithmField(fingerprint))
 	}
 }
 
 func (verifier *Verifier) verifyChecksum(fingerprint *dal.Fingerprint, fullPath string) {
 
+	start := time.Now()
 	hasher := common.NewHasher(fingerprint.Algorithm)
-	checksum := hasher.CalculateChecksum(fullPath)
+	hasher.SetProgressListener(verifier.progress)
+	verifier.readOptions.Apply(&hasher)
+
+	validBlocks := common.HasValidBlocks(fingerprint)
+	if fingerprint.BlockSize > 0 && !validBlocks {
+		util.LogWarn(fmt.Sprintf("Block hashes of %s are missing or do not match the block root, ignored.",
+			fingerprint.Filename), util.Field("file", fingerprint.Filename))
+	} else if validBlocks {
+		hasher.SetBlockSize(fingerprint.BlockSize)
+	}
+	checksum, blocks := hasher.CalculateChecksumWithBlocks(fullPath)
+	fields := []util.LogField{algorithmField(fingerprint), util.Field("duration", time.Since(start).Seconds())}
 
 	if util.CompareByteSlices(checksum, fingerprint.Checksum) {
-		verifier.Report.AddValidFile(fingerprint.Filename)
+		verifier.Report.AddValidFile(fingerprint.Filename, fields...)
+	} else if validBlocks {
+		verifier.addCorruptBlocks(fingerprint, blocks, fullPath, fields)
 	} else {
-		verifier.Report.AddCorruptFile(fingerprint.Filename)
+		verifier.Report.AddCorruptFile(fingerprint.Filename, fields...)
 	}
 }
+
+// addCorruptBlocks Reports the byte ranges of the blocks that do not match the stored block hashes.
+func (verifier *Verifier) addCorruptBlocks(
+	fingerprint *dal.Fingerprint, blocks [][]byte, fullPath string, fields []util.LogField) {
+
+	ranges := common.FindChangedRanges(
+		fingerprint.Blocks, blocks, fingerprint.BlockSize, fingerprint.Size, util.GetFileSize(fullPath))
+
+	if len(ranges) == 0 {
+		verifier.Report.AddCorruptFile(fingerprint.Filename, fields...)
+	} else {
+		verifier.Report.AddCorruptRanges(fingerprint.Filename, ranges, fields...)
+	}
+}
+
+// verifyArchiveMembers Verifies the given members of an archive, reading the archive only once. If the archive cannot
+// be read to the end, the members not found are reported as corrupt instead of missing.
+func (verifier *Verifier) verifyArchiveMembers(key archiveKey, members []*dal.Fingerprint, verifyNamesOnly bool) {
+
+	fullPath := verifier.getFullPath(key.archivePath)
+	checksums := make(map[string][]byte)
+	var err error
+
+	if !util.CheckIfFileExists(fullPath) {
+		err = os.ErrNotExist
+	} else if verifyNamesOnly {
+		err = common.WalkArchive(fullPath, func(member common.ArchiveMember, reader io.Reader) error {
+			checksums[member.Name] = []byte{}
+			return nil
+		})
+	} else {
+		hasher := common.NewHasher(key.algorithm)
+		verifier.readOptions.Apply(&hasher)
+		checksums, err = hasher.CalculateArchiveChecksums(fullPath)
+	}
+	if err != nil && err != os.ErrNotExist {
+		util.LogWarn(fmt.Sprintf("Cannot read archive %s: %s.", key.archivePath, err), util.Field("file", key.archivePath))
+	}
+
+	for _, fingerprint := range members {
+		memberName := strings.TrimPrefix(fingerprint.Filename, key.archivePath+common.ArchiveSeparator)
+		checksum, found := checksums[memberName]
+
+		if !found && (err == nil || err == os.ErrNotExist) {
+			verifier.Report.AddMissingFile(fingerprint.Filename, algorithmField(fingerprint))
+		} else if !found || (!verifyNamesOnly && !util.CompareByteSlices(checksum, fingerprint.Checksum)) {
+			verifier.Report.AddCorruptFile(fingerprint.Filename, algorithmField(fingerprint))
+		} else {
+			verifier.Report.AddValidFile(fingerprint.Filename, algorithmField(fingerprint))
+		}
+
+		if !verifyNamesOnly {
+			verifier.progress.AddBytes(fingerprint.Size)
+		}
+		verifier.progress.FinishFile()
+	}
+}
+
+// algorithmField Returns the algorithm of the given fingerprint as a log field.
+func algorithmField(fingerprint *dal.Fingerprint) util.LogField {
+
+	return util.Field("algorithm", fingerprint.Algorithm)
+}
@@ -2,8 +2,12 @@ package bll
 
 import (
 	"fmr/bll/common"
+	"fmr/bll/report"
 	"fmr/bll/testutil"
 	"fmr/dal"
+	"fmr/util"
+	"os"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -15,6 +19,11 @@ func TestVerifier(t *testing.T) {
 	t.Run("Verify", testVerifierVerify)
 	t.Run("Verify_Filtered", testVerifierVerifyFiltered)
 	t.Run("Verify_NamesOnly", testVerifierVerifyNamesOnly)
+	t.Run("Verify_ArchiveMembers", testVerifierVerifyArchiveMembers)
+	t.Run("Verify_CorruptRanges", testVerifierVerifyCorruptRanges)
+	t.Run("Verify_PathMatcher", testVerifierVerifyPathMatcher)
+	t.Run("Verify_MetadataDrift", testVerifierVerifyMetadataDrift)
+	t.Run("Verify_ChecksumXattrs", testVerifierVerifyChecksumXattrs)
 
 	tearDownVerifierTests()
 }
@@ -68,6 +77,147 @@ func testVerifierVerifyNamesOnly(t *testing.T) {
 	}
 }
 
+func testVerifierVerifyArchiveMembers(t *testing.T) {
+
+	// Arrange.
+	members := map[string]string{"hello.txt": "Hello World!", "dir1/lorem.txt": "Lorem ipsum, dolor sit amet."}
+	testutil.CreateZipArchive(testHelper.GetTestPath("members.zip"), members)
+	testutil.CreateZipArchive(testHelper.GetTestPath("corrupt.zip"), members)
+	testutil.CorruptFile(testHelper.GetTestPath("corrupt.zip"), "Hello World!", "Hello World?")
+	testHelper.CreateTestDirectory("Wow!")
+	testHelper.CreateTestFileWithContent("Wow!/hello.txt", "Hello World!")
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprint(testutil.CreateSparseFingerprint("Wow!/hello.txt", "1c291ca3", "crc32"))
+	memoryDatabase.AddFingerprint(testutil.CreateSparseFingerprint("members.zip!/hello.txt", "1c291ca3", "crc32"))
+	memoryDatabase.AddFingerprint(testutil.CreateSparseFingerprint("members.zip!/dir1/lorem.txt", "1d291cf2", "crc32"))
+	memoryDatabase.AddFingerprint(testutil.CreateSparseFingerprint("members.zip!/gone.txt", "a1b2c3d4", "crc32"))
+	memoryDatabase.AddFingerprint(testutil.CreateSparseFingerprint("corrupt.zip!/hello.txt", "1c291ca3", "crc32"))
+	verifier := NewVerifier(memoryDatabase, testHelper.GetTestRootDirectory())
+
+	// Act.
+	verifier.Verify(false, common.NewFingerprintFilter(""))
+
+	// Assert.
+	if !testHelper.HasStringItems(
+		verifier.Report.CorruptFiles, "members.zip!/dir1/lorem.txt", "corrupt.zip!/hello.txt") {
+		t.Error("Damaged archive members should be marked as corrupt.")
+	}
+	if !testHelper.HasStringItems(verifier.Report.MissingFiles, "members.zip!/gone.txt") ||
+		verifier.Report.MissingFiles.Len() != 1 {
+		t.Error("File should be marked as missing: \"members.zip!/gone.txt\".")
+	}
+	if verifier.Report.CountAll != 5 || verifier.Report.CorruptFiles.Len() != 2 {
+		t.Errorf("Wrong number of files: %d.", verifier.Report.CountAll)
+	}
+}
+
+func testVerifierVerifyCorruptRanges(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestFileWithContent("image.bin", "0123456789abcdefghij")
+	hasher := common.NewHasher("sha1")
+	hasher.SetBlockSize(4)
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprint(hasher.CalculateFingerprint(testHelper.GetTestRootDirectory(), "", "image.bin"))
+	testHelper.CreateTestFileWithContent("image.bin", "0123456X89abcdefgh")
+	verifier := NewVerifier(memoryDatabase, testHelper.GetTestRootDirectory())
+
+	// Act.
+	verifier.Verify(false, common.NewFingerprintFilter("image"))
+
+	// Assert.
+	if !testHelper.HasStringItems(verifier.Report.CorruptFiles, "image.bin") {
+		t.Fatal("File should be marked as corrupt: \"image.bin\".")
+	}
+	ranges := report.FormatByteRanges(verifier.Report.CorruptRanges["image.bin"])
+	if ranges != "4-7, 16-19" {
+		t.Errorf("Wrong corrupt ranges: %s.", ranges)
+	}
+}
+
+func testVerifierVerifyPathMatcher(t *testing.T) {
+
+	// Arrange.
+	testHelper.CreateTestDirectory("Cafe\u0301")
+	testHelper.CreateTestFileWithContent("Cafe\u0301/Menu.TXT", "Hello World!")
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprint(testutil.CreateSparseFingerprint("caf\u00e9/menu.txt", "1c291ca3", "crc32"))
+	testPath := testHelper.GetTestRootDirectory()
+	exactVerifier := NewVerifier(memoryDatabase, testPath)
+	verifier := NewVerifier(memoryDatabase, testPath)
+	verifier.SetPathMatcher(util.NewPathMatcher(true, true))
+	fpFilter := common.NewFingerprintFilter("")
+	fpFilter.SetPathMatcher(util.NewPathMatcher(true, true))
+
+	// Act.
+	exactVerifier.Verify(false, common.NewFingerprintFilter(""))
+	verifier.Verify(false, fpFilter)
+
+	// Assert.
+	if exactVerifier.Report.MissingFiles.Len() != 1 {
+		t.Error("The file should be missing without matching: \"caf\u00e9/menu.txt\".")
+	}
+	if verifier.Report.MissingFiles.Len() != 0 || verifier.Report.CorruptFiles.Len() != 0 {
+		t.Error("The file should be found by a matching name: \"caf\u00e9/menu.txt\".")
+	}
+}
+
+func testVerifierVerifyMetadataDrift(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Permission bits are not supported on Windows.")
+	}
+
+	// Arrange.
+	testHelper.CreateTestFileWithContent("private.txt", "Hello World!")
+	os.Chmod(testHelper.GetTestPath("private.txt"), 0600)
+	fingerprint := testutil.CreateSparseFingerprint("private.txt", "1c291ca3", "crc32")
+	fingerprint.Mode = "0644"
+	memoryDatabase := dal.NewMemoryDatabase()
+	memoryDatabase.AddFingerprint(fingerprint)
+	memoryDatabase.AddFingerprint(testutil.CreateSparseFingerprint("test.txt", "1c291ca3", "crc32"))
+	verifier := NewVerifier(memoryDatabase, testHelper.GetTestRootDirectory())
+
+	// Act.
+	verifier.Verify(false, common.NewFingerprintFilter(""))
+
+	// Assert.
+	if !testHelper.HasStringItems(verifier.Report.DriftedFiles, "private.txt") || verifier.Report.DriftedFiles.Len() != 1 {
+		t.Error("Only the file with changed permissions should be reported: \"private.txt\".")
+	}
+	if verifier.Report.CorruptFiles.Len() != 0 {
+		t.Error("Changed permissions should not be reported as corrupt content.")
+	}
+}
+
+func testVerifierVerifyChecksumXattrs(t *testing.T) {
+
+	testHelper.CreateTestDirectory("xattrs")
+	testHelper.CreateTestFileWithContent("xattrs/tagged.txt", "Hello World!")
+	if err := util.SetXattr(testHelper.GetTestPath("xattrs/tagged.txt"), "user.test", []byte("1")); err != nil {
+		t.Skipf("Extended attributes are not supported: %s.", err)
+	}
+
+	// Arrange.
+	testPath := testHelper.GetTestDirectory("xattrs")
+	memoryDatabase := dal.NewMemoryDatabase()
+	calculator := NewCalculator(memoryDatabase, testPath, "crc32", testPath)
+	calculator.SetCaptureAttributes(true)
+	calculator.Calculate(false)
+	xattrDatabase := dal.NewXattrDatabase(testPath, testPath)
+	xattrDatabase.AddFingerprints(memoryDatabase.GetFingerprints())
+	xattrDatabase.SaveFingerprints()
+	verifier := NewVerifier(memoryDatabase, testPath)
+
+	// Act.
+	verifier.Verify(false, common.NewFingerprintFilter(""))
+
+	// Assert.
+	if verifier.Report.DriftedFiles.Len() != 0 || verifier.Report.CountAll != 1 {
+		t.Error("Checksums stored in the extended attributes should not be reported as drift.")
+	}
+}
+
 func tearDownVerifierTests() {
 
 	testHelper.CleanUp()
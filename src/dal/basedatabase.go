@@ -0,0 +1,160 @@
+package dal
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/ed25519"
+	"fmr/util"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// baseDatabase Holds what the file databases have in common: the entries in memory, the keys, the name pairs and the
+// saving of the output file. The formats embed it and only read and render their files.
+type baseDatabase struct {
+	fpInputPath        string
+	fpOutputPath       string
+	namePairOutputPath string
+	fingerprints       *list.List
+	namePairs          *list.List
+	signingKey         ed25519.PrivateKey
+	verificationKey    ed25519.PublicKey
+	backupTaken        bool
+}
+
+func newBaseDatabase(fpInputPath string, fpOutputPath string, namePairOutputPath string) baseDatabase {
+
+	return baseDatabase{fpInputPath, fpOutputPath, namePairOutputPath, list.New(), list.New(), nil, nil, false}
+}
+
+// SetSigningKey Sets the key used to create a detached signature ("<output>.sig") whenever fingerprints are saved. The
+// signature covers the file as stored, compressed or not.
+func (db *baseDatabase) SetSigningKey(key ed25519.PrivateKey) {
+
+	db.signingKey = key
+}
+
+// SetVerificationKey Sets the key used to check the detached signature of the input before anything is loaded from
+// it. Loading fails if the signature is missing or invalid.
+func (db *baseDatabase) SetVerificationKey(key ed25519.PublicKey) {
+
+	db.verificationKey = key
+}
+
+// AddFingerprint Adds a fingerprint to the database.
+func (db *baseDatabase) AddFingerprint(fingerprint *Fingerprint) {
+
+	if fingerprint != nil {
+		db.fingerprints.PushFront(fingerprint)
+	}
+}
+
+// AddFingerprints Adds a list of fingerprints to the database.
+func (db *baseDatabase) AddFingerprints(fingerprints *list.List) {
+
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*Fingerprint)
+		db.AddFingerprint(fingerprint)
+	}
+}
+
+// AddNamePair Adds a name pair to the database.
+func (db *baseDatabase) AddNamePair(namePair *NamePair) {
+
+	if namePair != nil {
+		db.namePairs.PushFront(namePair)
+	}
+}
+
+// Clear Removes all entries from the database.
+func (db *baseDatabase) Clear() {
+
+	db.fingerprints.Init()
+	db.namePairs.Init()
+}
+
+// GetFingerprints Returns stored fingerprints.
+func (db *baseDatabase) GetFingerprints() *list.List {
+
+	return db.fingerprints
+}
+
+// GetNamePairs Returns stored name pairs.
+func (db *baseDatabase) GetNamePairs() *list.List {
+
+	return db.namePairs
+}
+
+// SaveNamePairs Saves name pairs to a text file. The file is replaced atomically.
+func (db *baseDatabase) SaveNamePairs() {
+
+	outputFile, err := util.CreateAtomicFile(db.namePairOutputPath, false)
+	util.CheckErr(err, fmt.Sprintf("Cannot write name pairs to %s.", db.namePairOutputPath))
+	defer outputFile.Abort()
+
+	for element := db.namePairs.Front(); element != nil; element = element.Next() {
+		namePair := element.Value.(*NamePair)
+		writeNamePair(namePair, outputFile.File)
+	}
+
+	err = outputFile.Commit()
+	util.CheckErr(err, fmt.Sprintf("Cannot write name pairs to %s.", db.namePairOutputPath))
+}
+
+// openInput Opens the input file. If a verification key is set, the file is read at once and its signature checked
+// before anything is returned.
+func (db *baseDatabase) openInput() io.ReadCloser {
+
+	if db.verificationKey != nil {
+		content := readFileContent(db.fpInputPath)
+		err := VerifySignature(db.fpInputPath, content, db.verificationKey)
+		util.CheckErrDontPanic(err, fmt.Sprintf("Signature verification failed for %s: %s.", db.fpInputPath, err))
+		return ioutil.NopCloser(bytes.NewReader(content))
+	}
+
+	file, err := os.Open(db.fpInputPath)
+	util.CheckErr(err, fmt.Sprintf("Cannot read file %s.", db.fpInputPath))
+
+	return file
+}
+
+// saveOutput Replaces the output file atomically with what the given function writes, signed if a signing key is set.
+// The version found by the first save (e.g. before the checkpoints of a run) is kept with a ".bak" extension.
+func (db *baseDatabase) saveOutput(write func(destination io.Writer) error) {
+
+	file, err := util.CreateAtomicFile(db.fpOutputPath, !db.backupTaken)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot write file %s.", db.fpOutputPath))
+	defer file.Abort()
+
+	var destination io.Writer = file
+	content := new(bytes.Buffer)
+	if db.signingKey != nil {
+		destination = io.MultiWriter(file, content)
+	}
+
+	err = write(destination)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot write file %s: %s.", db.fpOutputPath, err))
+	err = updateSignature(db.fpOutputPath, content.Bytes(), db.signingKey, !db.backupTaken)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot write signature for %s: %s.", db.fpOutputPath, err))
+	err = file.Commit()
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot write file %s.", db.fpOutputPath))
+	db.backupTaken = true
+}
+
+func readFileContent(filename string) []byte {
+
+	content, err := ioutil.ReadFile(filename)
+	util.CheckErr(err, fmt.Sprintf("Cannot read file %s.", filename))
+
+	return content
+}
+
+func writeNamePair(namePair *NamePair, outputFile *os.File) {
+
+	outputFile.WriteString(namePair.NewName + "\r\n")
+	outputFile.WriteString("    " + namePair.OldName + "\r\n")
+	outputFile.WriteString("    \r\n")
+	outputFile.WriteString("    \r\n")
+}
@@ -0,0 +1,148 @@
+package dal
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/ed25519"
+	"encoding/csv"
+	"encoding/hex"
+	"fmr/util"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// BlockFileSuffix The suffix of the file storing the block hashes of a database ("<database>.blocks").
+const BlockFileSuffix = ".blocks"
+
+// BlockFileMarker The first field of the record identifying a block file.
+const BlockFileMarker = "#fmr-blocks"
+
+// BlockFileVersion The version of the block file format.
+const BlockFileVersion = 1
+
+// SaveBlockFile Saves the block hashes of the given fingerprints: one record per fingerprint, holding the filename, the
+// algorithm and the hashes of the blocks in order. The file is replaced atomically and signed like the database if a
+// key is given. If no fingerprint has block hashes, an existing block file is removed instead.
+func SaveBlockFile(path string, fingerprints *list.List, key ed25519.PrivateKey) error {
+
+	records := [][]string{{BlockFileMarker, strconv.Itoa(BlockFileVersion)}}
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*Fingerprint)
+		if len(fingerprint.Blocks) > 0 {
+			records = append(records, createBlockRecord(fingerprint))
+		}
+	}
+
+	if len(records) == 1 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return updateSignature(path, nil, nil, false)
+	}
+
+	content := new(bytes.Buffer)
+	if err := writeCsv(records, content); err != nil {
+		return err
+	}
+
+	file, err := util.CreateAtomicFile(path, false)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	if _, err = file.Write(content.Bytes()); err != nil {
+		return err
+	}
+	if err = updateSignature(path, content.Bytes(), key, false); err != nil {
+		return err
+	}
+
+	return file.Commit()
+}
+
+// LoadBlockFile Sets the block hashes of the given fingerprints from the block file, matching them by filename and
+// algorithm. If a key is given, the signature of the block file is checked first. A missing block file is not an
+// error: the fingerprints are left without block hashes.
+func LoadBlockFile(path string, fingerprints *list.List, key ed25519.PublicKey) error {
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if key != nil {
+		if err := VerifySignature(path, content, key); err != nil {
+			return err
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+
+	marker, err := reader.Read()
+	if err != nil || len(marker) != 2 || marker[0] != BlockFileMarker || marker[1] != strconv.Itoa(BlockFileVersion) {
+		return fmt.Errorf("unsupported block file")
+	}
+
+	blocksByKey := make(map[string][][]byte)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if len(record) < 2 {
+			return fmt.Errorf("invalid record: %v", record)
+		}
+		blocks, err := parseBlockHashes(record[2:])
+		if err != nil {
+			return err
+		}
+		blocksByKey[getBlockKey(record[0], record[1])] = blocks
+	}
+
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*Fingerprint)
+		if fingerprint.BlockSize > 0 {
+			fingerprint.Blocks = blocksByKey[getBlockKey(fingerprint.Filename, fingerprint.Algorithm)]
+		}
+	}
+
+	return nil
+}
+
+func createBlockRecord(fingerprint *Fingerprint) []string {
+
+	record := make([]string, 0, len(fingerprint.Blocks)+2)
+	record = append(record, fingerprint.Filename, fingerprint.Algorithm)
+	for _, block := range fingerprint.Blocks {
+		record = append(record, hex.EncodeToString(block))
+	}
+
+	return record
+}
+
+func parseBlockHashes(fields []string) ([][]byte, error) {
+
+	blocks := make([][]byte, 0, len(fields))
+	for _, field := range fields {
+		block, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block hash: %s", field)
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+func getBlockKey(filename string, algorithm string) string {
+
+	return algorithm + ":" + filename
+}
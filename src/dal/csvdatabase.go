@@ -1,198 +1,184 @@
 package dal
 
 import (
-	"bytes"
 	"container/list"
 	"encoding/csv"
-	"encoding/hex"
 	"fmr/util"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
+	"strconv"
 )
 
 // CsvDatabase Logic for calculating checksums.
 type CsvDatabase struct {
-	fpInputPath        string
-	fpOutputPath       string
-	namePairOutputPath string
-	fingerprints       *list.List
-	namePairs          *list.List
+	baseDatabase
+	metadataColumns []string
 }
 
 // NewCsvDatabase Instantiates a new CsvDatabase object.
 func NewCsvDatabase(fpInputPath string, fpOutputPath string, namePairOutputPath string) *CsvDatabase {
 
-	return &CsvDatabase{fpInputPath, fpOutputPath, namePairOutputPath, list.New(), list.New()}
+	return &CsvDatabase{newBaseDatabase(fpInputPath, fpOutputPath, namePairOutputPath), make([]string, 0)}
 }
 
-// AddFingerprint Adds a fingerprint to the database.
-func (db *CsvDatabase) AddFingerprint(fingerprint *Fingerprint) {
+// SetMetadataColumns Declares custom metadata columns. They are written even if no fingerprint has a value for them.
+// Metadata columns found in the input are preserved without declaring them.
+func (db *CsvDatabase) SetMetadataColumns(columns []string) {
 
-	if fingerprint != nil {
-		db.fingerprints.PushFront(fingerprint)
-	}
+	db.metadataColumns = mergeColumns(db.metadataColumns, columns)
 }
 
-// AddFingerprints Adds a list of fingerprints to the database.
-func (db *CsvDatabase) AddFingerprints(fingerprints *list.List) {
-
-	for element := fingerprints.Front(); element != nil; element = element.Next() {
-		fingerprint := element.Value.(*Fingerprint)
-		db.AddFingerprint(fingerprint)
-	}
-}
+// LoadFingerprints Loads fingerprints from the given CSV file. The block hashes are loaded from the block file next to
+// it, if there is one.
+func (db *CsvDatabase) LoadFingerprints() {
 
-// AddNamePair Adds a name pair to the database.
-func (db *CsvDatabase) AddNamePair(namePair *NamePair) {
+	hasBlocks := false
+	db.readFingerprints(func(fingerprint *Fingerprint) {
+		db.fingerprints.PushFront(fingerprint)
+		hasBlocks = hasBlocks || fingerprint.BlockSize > 0
+	})
 
-	if namePair != nil {
-		db.namePairs.PushFront(namePair)
+	if hasBlocks {
+		err := LoadBlockFile(db.fpInputPath+BlockFileSuffix, db.fingerprints, db.verificationKey)
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot read the block hashes of %s: %s.", db.fpInputPath, err))
 	}
 }
 
-// Clear Removes all entries from the database.
-func (db *CsvDatabase) Clear() {
+// LoadNamesFromFingeprints Loads the filenames and forwards it to the given StringWriter.
+func (db *CsvDatabase) LoadNamesFromFingeprints(writer util.StringWriter) {
 
-	db.fingerprints.Init()
-	db.namePairs.Init()
+	db.readFingerprints(func(fingerprint *Fingerprint) {
+		writer.Write(fingerprint.Filename)
+	})
 }
 
-// GetFingerprints Returns stored fingerprints.
-func (db *CsvDatabase) GetFingerprints() *list.List {
-
-	return db.fingerprints
-}
+// SaveFingerprints Saves fingerprints to the output CSV file. The file is replaced atomically, the version found by the
+// first save (e.g. before the checkpoints of a run) is kept with a ".bak" extension.
+func (db *CsvDatabase) SaveFingerprints() {
 
-// GetNamePairs Returns stored name pairs.
-func (db *CsvDatabase) GetNamePairs() *list.List {
+	db.saveOutput(db.RenderFingerprints)
 
-	return db.namePairs
+	err := SaveBlockFile(db.fpOutputPath+BlockFileSuffix, db.fingerprints, db.signingKey)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot write the block hashes of %s: %s.", db.fpOutputPath, err))
 }
 
-// LoadFingerprints Loads fingerprints from the given CSV file.
-func (db *CsvDatabase) LoadFingerprints() {
-
-	content := readFileContent(db.fpInputPath)
-	reader := csv.NewReader(bytes.NewReader(content))
+// RenderFingerprints Writes the content of the output CSV file to the given destination.
+func (db *CsvDatabase) RenderFingerprints(destination io.Writer) error {
 
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		util.CheckErrDontPanic(err, "")
-		db.addFingerprint(record)
-	}
+	return WriteFingerprints(db.fingerprints, db.metadataColumns, destination)
 }
 
-// LoadNamesFromFingeprints Loads the filenames and forwards it to the given StringWriter.
-func (db *CsvDatabase) LoadNamesFromFingeprints(writer util.StringWriter) {
+// readFingerprints Parses the input and passes each fingerprint to the given function. Versioned files start with a
+// record holding the schema version followed by the header row; files without them are read with the legacy,
+// positional layout.
+func (db *CsvDatabase) readFingerprints(handle func(fingerprint *Fingerprint)) {
 
-	content := readFileContent(db.fpInputPath)
-	reader := csv.NewReader(bytes.NewReader(content))
+	input := db.openInput()
+	defer input.Close()
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
 
-	for {
+	var schema *csvSchema
+	for recordNumber := 1; ; recordNumber++ {
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
-		util.CheckErrDontPanic(err, "")
-		writer.Write(record[0])
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot parse %s: %s.", db.fpInputPath, err))
+
+		if schema == nil {
+			schema = db.readSchema(reader, record)
+			if !schema.legacy {
+				// The header row has been read as well.
+				recordNumber++
+				continue
+			}
+		}
+
+		fingerprint, err := schema.createFingerprint(record)
+		util.CheckErrDontPanic(err, fmt.Sprintf("Invalid record %d in %s: %s.", recordNumber, db.fpInputPath, err))
+		handle(fingerprint)
 	}
 }
 
-// SaveFingerprints Saves fingerprints to the output CSV file.
-func (db *CsvDatabase) SaveFingerprints() {
-
-	records := db.createCsvRecords()
+func (db *CsvDatabase) readSchema(reader *csv.Reader, firstRecord []string) *csvSchema {
 
-	file, err := os.Create(db.fpOutputPath)
-	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot write file %s.", db.fpOutputPath))
-	defer file.Close()
+	if firstRecord[0] != CsvSchemaMarker {
+		return newLegacySchema()
+	}
 
-	writeCsv(records, file)
-}
+	err := parseCsvSchemaVersion(firstRecord)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot read %s: %s.", db.fpInputPath, err))
 
-// SaveNamePairs Saves name pairs to a text file.
-func (db *CsvDatabase) SaveNamePairs() {
+	header, err := reader.Read()
+	if err == io.EOF {
+		err = fmt.Errorf("missing header")
+	}
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot read %s: %s.", db.fpInputPath, err))
 
-	outputFile, err := os.OpenFile(db.namePairOutputPath, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0660)
-	util.CheckErr(err, fmt.Sprintf("Cannot write name pairs to %s.", db.namePairOutputPath))
-	defer outputFile.Close()
+	schema, err := parseCsvSchema(header)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot read %s: %s.", db.fpInputPath, err))
+	db.metadataColumns = mergeColumns(schema.getMetadataColumns(), db.metadataColumns)
 
-	for element := db.namePairs.Front(); element != nil; element = element.Next() {
-		namePair := element.Value.(*NamePair)
-		writeNamePair(namePair, outputFile)
-	}
+	return schema
 }
 
-func (db *CsvDatabase) addFingerprint(record []string) {
+// WriteFingerprints Writes the given fingerprints in the format of CsvDatabase: the schema version, the header and the
+// records. The given metadata columns are written even if no fingerprint has a value for them.
+func WriteFingerprints(fingerprints *list.List, metadataColumns []string, destination io.Writer) error {
 
-	checksumBytes, err := hex.DecodeString(record[1])
-	util.CheckErrDontPanic(err, "")
+	schema := createSchema(fingerprints, metadataColumns)
 
-	fingerprint := createFingerprint(record, checksumBytes)
-	db.fingerprints.PushFront(fingerprint)
+	return writeCsv(createCsvRecords(schema, fingerprints), destination)
 }
 
-func (db *CsvDatabase) createCsvRecords() [][]string {
-
-	records := make([][]string, db.fingerprints.Len())
-	index := 0
+// createSchema Creates the schema used for saving: the standard columns, the block columns if any fingerprint has block
+// hashes, the link columns if any fingerprint is a symbolic link, the attribute columns if any fingerprint has its
+// attributes captured, followed by the given metadata columns and the metadata keys of the fingerprints.
+func createSchema(fingerprints *list.List, metadataColumns []string) *csvSchema {
 
-	for element := db.fingerprints.Front(); element != nil; element = element.Next() {
+	fingerprintSlice := make([]*Fingerprint, 0, fingerprints.Len())
+	blocks, links, attributes := false, false, false
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
 		fingerprint := element.Value.(*Fingerprint)
-		records[index] = createCsvRecord(fingerprint)
-		index++
+		fingerprintSlice = append(fingerprintSlice, fingerprint)
+		blocks = blocks || fingerprint.BlockSize > 0
+		links = links || fingerprint.LinkTarget != ""
+		attributes = attributes || fingerprint.Mode != ""
 	}
 
-	return records
-}
-
-func readFileContent(filename string) []byte {
-
-	content, err := ioutil.ReadFile(filename)
-	util.CheckErr(err, fmt.Sprintf("Cannot read file %s.", filename))
+	optionalColumns := make([]string, 0)
+	if blocks {
+		optionalColumns = append(optionalColumns, blockColumns...)
+	}
+	if links {
+		optionalColumns = append(optionalColumns, linkColumns...)
+	}
+	if attributes {
+		optionalColumns = append(optionalColumns, attributeColumns...)
+	}
 
-	return content
+	return newCsvSchema(mergeMetadataColumns(metadataColumns, fingerprintSlice), optionalColumns)
 }
 
-func createFingerprint(record []string, checksumBytes []byte) *Fingerprint {
-
-	fingerprint := new(Fingerprint)
-	fingerprint.Filename = record[0]
-	fingerprint.Checksum = checksumBytes
-	fingerprint.Algorithm = record[2]
-	fingerprint.CreatedAt = record[3]
-	fingerprint.Creator = record[4]
-	fingerprint.Note = record[5]
+func createCsvRecords(schema *csvSchema, fingerprints *list.List) [][]string {
 
-	return fingerprint
-}
+	records := make([][]string, 0, fingerprints.Len()+2)
+	records = append(records, []string{CsvSchemaMarker, strconv.Itoa(CsvSchemaVersion)})
+	records = append(records, schema.columns)
 
-func createCsvRecord(fingerprint *Fingerprint) []string {
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*Fingerprint)
+		records = append(records, schema.createRecord(fingerprint))
+	}
 
-	return []string{
-		fingerprint.Filename, hex.EncodeToString(fingerprint.Checksum), fingerprint.Algorithm,
-		fingerprint.CreatedAt, fingerprint.Creator, fingerprint.Note}
+	return records
 }
 
-func writeCsv(records [][]string, destination io.Writer) {
+func writeCsv(records [][]string, destination io.Writer) error {
 
 	writer := csv.NewWriter(destination)
 
 	// Calls Flush internally.
-	writer.WriteAll(records)
-
-	util.CheckErrDontPanic(writer.Error(), "Error writing CSV.")
-}
-
-func writeNamePair(namePair *NamePair, outputFile *os.File) {
-
-	outputFile.WriteString(namePair.NewName + "\r\n")
-	outputFile.WriteString("    " + namePair.OldName + "\r\n")
-	outputFile.WriteString("    \r\n")
-	outputFile.WriteString("    \r\n")
+	return writer.WriteAll(records)
 }
@@ -1,6 +1,9 @@
 package dal
 
 import (
+	"fmr/util"
+	"io/ioutil"
+	"strings"
 	"testing"
 )
 
@@ -14,6 +17,11 @@ func TestCsvDatabase(t *testing.T) {
 	t.Run("CsvDatabase_Clear", testCsvDatabaseClear)
 	t.Run("CsvDatabase_LoadNamesFromFingerprints", testCsvDatabaseLoadNamesFromFingerprints)
 	t.Run("CsvDatabase_SaveAndLoadFingerprints", testCsvDatabaseSaveAndLoadFingerprints)
+	t.Run("CsvDatabase_SaveFingerprints_Backup", testCsvDatabaseSaveFingerprintsBackup)
+	t.Run("CsvDatabase_SaveFingerprints_Header", testCsvDatabaseSaveFingerprintsHeader)
+	t.Run("CsvDatabase_LoadFingerprints_Legacy", testCsvDatabaseLoadFingerprintsLegacy)
+	t.Run("CsvDatabase_Metadata_Preserved", testCsvDatabaseMetadataPreserved)
+	t.Run("CsvDatabase_Blocks", testCsvDatabaseBlocks)
 
 	tearDownCsvDatabaseTests()
 }
@@ -76,7 +84,7 @@ func testCsvDatabaseLoadNamesFromFingerprints(t *testing.T) {
 func testCsvDatabaseSaveAndLoadFingerprints(t *testing.T) {
 
 	checksum := []byte{12, 23, 34, 45}
-	fingerprint := &Fingerprint{"simple.txt", checksum, "sha1", "", "", ""}
+	fingerprint := &Fingerprint{Filename: "simple.txt", Checksum: checksum, Algorithm: "sha1"}
 	csvDatabase := NewCsvDatabase(
 		testHelper.GetTestPath("fingerprints.csv"),
 		testHelper.GetTestPath("fingerprints.csv"),
@@ -90,3 +98,113 @@ func testCsvDatabaseSaveAndLoadFingerprints(t *testing.T) {
 
 	assertStoredFingerprintIsValid(t, actualFingerprints)
 }
+
+func testCsvDatabaseSaveFingerprintsBackup(t *testing.T) {
+
+	checksum := []byte{12, 23, 34, 45}
+	fingerprint := &Fingerprint{Filename: "simple.txt", Checksum: checksum, Algorithm: "sha1"}
+	outputPath := testHelper.GetTestPath("backup.csv")
+	previousRun := NewCsvDatabase("", outputPath, "")
+	previousRun.AddFingerprint(fingerprint)
+	previousRun.SaveFingerprints()
+	csvDatabase := NewCsvDatabase(outputPath+util.BackupExtension, outputPath, "")
+
+	csvDatabase.AddFingerprint(&Fingerprint{Filename: "other.txt", Checksum: checksum, Algorithm: "sha1"})
+	csvDatabase.SaveFingerprints()
+	csvDatabase.AddFingerprint(&Fingerprint{Filename: "third.txt", Checksum: checksum, Algorithm: "sha1"})
+	csvDatabase.SaveFingerprints()
+	csvDatabase.Clear()
+	csvDatabase.LoadFingerprints()
+	actualFingerprints := csvDatabase.GetFingerprints()
+
+	assertStoredFingerprintIsValid(t, actualFingerprints)
+}
+
+func testCsvDatabaseSaveFingerprintsHeader(t *testing.T) {
+
+	path := testHelper.GetTestPath("header.csv")
+	csvDatabase := NewCsvDatabase(path, path, "")
+	csvDatabase.SetMetadataColumns([]string{"project"})
+
+	csvDatabase.AddFingerprint(&Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}})
+	csvDatabase.SaveFingerprints()
+
+	content, _ := ioutil.ReadFile(path)
+	lines := strings.Split(string(content), "\n")
+	if lines[0] != "#fmr-csv,2" {
+		t.Errorf("Wrong version record: %s.", lines[0])
+	}
+	if lines[1] != "filename,checksum,algorithm,created_at,creator,note,size,modified_at,tags,project" {
+		t.Errorf("Wrong header: %s.", lines[1])
+	}
+}
+
+func testCsvDatabaseLoadFingerprintsLegacy(t *testing.T) {
+
+	path := testHelper.GetTestPath("legacy.csv")
+	ioutil.WriteFile(path, []byte("simple.txt,0c17222d,sha1,,,\n"), 0644)
+	csvDatabase := NewCsvDatabase(path, path, "")
+
+	csvDatabase.LoadFingerprints()
+	actualFingerprints := csvDatabase.GetFingerprints()
+
+	assertStoredFingerprintIsValid(t, actualFingerprints)
+}
+
+func testCsvDatabaseMetadataPreserved(t *testing.T) {
+
+	path := testHelper.GetTestPath("metadata.csv")
+	ioutil.WriteFile(path, []byte(
+		"#fmr-csv,2\n"+
+			"filename,checksum,algorithm,retention,owner\n"+
+			"simple.txt,0c17222d,sha1,10y,\n"), 0644)
+	csvDatabase := NewCsvDatabase(path, path, "")
+
+	csvDatabase.LoadFingerprints()
+	csvDatabase.SaveFingerprints()
+	csvDatabase.Clear()
+	csvDatabase.LoadFingerprints()
+	actualFingerprints := csvDatabase.GetFingerprints()
+
+	assertStoredFingerprintIsValid(t, actualFingerprints)
+	fingerprint := actualFingerprints.Front().Value.(*Fingerprint)
+	if fingerprint.Metadata["retention"] != "10y" {
+		t.Errorf("Unknown column is not preserved: %v.", fingerprint.Metadata)
+	}
+	content, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(content), ",retention,owner\n") {
+		t.Errorf("Empty metadata column is not preserved: %s.", content)
+	}
+}
+
+func testCsvDatabaseBlocks(t *testing.T) {
+
+	path := testHelper.GetTestPath("blocks.csv")
+	csvDatabase := NewCsvDatabase(path, path, "")
+	csvDatabase.AddFingerprint(&Fingerprint{
+		Filename: "disk.img", Checksum: []byte{1, 2}, Algorithm: "crc32",
+		BlockSize: 4096, BlockRoot: []byte{3, 4}, Blocks: [][]byte{{5, 6}, {7, 8}}})
+	csvDatabase.AddFingerprint(&Fingerprint{Filename: "small.txt", Checksum: []byte{9}, Algorithm: "crc32"})
+
+	csvDatabase.SaveFingerprints()
+	csvDatabase.Clear()
+	csvDatabase.LoadFingerprints()
+
+	for element := csvDatabase.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*Fingerprint)
+		if fingerprint.Filename == "disk.img" &&
+			(fingerprint.BlockSize != 4096 || len(fingerprint.BlockRoot) != 2 || len(fingerprint.Blocks) != 2) {
+			t.Errorf("Block hashes are not preserved: %v.", fingerprint)
+		}
+		if fingerprint.Filename == "small.txt" && (fingerprint.BlockSize != 0 || fingerprint.Blocks != nil) {
+			t.Errorf("Unexpected block hashes: %v.", fingerprint)
+		}
+	}
+
+	csvDatabase.Clear()
+	csvDatabase.AddFingerprint(&Fingerprint{Filename: "small.txt", Checksum: []byte{9}, Algorithm: "crc32"})
+	csvDatabase.SaveFingerprints()
+	if util.CheckIfFileExists(path + BlockFileSuffix) {
+		t.Error("The block file should be removed if there are no block hashes.")
+	}
+}
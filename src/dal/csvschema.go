@@ -0,0 +1,396 @@
+package dal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CsvSchemaVersion The version of the CSV format written by CsvDatabase.
+const CsvSchemaVersion = 2
+
+// CsvSchemaMarker The first field of the record identifying a versioned CSV file.
+const CsvSchemaMarker = "#fmr-csv"
+
+// Names of the columns mapped onto the fields of Fingerprint.
+const (
+	ColumnFilename   = "filename"
+	ColumnChecksum   = "checksum"
+	ColumnAlgorithm  = "algorithm"
+	ColumnCreatedAt  = "created_at"
+	ColumnCreator    = "creator"
+	ColumnNote       = "note"
+	ColumnSize       = "size"
+	ColumnModifiedAt = "modified_at"
+	ColumnTags       = "tags"
+	ColumnBlockSize  = "block_size"
+	ColumnBlockRoot  = "block_root"
+	ColumnLinkTarget = "link_target"
+	ColumnMode       = "mode"
+	ColumnUID        = "uid"
+	ColumnGID        = "gid"
+	ColumnXattrs     = "xattrs"
+)
+
+// TagSeparator Separates the tags stored in a single field.
+const TagSeparator = ";"
+
+// standardColumns Lists the columns mapped onto the fields of Fingerprint in the order they are written.
+var standardColumns = []string{
+	ColumnFilename, ColumnChecksum, ColumnAlgorithm, ColumnCreatedAt,
+	ColumnCreator, ColumnNote, ColumnSize, ColumnModifiedAt, ColumnTags}
+
+// blockColumns Lists the columns describing block-level fingerprints. They are only written if at least one fingerprint
+// has block hashes.
+var blockColumns = []string{ColumnBlockSize, ColumnBlockRoot}
+
+// linkColumns Lists the columns describing symbolic links. They are only written if at least one fingerprint is a link.
+var linkColumns = []string{ColumnLinkTarget}
+
+// attributeColumns Lists the columns describing permissions, ownership and extended attributes. They are only written
+// if at least one fingerprint has its attributes captured.
+var attributeColumns = []string{ColumnMode, ColumnUID, ColumnGID, ColumnXattrs}
+
+// XattrSeparator Separates the name=value pairs of the extended attributes stored in a single field; the values are
+// hexadecimal, a separator or a percent sign in a name is percent-encoded.
+const XattrSeparator = ";"
+
+var xattrNameEscaper = strings.NewReplacer("%", "%25", XattrSeparator, "%3B")
+var xattrNameUnescaper = strings.NewReplacer("%25", "%", "%3B", XattrSeparator)
+
+// legacyColumns Lists the columns of the files written before the header row was introduced, in their order.
+var legacyColumns = []string{
+	ColumnFilename, ColumnChecksum, ColumnAlgorithm, ColumnCreatedAt,
+	ColumnCreator, ColumnNote, ColumnSize, ColumnModifiedAt}
+
+// csvSchema Describes the columns of a CSV file. Columns not mapped onto a field of Fingerprint are stored in its
+// Metadata.
+type csvSchema struct {
+	columns []string
+	legacy  bool
+}
+
+// IsStandardColumn Checks whether the given column is mapped onto a field of Fingerprint.
+func IsStandardColumn(name string) bool {
+
+	return containsColumn(standardColumns, name) || containsColumn(blockColumns, name) ||
+		containsColumn(linkColumns, name) || containsColumn(attributeColumns, name)
+}
+
+// newLegacySchema Creates the schema of the files written before the header row was introduced.
+func newLegacySchema() *csvSchema {
+
+	return &csvSchema{legacyColumns, true}
+}
+
+// newCsvSchema Creates a schema having the standard columns, the given optional columns (e.g. the block columns),
+// followed by the given metadata columns.
+func newCsvSchema(metadataColumns []string, optionalColumns []string) *csvSchema {
+
+	columns := make([]string, 0, len(standardColumns)+len(optionalColumns)+len(metadataColumns))
+	columns = append(columns, standardColumns...)
+	columns = append(columns, optionalColumns...)
+	columns = append(columns, metadataColumns...)
+
+	return &csvSchema{columns, false}
+}
+
+// parseCsvSchema Creates a schema from the given header row.
+func parseCsvSchema(header []string) (*csvSchema, error) {
+
+	seen := make(map[string]bool)
+	for _, column := range header {
+		if column == "" {
+			return nil, fmt.Errorf("empty column name in header")
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("duplicate column in header: %s", column)
+		}
+		seen[column] = true
+	}
+	if !seen[ColumnFilename] || !seen[ColumnChecksum] {
+		return nil, fmt.Errorf("the header must contain the %s and %s columns", ColumnFilename, ColumnChecksum)
+	}
+
+	return &csvSchema{header, false}, nil
+}
+
+// parseCsvSchemaVersion Parses the version record and checks whether the version is supported.
+func parseCsvSchemaVersion(record []string) error {
+
+	if len(record) < 2 {
+		return fmt.Errorf("missing schema version")
+	}
+
+	version, err := strconv.Atoi(record[1])
+	if err != nil {
+		return fmt.Errorf("invalid schema version: %s", record[1])
+	}
+	if version < 2 || version > CsvSchemaVersion {
+		return fmt.Errorf("unsupported schema version: %d", version)
+	}
+
+	return nil
+}
+
+// getMetadataColumns Returns the columns stored in the Metadata of fingerprints.
+func (schema *csvSchema) getMetadataColumns() []string {
+
+	metadataColumns := make([]string, 0)
+	for _, column := range schema.columns {
+		if !IsStandardColumn(column) {
+			metadataColumns = append(metadataColumns, column)
+		}
+	}
+
+	return metadataColumns
+}
+
+func (schema *csvSchema) createFingerprint(record []string) (*Fingerprint, error) {
+
+	// Files without header may have fewer columns: size and modification time were added later.
+	if (schema.legacy && (len(record) < 2 || len(record) > len(schema.columns))) ||
+		(!schema.legacy && len(record) != len(schema.columns)) {
+		return nil, fmt.Errorf("wrong number of fields: %d (expected: %d)", len(record), len(schema.columns))
+	}
+
+	fingerprint := new(Fingerprint)
+	for index, value := range record {
+		if err := setFingerprintField(fingerprint, schema.columns[index], value); err != nil {
+			return nil, err
+		}
+	}
+
+	return fingerprint, nil
+}
+
+func (schema *csvSchema) createRecord(fingerprint *Fingerprint) []string {
+
+	record := make([]string, len(schema.columns))
+	for index, column := range schema.columns {
+		record[index] = getFingerprintField(fingerprint, column)
+	}
+
+	return record
+}
+
+func setFingerprintField(fingerprint *Fingerprint, column string, value string) error {
+
+	switch column {
+	case ColumnFilename:
+		fingerprint.Filename = value
+	case ColumnChecksum:
+		checksum, err := hex.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("invalid checksum: %s", value)
+		}
+		fingerprint.Checksum = checksum
+	case ColumnAlgorithm:
+		fingerprint.Algorithm = value
+	case ColumnCreatedAt:
+		fingerprint.CreatedAt = value
+	case ColumnCreator:
+		fingerprint.Creator = value
+	case ColumnNote:
+		fingerprint.Note = value
+	case ColumnSize:
+		if value == "" {
+			return nil
+		}
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid file size: %s", value)
+		}
+		fingerprint.Size = size
+	case ColumnModifiedAt:
+		fingerprint.ModifiedAt = value
+	case ColumnTags:
+		fingerprint.Tags = ParseTags(value)
+	case ColumnBlockSize:
+		if value == "" {
+			return nil
+		}
+		blockSize, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || blockSize < 0 {
+			return fmt.Errorf("invalid block size: %s", value)
+		}
+		fingerprint.BlockSize = blockSize
+	case ColumnBlockRoot:
+		blockRoot, err := hex.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("invalid block root: %s", value)
+		}
+		if len(blockRoot) > 0 {
+			fingerprint.BlockRoot = blockRoot
+		}
+	case ColumnLinkTarget:
+		fingerprint.LinkTarget = value
+	case ColumnMode:
+		fingerprint.Mode = value
+	case ColumnUID:
+		fingerprint.UID = value
+	case ColumnGID:
+		fingerprint.GID = value
+	case ColumnXattrs:
+		xattrs, err := parseXattrs(value)
+		if err != nil {
+			return err
+		}
+		fingerprint.Xattrs = xattrs
+	default:
+		if value != "" {
+			if fingerprint.Metadata == nil {
+				fingerprint.Metadata = make(map[string]string)
+			}
+			fingerprint.Metadata[column] = value
+		}
+	}
+
+	return nil
+}
+
+func getFingerprintField(fingerprint *Fingerprint, column string) string {
+
+	switch column {
+	case ColumnFilename:
+		return fingerprint.Filename
+	case ColumnChecksum:
+		return hex.EncodeToString(fingerprint.Checksum)
+	case ColumnAlgorithm:
+		return fingerprint.Algorithm
+	case ColumnCreatedAt:
+		return fingerprint.CreatedAt
+	case ColumnCreator:
+		return fingerprint.Creator
+	case ColumnNote:
+		return fingerprint.Note
+	case ColumnSize:
+		return strconv.FormatInt(fingerprint.Size, 10)
+	case ColumnModifiedAt:
+		return fingerprint.ModifiedAt
+	case ColumnTags:
+		return strings.Join(fingerprint.Tags, TagSeparator)
+	case ColumnBlockSize:
+		if fingerprint.BlockSize == 0 {
+			return ""
+		}
+		return strconv.FormatInt(fingerprint.BlockSize, 10)
+	case ColumnBlockRoot:
+		return hex.EncodeToString(fingerprint.BlockRoot)
+	case ColumnLinkTarget:
+		return fingerprint.LinkTarget
+	case ColumnMode:
+		return fingerprint.Mode
+	case ColumnUID:
+		return fingerprint.UID
+	case ColumnGID:
+		return fingerprint.GID
+	case ColumnXattrs:
+		return formatXattrs(fingerprint.Xattrs)
+	}
+
+	return fingerprint.Metadata[column]
+}
+
+// ParseTags Splits the given text at TagSeparator. Surrounding whitespace and empty tags are dropped.
+func ParseTags(text string) []string {
+
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(text, TagSeparator) {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// formatXattrs Formats the extended attributes as name=value pairs sorted by name, the values in hexadecimal and the
+// names escaped (see XattrSeparator).
+func formatXattrs(xattrs map[string][]byte) string {
+
+	names := make([]string, 0, len(xattrs))
+	for name := range xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for index, name := range names {
+		pairs[index] = xattrNameEscaper.Replace(name) + "=" + hex.EncodeToString(xattrs[name])
+	}
+
+	return strings.Join(pairs, XattrSeparator)
+}
+
+func parseXattrs(text string) (map[string][]byte, error) {
+
+	xattrs := make(map[string][]byte)
+	if text == "" {
+		return xattrs, nil
+	}
+
+	for _, pair := range strings.Split(text, XattrSeparator) {
+		separatorIndex := strings.LastIndex(pair, "=")
+		if separatorIndex < 1 {
+			return nil, fmt.Errorf("invalid extended attribute: %s", pair)
+		}
+		value, err := hex.DecodeString(pair[separatorIndex+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid extended attribute: %s", pair)
+		}
+		xattrs[xattrNameUnescaper.Replace(pair[:separatorIndex])] = value
+	}
+
+	return xattrs, nil
+}
+
+// mergeColumns Appends the columns of the second list which are not in the first one.
+func mergeColumns(columns []string, otherColumns []string) []string {
+
+	merged := append(make([]string, 0, len(columns)+len(otherColumns)), columns...)
+	for _, column := range otherColumns {
+		if !containsColumn(merged, column) {
+			merged = append(merged, column)
+		}
+	}
+
+	return merged
+}
+
+func containsColumn(columns []string, name string) bool {
+
+	for _, column := range columns {
+		if column == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeMetadataColumns Appends the metadata keys of the given fingerprints that are not listed yet, in alphabetical
+// order.
+func mergeMetadataColumns(columns []string, fingerprints []*Fingerprint) []string {
+
+	known := make(map[string]bool)
+	for _, column := range columns {
+		known[column] = true
+	}
+
+	newColumns := make([]string, 0)
+	for _, fingerprint := range fingerprints {
+		for key := range fingerprint.Metadata {
+			if !known[key] {
+				known[key] = true
+				newColumns = append(newColumns, key)
+			}
+		}
+	}
+	sort.Strings(newColumns)
+
+	return mergeColumns(columns, newColumns)
+}
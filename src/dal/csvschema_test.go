@@ -0,0 +1,152 @@
+package dal
+
+import (
+	"testing"
+)
+
+func TestCsvSchema(t *testing.T) {
+
+	t.Run("CsvSchema_CreateFingerprint", testCsvSchemaCreateFingerprint)
+	t.Run("CsvSchema_CreateFingerprint_Legacy", testCsvSchemaCreateFingerprintLegacy)
+	t.Run("CsvSchema_CreateFingerprint_ShortRecord", testCsvSchemaCreateFingerprintShortRecord)
+	t.Run("CsvSchema_CreateRecord", testCsvSchemaCreateRecord)
+	t.Run("CsvSchema_FileAttributes", testCsvSchemaFileAttributes)
+	t.Run("CsvSchema_LinkTarget", testCsvSchemaLinkTarget)
+	t.Run("CsvSchema_ParseHeader_MissingColumn", testCsvSchemaParseHeaderMissingColumn)
+	t.Run("CsvSchema_ParseVersion", testCsvSchemaParseVersion)
+}
+
+func testCsvSchemaCreateFingerprint(t *testing.T) {
+
+	schema, _ := parseCsvSchema([]string{"checksum", "project", "filename", "size"})
+
+	fingerprint, err := schema.createFingerprint([]string{"0c17222d", "apollo", "simple.txt", "42"})
+
+	if err != nil {
+		t.Fatalf("Valid record is rejected: %s.", err)
+	}
+	if fingerprint.Filename != "simple.txt" || fingerprint.Size != 42 || fingerprint.Checksum[0] != 0x0c {
+		t.Error("The standard columns are not parsed correctly.")
+	}
+	if fingerprint.Metadata["project"] != "apollo" {
+		t.Errorf("Wrong metadata value: %s.", fingerprint.Metadata["project"])
+	}
+}
+
+func testCsvSchemaCreateFingerprintLegacy(t *testing.T) {
+
+	schema := newLegacySchema()
+
+	fingerprint, err := schema.createFingerprint([]string{"simple.txt", "0c17222d", "crc32", "", "", ""})
+
+	if err != nil {
+		t.Fatalf("Legacy record is rejected: %s.", err)
+	}
+	if fingerprint.Filename != "simple.txt" || fingerprint.Algorithm != "crc32" || fingerprint.Metadata != nil {
+		t.Error("The legacy record is not parsed correctly.")
+	}
+}
+
+func testCsvSchemaCreateFingerprintShortRecord(t *testing.T) {
+
+	legacySchema := newLegacySchema()
+	schema := newCsvSchema([]string{"project"}, nil)
+
+	_, err1 := legacySchema.createFingerprint([]string{"simple.txt"})
+	_, err2 := schema.createFingerprint([]string{"simple.txt", "0c17222d", "crc32"})
+
+	if err1 == nil {
+		t.Error("A legacy record without checksum should be rejected.")
+	}
+	if err2 == nil {
+		t.Error("A record shorter than the header should be rejected.")
+	}
+}
+
+func testCsvSchemaCreateRecord(t *testing.T) {
+
+	schema := newCsvSchema([]string{"owner"}, nil)
+	fingerprint := &Fingerprint{
+		Filename: "simple.txt", Checksum: []byte{12, 23}, Size: 7, Tags: []string{"raw", "2019"},
+		Metadata: map[string]string{"owner": "alice"}}
+
+	record := schema.createRecord(fingerprint)
+
+	expected := []string{"simple.txt", "0c17", "", "", "", "", "7", "", "raw;2019", "alice"}
+	if len(record) != len(expected) {
+		t.Fatalf("Wrong number of fields: %d.", len(record))
+	}
+	for index := range expected {
+		if record[index] != expected[index] {
+			t.Errorf("Wrong value in column %s: %s.", schema.columns[index], record[index])
+		}
+	}
+}
+
+func testCsvSchemaFileAttributes(t *testing.T) {
+
+	schema := newCsvSchema(nil, attributeColumns)
+	xattrs := map[string][]byte{"user.tag": []byte("blue"), "user.a=b": {0, 255}, "user.x;y%": []byte("z")}
+	fingerprint := &Fingerprint{
+		Filename: "passwd", Checksum: []byte{12, 23}, Mode: "0644", UID: "0", GID: "0", Xattrs: xattrs}
+
+	record := schema.createRecord(fingerprint)
+	parsed, err := schema.createFingerprint(record)
+
+	if record[len(record)-1] != "user.a=b=00ff;user.tag=626c7565;user.x%3By%25=7a" {
+		t.Errorf("Wrong value in column %s: %s.", ColumnXattrs, record[len(record)-1])
+	}
+	if err != nil || parsed.Mode != "0644" || parsed.UID != "0" || parsed.GID != "0" {
+		t.Fatal("The mode and ownership are not parsed correctly.")
+	}
+	if len(parsed.Xattrs) != 3 || string(parsed.Xattrs["user.tag"]) != "blue" || parsed.Xattrs["user.a=b"][1] != 255 ||
+		string(parsed.Xattrs["user.x;y%"]) != "z" {
+		t.Errorf("The extended attributes are not parsed correctly: %v.", parsed.Xattrs)
+	}
+}
+
+func testCsvSchemaLinkTarget(t *testing.T) {
+
+	schema := newCsvSchema(nil, linkColumns)
+	fingerprint := &Fingerprint{Filename: "latest", Checksum: []byte{12, 23}, LinkTarget: "releases/1.2"}
+
+	record := schema.createRecord(fingerprint)
+	parsed, err := schema.createFingerprint(record)
+
+	if record[len(record)-1] != "releases/1.2" {
+		t.Errorf("Wrong value in column %s: %s.", ColumnLinkTarget, record[len(record)-1])
+	}
+	if err != nil || parsed.LinkTarget != "releases/1.2" || !IsStandardColumn(ColumnLinkTarget) {
+		t.Error("The link target is not parsed correctly.")
+	}
+}
+
+func testCsvSchemaParseHeaderMissingColumn(t *testing.T) {
+
+	_, err1 := parseCsvSchema([]string{"filename", "algorithm"})
+	_, err2 := parseCsvSchema([]string{"filename", "checksum", "filename"})
+
+	if err1 == nil {
+		t.Error("A header without checksum column should be rejected.")
+	}
+	if err2 == nil {
+		t.Error("A header with duplicate columns should be rejected.")
+	}
+}
+
+func testCsvSchemaParseVersion(t *testing.T) {
+
+	err1 := parseCsvSchemaVersion([]string{CsvSchemaMarker, "2"})
+	err2 := parseCsvSchemaVersion([]string{CsvSchemaMarker, "3"})
+	err3 := parseCsvSchemaVersion([]string{CsvSchemaMarker})
+
+	if err1 != nil {
+		t.Errorf("The current version is rejected: %s.", err1)
+	}
+	if err2 == nil {
+		t.Error("A newer version should be rejected.")
+	}
+	if err3 == nil {
+		t.Error("A missing version should be rejected.")
+	}
+}
@@ -48,7 +48,7 @@ func assertStoredFingerprintIsValid(t *testing.T, actualFingerprints *list.List)
 func testDatabaseAddFingerprint(t *testing.T, database Database) {
 
 	checksum := []byte{12, 23, 34, 45}
-	fingerprint := &Fingerprint{"simple.txt", checksum, "sha1", "", "", ""}
+	fingerprint := &Fingerprint{Filename: "simple.txt", Checksum: checksum, Algorithm: "sha1"}
 
 	database.AddFingerprint(fingerprint)
 	actualFingerprints := database.GetFingerprints()
@@ -59,7 +59,7 @@ func testDatabaseAddFingerprint(t *testing.T, database Database) {
 func testDatabaseAddFingerprints(t *testing.T, database Database) {
 
 	checksum := []byte{12, 23, 34, 45}
-	fingerprint := &Fingerprint{"simple.txt", checksum, "sha1", "", "", ""}
+	fingerprint := &Fingerprint{Filename: "simple.txt", Checksum: checksum, Algorithm: "sha1"}
 	fingerprints := list.New()
 	fingerprints.PushFront(fingerprint)
 
@@ -90,7 +90,7 @@ func testDatabaseAddNamePair(t *testing.T, database Database) {
 
 func testDatabaseClear(t *testing.T, database Database) {
 
-	fingerprint := &Fingerprint{"simple.txt", nil, "", "", "", ""}
+	fingerprint := &Fingerprint{Filename: "simple.txt"}
 	namePair := &NamePair{"apple", "orange"}
 
 	database.AddFingerprint(fingerprint)
@@ -109,7 +109,7 @@ func testDatabaseClear(t *testing.T, database Database) {
 
 func testDatabaseLoadNamesFromFingerprints(t *testing.T, database Database) {
 
-	fingerprint := &Fingerprint{"simple.txt", nil, "", "", "", ""}
+	fingerprint := &Fingerprint{Filename: "simple.txt"}
 	otfReadTester := newOnTheFlyFingerprintReadTester(t)
 
 	database.AddFingerprint(fingerprint)
@@ -0,0 +1,129 @@
+package dal
+
+import (
+	"bytes"
+	"fmr/util"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// DryRunDatabase Wraps a database so that nothing is saved. Loading and changing the entries work as usual, but what
+// would be saved is written to the given output instead: the records of the output file of a FileDatabase that would be
+// removed and added, the checksums of other databases and the name pairs as a list.
+type DryRunDatabase struct {
+	Database
+	fpOutputPath       string
+	namePairOutputPath string
+	output             io.Writer
+}
+
+// NewDryRunDatabase Instantiates a new DryRunDatabase object for the given database and its output paths.
+func NewDryRunDatabase(
+	db Database, fpOutputPath string, namePairOutputPath string, output io.Writer) *DryRunDatabase {
+
+	return &DryRunDatabase{db, fpOutputPath, namePairOutputPath, output}
+}
+
+// SaveFingerprints Writes the changes that saving the fingerprints would make instead of saving them.
+func (db *DryRunDatabase) SaveFingerprints() {
+
+	fileDatabase, isFileDatabase := db.Database.(FileDatabase)
+	if !isFileDatabase {
+		db.writeFingerprintList()
+		return
+	}
+
+	content := new(bytes.Buffer)
+	err := fileDatabase.RenderFingerprints(content)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot render %s: %s.", db.fpOutputPath, err))
+
+	currentContent, err := readUncompressedFile(db.fpOutputPath)
+	if err != nil && !os.IsNotExist(err) {
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot read %s: %s.", db.fpOutputPath, err))
+	}
+
+	removed, added := util.DiffRecords(
+		splitRecords(string(currentContent), db.fpOutputPath), splitRecords(content.String(), db.fpOutputPath))
+	if len(removed) == 0 && len(added) == 0 {
+		fmt.Fprintf(db.output, "Dry run: %s would not change.\n", db.fpOutputPath)
+		return
+	}
+
+	fmt.Fprintf(db.output, "Dry run: %s would be written with %d record(s) removed and %d added, in any order.\n",
+		db.fpOutputPath, len(removed), len(added))
+	for _, record := range removed {
+		fmt.Fprintf(db.output, "Removed: %s\n", record)
+	}
+	for _, record := range added {
+		fmt.Fprintf(db.output, "Added: %s\n", record)
+	}
+}
+
+// splitRecords Splits the content of the database file with the given name into records: the lines of a JSON Lines
+// file, the records of a CSV, where a line break between double quotes belongs to the field.
+func splitRecords(content string, p string) []string {
+
+	content = strings.TrimSuffix(strings.Replace(content, "\r\n", "\n", -1), "\n")
+	if content == "" {
+		return []string{}
+	}
+	if IsJsonlPath(p) {
+		return strings.Split(content, "\n")
+	}
+
+	records := make([]string, 0)
+	start, isQuoted := 0, false
+	for index, character := range content {
+		if character == '"' {
+			isQuoted = !isQuoted
+		} else if character == '\n' && !isQuoted {
+			records = append(records, content[start:index])
+			start = index + 1
+		}
+	}
+
+	return append(records, content[start:])
+}
+
+// SaveNamePairs Writes the name pairs instead of saving them.
+func (db *DryRunDatabase) SaveNamePairs() {
+
+	namePairs := db.GetNamePairs()
+	fmt.Fprintf(db.output, "Dry run: %d name pair(s) would be written to %s.\n", namePairs.Len(), db.namePairOutputPath)
+	for element := namePairs.Front(); element != nil; element = element.Next() {
+		namePair := element.Value.(*NamePair)
+		fmt.Fprintf(db.output, "%s <- %s\n", namePair.NewName, namePair.OldName)
+	}
+}
+
+// writeFingerprintList Writes the checksums that a database without an output file, e.g. the extended attributes of the
+// files, would store.
+func (db *DryRunDatabase) writeFingerprintList() {
+
+	fingerprints := db.GetFingerprints()
+	fmt.Fprintf(db.output, "Dry run: %d checksum(s) would be stored.\n", fingerprints.Len())
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*Fingerprint)
+		fmt.Fprintf(db.output, "%s %x %s\n", fingerprint.Algorithm, fingerprint.Checksum, fingerprint.Filename)
+	}
+}
+
+// readUncompressedFile Reads the given database file, decompressing it if needed.
+func readUncompressedFile(p string) ([]byte, error) {
+
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := newDecompressingReader(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(reader)
+}
@@ -0,0 +1,120 @@
+package dal
+
+import (
+	"bytes"
+	"fmr/util"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDryRunDatabase(t *testing.T) {
+
+	setupDryRunDatabaseTests()
+
+	t.Run("DryRunDatabase_SaveFingerprints_Diff", testDryRunDatabaseSaveFingerprintsDiff)
+	t.Run("DryRunDatabase_SaveFingerprints_NewFile", testDryRunDatabaseSaveFingerprintsNewFile)
+	t.Run("DryRunDatabase_SaveFingerprints_MultilineField", testDryRunDatabaseSaveFingerprintsMultilineField)
+	t.Run("DryRunDatabase_SaveFingerprints_List", testDryRunDatabaseSaveFingerprintsList)
+	t.Run("DryRunDatabase_SaveNamePairs", testDryRunDatabaseSaveNamePairs)
+
+	tearDownDryRunDatabaseTests()
+}
+
+func setupDryRunDatabaseTests() {
+
+	testHelper.CreateTestRootDirectory()
+}
+
+func tearDownDryRunDatabaseTests() {
+
+	testHelper.CleanUp()
+}
+
+func testDryRunDatabaseSaveFingerprintsDiff(t *testing.T) {
+
+	path := testHelper.GetTestPath("dryrun.csv")
+	csvDatabase := NewCsvDatabase(path, path, "")
+	csvDatabase.AddFingerprint(&Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"})
+	csvDatabase.SaveFingerprints()
+	original, _ := ioutil.ReadFile(path)
+	output := new(bytes.Buffer)
+	db := NewDryRunDatabase(csvDatabase, path, "", output)
+
+	db.AddFingerprint(&Fingerprint{Filename: "other.txt", Checksum: []byte{1}, Algorithm: "sha1"})
+	db.SaveFingerprints()
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "Added: other.txt,01,sha1") {
+		t.Errorf("Wrong dry-run output: %s.", output.String())
+	}
+	content, _ := ioutil.ReadFile(path)
+	if string(content) != string(original) {
+		t.Error("The database should not be written in dry-run mode.")
+	}
+}
+
+func testDryRunDatabaseSaveFingerprintsNewFile(t *testing.T) {
+
+	path := testHelper.GetTestPath("dryrun-new.jsonl")
+	output := new(bytes.Buffer)
+	db := NewDryRunDatabase(NewJsonlDatabase("", path, ""), path, "", output)
+
+	db.AddFingerprint(&Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"})
+	db.SaveFingerprints()
+
+	if !strings.Contains(output.String(), "0 record(s) removed and 1 added") ||
+		!strings.Contains(output.String(), `Added: {"filename":"simple.txt"`) {
+		t.Errorf("Wrong dry-run output: %s.", output.String())
+	}
+	if util.CheckIfFileExists(path) {
+		t.Error("The database should not be created in dry-run mode.")
+	}
+}
+
+func testDryRunDatabaseSaveFingerprintsMultilineField(t *testing.T) {
+
+	path := testHelper.GetTestPath("dryrun-multiline.csv")
+	csvDatabase := NewCsvDatabase(path, path, "")
+	csvDatabase.AddFingerprint(&Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"})
+	csvDatabase.SaveFingerprints()
+	output := new(bytes.Buffer)
+	db := NewDryRunDatabase(csvDatabase, path, "", output)
+
+	db.GetFingerprints().Front().Value.(*Fingerprint).Note = "first line\nsecond \"line\""
+	db.SaveFingerprints()
+
+	records := strings.Split(strings.TrimSpace(output.String()), "\nAdded: ")
+	if !strings.Contains(output.String(), "1 record(s) removed and 1 added") || len(records) != 2 ||
+		!strings.HasPrefix(records[1], "simple.txt,") || !strings.Contains(records[1], "first line\nsecond \"\"line\"\"") {
+		t.Errorf("A field with a line break should not split the record: %s.", output.String())
+	}
+}
+
+func testDryRunDatabaseSaveFingerprintsList(t *testing.T) {
+
+	output := new(bytes.Buffer)
+	db := NewDryRunDatabase(NewMemoryDatabase(), "", "", output)
+
+	db.AddFingerprint(&Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"})
+	db.SaveFingerprints()
+
+	expected := "Dry run: 1 checksum(s) would be stored.\nsha1 0c17222d simple.txt\n"
+	if output.String() != expected {
+		t.Errorf("Wrong dry-run output: %s.", output.String())
+	}
+}
+
+func testDryRunDatabaseSaveNamePairs(t *testing.T) {
+
+	path := testHelper.GetTestPath("dryrun-namepairs.fm")
+	output := new(bytes.Buffer)
+	db := NewDryRunDatabase(NewMemoryDatabase(), "", path, output)
+
+	db.AddNamePair(&NamePair{OldName: "old.txt", NewName: "new.txt"})
+	db.SaveNamePairs()
+
+	if !strings.HasSuffix(output.String(), "new.txt <- old.txt\n") || util.CheckIfFileExists(path) {
+		t.Errorf("Wrong dry-run output: %s.", output.String())
+	}
+}
@@ -2,12 +2,24 @@ package dal
 
 // Fingerprint Stores the necessary data to identify a file and a bit more.
 type Fingerprint struct {
-	Filename  string
-	Checksum  []byte
-	Algorithm string
-	CreatedAt string
-	Creator   string
-	Note      string
+	Filename   string
+	Checksum   []byte
+	Algorithm  string
+	CreatedAt  string
+	Creator    string
+	Note       string
+	Size       int64
+	ModifiedAt string
+	Tags       []string
+	Metadata   map[string]string
+	BlockSize  int64
+	BlockRoot  []byte
+	Blocks     [][]byte
+	LinkTarget string
+	Mode       string
+	UID        string
+	GID        string
+	Xattrs     map[string][]byte
 }
 
 // NamePair Stores old name - new name pairs.
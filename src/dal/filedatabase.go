@@ -0,0 +1,134 @@
+package dal
+
+import (
+	"container/list"
+	"crypto/ed25519"
+	"fmr/util"
+	"io"
+)
+
+// NewFileDatabase Instantiates the database matching the extensions of the given paths: a JsonlDatabase for ".jsonl"
+// files, compressed or not, a CsvDatabase otherwise. If the input and the output have different formats, the input is
+// read in its format and the output is written in the other one.
+func NewFileDatabase(fpInputPath string, fpOutputPath string, namePairOutputPath string) FileDatabase {
+
+	if fpInputPath == "" || fpOutputPath == "" || IsJsonlPath(fpInputPath) == IsJsonlPath(fpOutputPath) {
+		formatPath := fpOutputPath
+		if formatPath == "" {
+			formatPath = fpInputPath
+		}
+		return newFileDatabase(formatPath, fpInputPath, fpOutputPath, namePairOutputPath)
+	}
+
+	input := newFileDatabase(fpInputPath, fpInputPath, "", "")
+	output := newFileDatabase(fpOutputPath, "", fpOutputPath, namePairOutputPath)
+
+	return &convertingDatabase{input, output}
+}
+
+func newFileDatabase(
+	formatPath string, fpInputPath string, fpOutputPath string, namePairOutputPath string) FileDatabase {
+
+	if IsJsonlPath(formatPath) {
+		return NewJsonlDatabase(fpInputPath, fpOutputPath, namePairOutputPath)
+	}
+
+	return NewCsvDatabase(fpInputPath, fpOutputPath, namePairOutputPath)
+}
+
+// convertingDatabase Reads the fingerprints with one database and holds and saves them with another one, so that the
+// input and the output may have different formats.
+type convertingDatabase struct {
+	input  FileDatabase
+	output FileDatabase
+}
+
+// SetSigningKey Sets the key used to sign the output.
+func (db *convertingDatabase) SetSigningKey(key ed25519.PrivateKey) {
+
+	db.output.SetSigningKey(key)
+}
+
+// SetVerificationKey Sets the key used to check the signature of the input.
+func (db *convertingDatabase) SetVerificationKey(key ed25519.PublicKey) {
+
+	db.input.SetVerificationKey(key)
+}
+
+// SetMetadataColumns Declares custom metadata columns of the output.
+func (db *convertingDatabase) SetMetadataColumns(columns []string) {
+
+	db.output.SetMetadataColumns(columns)
+}
+
+// AddFingerprint Adds a fingerprint to the database.
+func (db *convertingDatabase) AddFingerprint(fingerprint *Fingerprint) {
+
+	db.output.AddFingerprint(fingerprint)
+}
+
+// AddFingerprints Adds a list of fingerprints to the database.
+func (db *convertingDatabase) AddFingerprints(fingerprints *list.List) {
+
+	db.output.AddFingerprints(fingerprints)
+}
+
+// AddNamePair Adds a name pair to the database.
+func (db *convertingDatabase) AddNamePair(namePair *NamePair) {
+
+	db.output.AddNamePair(namePair)
+}
+
+// Clear Removes all entries from the database.
+func (db *convertingDatabase) Clear() {
+
+	db.input.Clear()
+	db.output.Clear()
+}
+
+// GetFingerprints Returns stored fingerprints.
+func (db *convertingDatabase) GetFingerprints() *list.List {
+
+	return db.output.GetFingerprints()
+}
+
+// GetNamePairs Returns stored name pairs.
+func (db *convertingDatabase) GetNamePairs() *list.List {
+
+	return db.output.GetNamePairs()
+}
+
+// LoadFingerprints Loads the fingerprints of the input, keeping their order.
+func (db *convertingDatabase) LoadFingerprints() {
+
+	db.input.LoadFingerprints()
+	fingerprints := db.input.GetFingerprints()
+	for element := fingerprints.Back(); element != nil; element = element.Prev() {
+		db.output.AddFingerprint(element.Value.(*Fingerprint))
+	}
+	db.input.Clear()
+}
+
+// LoadNamesFromFingeprints Loads the filenames of the input and forwards it to the given StringWriter.
+func (db *convertingDatabase) LoadNamesFromFingeprints(writer util.StringWriter) {
+
+	db.input.LoadNamesFromFingeprints(writer)
+}
+
+// SaveFingerprints Saves fingerprints to the output.
+func (db *convertingDatabase) SaveFingerprints() {
+
+	db.output.SaveFingerprints()
+}
+
+// RenderFingerprints Writes the content of the output to the given destination.
+func (db *convertingDatabase) RenderFingerprints(destination io.Writer) error {
+
+	return db.output.RenderFingerprints(destination)
+}
+
+// SaveNamePairs Saves name pairs to the output.
+func (db *convertingDatabase) SaveNamePairs() {
+
+	db.output.SaveNamePairs()
+}
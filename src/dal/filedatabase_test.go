@@ -0,0 +1,71 @@
+package dal
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestFileDatabase(t *testing.T) {
+
+	setupFileDatabaseTests()
+
+	t.Run("IsJsonlPath", testIsJsonlPath)
+	t.Run("NewFileDatabase_Format", testNewFileDatabaseFormat)
+	t.Run("NewFileDatabase_Converting", testNewFileDatabaseConverting)
+
+	tearDownFileDatabaseTests()
+}
+
+func setupFileDatabaseTests() {
+
+	testHelper.CreateTestRootDirectory()
+}
+
+func tearDownFileDatabaseTests() {
+
+	testHelper.CleanUp()
+}
+
+func testIsJsonlPath(t *testing.T) {
+
+	for path, expected := range map[string]bool{
+		"snapshot.jsonl": true, "snapshot.JSONL.gz": true, "snapshot.jsonl.zst": true,
+		"snapshot.csv": false, "snapshot.csv.gz": false, "jsonl": false} {
+		if actual := IsJsonlPath(path); actual != expected {
+			t.Errorf("Wrong result for %s: %t.", path, actual)
+		}
+	}
+}
+
+func testNewFileDatabaseFormat(t *testing.T) {
+
+	if _, ok := NewFileDatabase("in.jsonl.gz", "", "").(*JsonlDatabase); !ok {
+		t.Error("A JsonlDatabase should be created for a .jsonl.gz input.")
+	}
+	if _, ok := NewFileDatabase("", "out.csv", "").(*CsvDatabase); !ok {
+		t.Error("A CsvDatabase should be created for a .csv output.")
+	}
+	if _, ok := NewFileDatabase("in.csv", "out.jsonl", "").(*convertingDatabase); !ok {
+		t.Error("A converting database should be created for different formats.")
+	}
+}
+
+func testNewFileDatabaseConverting(t *testing.T) {
+
+	inputPath := testHelper.GetTestPath("input.csv")
+	outputPath := testHelper.GetTestPath("output.jsonl")
+	csvDatabase := NewCsvDatabase("", inputPath, "")
+	csvDatabase.AddFingerprint(&Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"})
+	csvDatabase.SaveFingerprints()
+	db := NewFileDatabase(inputPath, outputPath, "")
+
+	db.LoadFingerprints()
+	db.SaveFingerprints()
+
+	assertStoredFingerprintIsValid(t, db.GetFingerprints())
+	content, _ := ioutil.ReadFile(outputPath)
+	if !strings.HasPrefix(string(content), `{"filename":"simple.txt","checksum":"0c17222d"`) {
+		t.Errorf("The output should be written as JSON Lines: %s.", content)
+	}
+}
@@ -45,8 +45,28 @@ const SHA512EXT string = ".sha512"
 // SHA512LEN Stores the length of an SHA-512 hash.
 const SHA512LEN int = 128
 
+// ZIPEXT Stores the extension of ZIP archives, whose central directory contains the CRC32 of each member.
+const ZIPEXT string = ".zip"
+
+// DEBMD5SUMS Stores the name of the control file listing the MD5 hashes of the files in a Debian package.
+const DEBMD5SUMS string = "md5sums"
+
+// DEBMD5SUMSEXT Stores the extension of the MD5 lists of installed Debian packages (/var/lib/dpkg/info).
+const DEBMD5SUMSEXT string = ".md5sums"
+
+// RPMDUMPEXT Stores the extension of an RPM file list in the format of "rpm -q --dump".
+const RPMDUMPEXT string = ".rpmdump"
+
+// MTREEEXT Stores the extension of an mtree specification, which describes the type, mode, size, modification time
+// and digests of each path in a tree.
+const MTREEEXT string = ".mtree"
+
 // PATTERNCOMMON The Regular Expression for the common file types.
 const PATTERNCOMMON string = "^(?P<hash>[a-fA-F0-9]{%d}) ( |\\*)(?P<file>.+)$"
 
 // PATTERNCRC32 The Regular Expression for the CRC32 file types.
 const PATTERNCRC32 string = "^(?P<file>.+) (?P<hash>[a-fA-F0-9]{%d})$"
+
+// PATTERNRPMDUMP The Regular Expression for RPM file lists: path, size, modification time and digest, followed by
+// further attributes.
+const PATTERNRPMDUMP string = "^(?P<file>.+) (?P<size>[0-9]+) (?P<mtime>[0-9]+) (?P<hash>[a-fA-F0-9]{%d}) "
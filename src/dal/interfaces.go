@@ -2,7 +2,9 @@ package dal
 
 import (
 	"container/list"
+	"crypto/ed25519"
 	"fmr/util"
+	"io"
 )
 
 // Database Interface for database implementations.
@@ -18,3 +20,12 @@ type Database interface {
 	SaveFingerprints()
 	SaveNamePairs()
 }
+
+// FileDatabase Interface for databases stored in files, which can be signed and declare custom metadata columns.
+type FileDatabase interface {
+	Database
+	SetSigningKey(key ed25519.PrivateKey)
+	SetVerificationKey(key ed25519.PublicKey)
+	SetMetadataColumns(columns []string)
+	RenderFingerprints(destination io.Writer) error
+}
@@ -0,0 +1,328 @@
+package dal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmr/util"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JsonlExtension The extension of the files read and written by JsonlDatabase.
+const JsonlExtension = ".jsonl"
+
+// GzipExtension The extension of gzip compressed files.
+const GzipExtension = ".gz"
+
+// ZstdExtension The extension of zstd compressed files.
+const ZstdExtension = ".zst"
+
+// ErrZstdNotSupported Returned when a database is, or should be, compressed with zstd.
+var ErrZstdNotSupported = errors.New("zstd compression is not supported, use gzip")
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// JsonlDatabase Stores fingerprints in a JSON Lines file: one JSON object per fingerprint, with the same keys as the
+// columns of CsvDatabase. Checksums are hexadecimal, block hashes are stored inline, metadata holding a JSON object or
+// array is kept nested. Files ending in ".gz" are compressed with gzip. Fingerprints are read and written one line at
+// a time, only signing and signature verification need the whole file in memory.
+type JsonlDatabase struct {
+	baseDatabase
+}
+
+// jsonlRecord The JSON object stored for a fingerprint.
+type jsonlRecord struct {
+	Filename   string                     `json:"filename"`
+	Checksum   string                     `json:"checksum"`
+	Algorithm  string                     `json:"algorithm"`
+	CreatedAt  string                     `json:"created_at,omitempty"`
+	Creator    string                     `json:"creator,omitempty"`
+	Note       string                     `json:"note,omitempty"`
+	Size       int64                      `json:"size"`
+	ModifiedAt string                     `json:"modified_at,omitempty"`
+	Tags       []string                   `json:"tags,omitempty"`
+	Metadata   map[string]json.RawMessage `json:"metadata,omitempty"`
+	BlockSize  int64                      `json:"block_size,omitempty"`
+	BlockRoot  string                     `json:"block_root,omitempty"`
+	Blocks     []string                   `json:"blocks,omitempty"`
+	LinkTarget string                     `json:"link_target,omitempty"`
+	Mode       string                     `json:"mode,omitempty"`
+	UID        string                     `json:"uid,omitempty"`
+	GID        string                     `json:"gid,omitempty"`
+	Xattrs     map[string]string          `json:"xattrs,omitempty"`
+}
+
+// NewJsonlDatabase Instantiates a new JsonlDatabase object.
+func NewJsonlDatabase(fpInputPath string, fpOutputPath string, namePairOutputPath string) *JsonlDatabase {
+
+	return &JsonlDatabase{newBaseDatabase(fpInputPath, fpOutputPath, namePairOutputPath)}
+}
+
+// IsJsonlPath Checks whether the given path names a JSON Lines file, compressed or not.
+func IsJsonlPath(p string) bool {
+
+	p = strings.ToLower(p)
+	p = strings.TrimSuffix(strings.TrimSuffix(p, GzipExtension), ZstdExtension)
+
+	return strings.HasSuffix(p, JsonlExtension)
+}
+
+// SetMetadataColumns Does nothing, every metadata key of a fingerprint is written with it.
+func (db *JsonlDatabase) SetMetadataColumns(columns []string) {
+}
+
+// LoadFingerprints Loads fingerprints from the input file.
+func (db *JsonlDatabase) LoadFingerprints() {
+
+	db.readFingerprints(func(fingerprint *Fingerprint) {
+		db.fingerprints.PushFront(fingerprint)
+	})
+}
+
+// LoadNamesFromFingeprints Loads the filenames and forwards it to the given StringWriter.
+func (db *JsonlDatabase) LoadNamesFromFingeprints(writer util.StringWriter) {
+
+	db.readFingerprints(func(fingerprint *Fingerprint) {
+		writer.Write(fingerprint.Filename)
+	})
+}
+
+// SaveFingerprints Saves fingerprints to the output file, compressed if its name ends in ".gz". The file is replaced
+// atomically, the version found by the first save (e.g. before the checkpoints of a run) is kept with a ".bak"
+// extension.
+func (db *JsonlDatabase) SaveFingerprints() {
+
+	compress, err := getJsonlCompression(db.fpOutputPath)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot write %s: %s.", db.fpOutputPath, err))
+
+	db.saveOutput(func(destination io.Writer) error {
+		return writeJsonlFingerprints(db.fingerprints, destination, compress)
+	})
+}
+
+// RenderFingerprints Writes the content of the output file to the given destination, uncompressed.
+func (db *JsonlDatabase) RenderFingerprints(destination io.Writer) error {
+
+	return writeJsonlFingerprints(db.fingerprints, destination, false)
+}
+
+// readFingerprints Decodes the input one object at a time and passes each fingerprint to the given function. The
+// input is only read at once if its signature has to be verified.
+func (db *JsonlDatabase) readFingerprints(handle func(fingerprint *Fingerprint)) {
+
+	input := db.openInput()
+	defer input.Close()
+
+	reader, err := newDecompressingReader(input)
+	util.CheckErrDontPanic(err, fmt.Sprintf("Cannot read %s: %s.", db.fpInputPath, err))
+
+	decoder := json.NewDecoder(reader)
+	for recordNumber := 1; ; recordNumber++ {
+		var record jsonlRecord
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		util.CheckErrDontPanic(err, fmt.Sprintf("Cannot parse %s: %s.", db.fpInputPath, err))
+
+		fingerprint, err := record.createFingerprint()
+		util.CheckErrDontPanic(err, fmt.Sprintf("Invalid record %d in %s: %s.", recordNumber, db.fpInputPath, err))
+		handle(fingerprint)
+	}
+}
+
+// getJsonlCompression Checks whether the file with the given name has to be compressed with gzip.
+func getJsonlCompression(p string) (bool, error) {
+
+	p = strings.ToLower(p)
+	if strings.HasSuffix(p, ZstdExtension) {
+		return false, ErrZstdNotSupported
+	}
+
+	return strings.HasSuffix(p, GzipExtension), nil
+}
+
+// newDecompressingReader Returns a reader of the uncompressed content of the given input. The compression is recognized
+// by its magic number, not by the extension of the file.
+func newDecompressingReader(input io.Reader) (io.Reader, error) {
+
+	reader := bufio.NewReader(input)
+	magic, _ := reader.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(reader)
+	case bytes.HasPrefix(magic, zstdMagic):
+		return nil, ErrZstdNotSupported
+	}
+
+	return reader, nil
+}
+
+// writeJsonlFingerprints Writes the given fingerprints to the destination, one JSON object per line.
+func writeJsonlFingerprints(fingerprints *list.List, destination io.Writer, compress bool) error {
+
+	var compressor *gzip.Writer
+	if compress {
+		compressor = gzip.NewWriter(destination)
+		destination = compressor
+	}
+
+	writer := bufio.NewWriter(destination)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*Fingerprint)
+		if err := encoder.Encode(newJsonlRecord(fingerprint)); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if compressor != nil {
+		return compressor.Close()
+	}
+
+	return nil
+}
+
+func newJsonlRecord(fingerprint *Fingerprint) *jsonlRecord {
+
+	record := &jsonlRecord{
+		Filename:   fingerprint.Filename,
+		Checksum:   hex.EncodeToString(fingerprint.Checksum),
+		Algorithm:  fingerprint.Algorithm,
+		CreatedAt:  fingerprint.CreatedAt,
+		Creator:    fingerprint.Creator,
+		Note:       fingerprint.Note,
+		Size:       fingerprint.Size,
+		ModifiedAt: fingerprint.ModifiedAt,
+		Tags:       fingerprint.Tags,
+		BlockSize:  fingerprint.BlockSize,
+		BlockRoot:  hex.EncodeToString(fingerprint.BlockRoot),
+		LinkTarget: fingerprint.LinkTarget,
+		Mode:       fingerprint.Mode,
+		UID:        fingerprint.UID,
+		GID:        fingerprint.GID,
+	}
+
+	for _, block := range fingerprint.Blocks {
+		record.Blocks = append(record.Blocks, hex.EncodeToString(block))
+	}
+	if len(fingerprint.Metadata) > 0 {
+		record.Metadata = make(map[string]json.RawMessage)
+		for key, value := range fingerprint.Metadata {
+			record.Metadata[key] = encodeJsonlMetadata(value)
+		}
+	}
+	if len(fingerprint.Xattrs) > 0 {
+		record.Xattrs = make(map[string]string)
+		for name, value := range fingerprint.Xattrs {
+			record.Xattrs[name] = hex.EncodeToString(value)
+		}
+	}
+
+	return record
+}
+
+func (record *jsonlRecord) createFingerprint() (*Fingerprint, error) {
+
+	if record.Filename == "" {
+		return nil, errors.New("missing filename")
+	}
+	checksum, err := hex.DecodeString(record.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum: %s", record.Checksum)
+	}
+	if record.BlockSize < 0 {
+		return nil, fmt.Errorf("invalid block size: %d", record.BlockSize)
+	}
+
+	fingerprint := &Fingerprint{
+		Filename:   record.Filename,
+		Checksum:   checksum,
+		Algorithm:  record.Algorithm,
+		CreatedAt:  record.CreatedAt,
+		Creator:    record.Creator,
+		Note:       record.Note,
+		Size:       record.Size,
+		ModifiedAt: record.ModifiedAt,
+		Tags:       record.Tags,
+		BlockSize:  record.BlockSize,
+		LinkTarget: record.LinkTarget,
+		Mode:       record.Mode,
+		UID:        record.UID,
+		GID:        record.GID,
+	}
+	if fingerprint.Tags == nil {
+		fingerprint.Tags = make([]string, 0)
+	}
+
+	if record.BlockRoot != "" {
+		if fingerprint.BlockRoot, err = hex.DecodeString(record.BlockRoot); err != nil {
+			return nil, fmt.Errorf("invalid block root: %s", record.BlockRoot)
+		}
+	}
+	for _, block := range record.Blocks {
+		hash, err := hex.DecodeString(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block hash: %s", block)
+		}
+		fingerprint.Blocks = append(fingerprint.Blocks, hash)
+	}
+
+	if len(record.Metadata) > 0 {
+		fingerprint.Metadata = make(map[string]string)
+		for key, value := range record.Metadata {
+			fingerprint.Metadata[key] = decodeJsonlMetadata(value)
+		}
+	}
+
+	// Attributes are captured together: a record with a mode has all extended attributes of the file.
+	if record.Mode != "" || len(record.Xattrs) > 0 {
+		fingerprint.Xattrs = make(map[string][]byte)
+	}
+	for name, value := range record.Xattrs {
+		if fingerprint.Xattrs[name], err = hex.DecodeString(value); err != nil {
+			return nil, fmt.Errorf("invalid extended attribute: %s", name)
+		}
+	}
+
+	return fingerprint, nil
+}
+
+// encodeJsonlMetadata Encodes a metadata value as a JSON string, unless it holds a JSON object or array, which is
+// written as it is.
+func encodeJsonlMetadata(value string) json.RawMessage {
+
+	trimmed := strings.TrimSpace(value)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+
+	encoded, _ := json.Marshal(value)
+
+	return encoded
+}
+
+// decodeJsonlMetadata Decodes a metadata value. Values other than strings are kept as JSON.
+func decodeJsonlMetadata(value json.RawMessage) string {
+
+	var text string
+	if err := json.Unmarshal(value, &text); err == nil {
+		return text
+	}
+
+	return string(value)
+}
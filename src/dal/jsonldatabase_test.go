@@ -0,0 +1,169 @@
+package dal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestJsonlDatabase(t *testing.T) {
+
+	setupJsonlDatabaseTests()
+
+	t.Run("JsonlDatabase_AddFingerprint", testJsonlDatabaseAddFingerprint)
+	t.Run("JsonlDatabase_AddNamePair", testJsonlDatabaseAddNamePair)
+	t.Run("JsonlDatabase_Clear", testJsonlDatabaseClear)
+	t.Run("JsonlDatabase_LoadNamesFromFingerprints", testJsonlDatabaseLoadNamesFromFingerprints)
+	t.Run("JsonlDatabase_SaveAndLoadFingerprints", testJsonlDatabaseSaveAndLoadFingerprints)
+	t.Run("JsonlDatabase_SaveFingerprints_Format", testJsonlDatabaseSaveFingerprintsFormat)
+	t.Run("JsonlDatabase_Gzip", testJsonlDatabaseGzip)
+	t.Run("JsonlDatabase_Metadata_Nested", testJsonlDatabaseMetadataNested)
+	t.Run("JsonlDatabase_BlocksAndAttributes", testJsonlDatabaseBlocksAndAttributes)
+	t.Run("JsonlDatabase_Zstd", testJsonlDatabaseZstd)
+
+	tearDownJsonlDatabaseTests()
+}
+
+func setupJsonlDatabaseTests() {
+
+	testHelper.CreateTestRootDirectory()
+}
+
+func tearDownJsonlDatabaseTests() {
+
+	testHelper.CleanUp()
+}
+
+func testJsonlDatabaseAddFingerprint(t *testing.T) {
+
+	path := testHelper.GetTestPath("fingerprints.jsonl")
+	testDatabaseAddFingerprint(t, NewJsonlDatabase(path, path, testHelper.GetTestPath("namepairs.fm")))
+}
+
+func testJsonlDatabaseAddNamePair(t *testing.T) {
+
+	path := testHelper.GetTestPath("fingerprints.jsonl")
+	testDatabaseAddNamePair(t, NewJsonlDatabase(path, path, testHelper.GetTestPath("namepairs.fm")))
+}
+
+func testJsonlDatabaseClear(t *testing.T) {
+
+	path := testHelper.GetTestPath("fingerprints.jsonl")
+	testDatabaseClear(t, NewJsonlDatabase(path, path, testHelper.GetTestPath("namepairs.fm")))
+}
+
+func testJsonlDatabaseLoadNamesFromFingerprints(t *testing.T) {
+
+	path := testHelper.GetTestPath("fingerprints.jsonl")
+	testDatabaseLoadNamesFromFingerprints(t, NewJsonlDatabase(path, path, testHelper.GetTestPath("namepairs.fm")))
+}
+
+func testJsonlDatabaseSaveAndLoadFingerprints(t *testing.T) {
+
+	path := testHelper.GetTestPath("fingerprints.jsonl")
+	jsonlDatabase := NewJsonlDatabase(path, path, "")
+
+	jsonlDatabase.AddFingerprint(&Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"})
+	jsonlDatabase.SaveFingerprints()
+	jsonlDatabase.Clear()
+	jsonlDatabase.LoadFingerprints()
+
+	assertStoredFingerprintIsValid(t, jsonlDatabase.GetFingerprints())
+}
+
+func testJsonlDatabaseSaveFingerprintsFormat(t *testing.T) {
+
+	path := testHelper.GetTestPath("format.jsonl")
+	jsonlDatabase := NewJsonlDatabase(path, path, "")
+	jsonlDatabase.AddFingerprint(&Fingerprint{
+		Filename: "a&b.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1", Size: 4, Tags: []string{"raw"}})
+	jsonlDatabase.AddFingerprint(&Fingerprint{Filename: "c.txt", Checksum: []byte{1}, Algorithm: "crc32"})
+
+	jsonlDatabase.SaveFingerprints()
+
+	content, _ := ioutil.ReadFile(path)
+	expected := `{"filename":"a&b.txt","checksum":"0c17222d","algorithm":"sha1","size":4,"tags":["raw"]}` + "\n"
+	if lines := strings.SplitAfter(string(content), "\n"); len(lines) != 3 || lines[1] != expected {
+		t.Errorf("Wrong content: %s.", content)
+	}
+}
+
+func testJsonlDatabaseGzip(t *testing.T) {
+
+	path := testHelper.GetTestPath("fingerprints.jsonl.gz")
+	jsonlDatabase := NewJsonlDatabase(path, path, "")
+
+	jsonlDatabase.AddFingerprint(&Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"})
+	jsonlDatabase.SaveFingerprints()
+	jsonlDatabase.Clear()
+	jsonlDatabase.LoadFingerprints()
+
+	assertStoredFingerprintIsValid(t, jsonlDatabase.GetFingerprints())
+	content, _ := ioutil.ReadFile(path)
+	if !bytes.HasPrefix(content, gzipMagic) {
+		t.Error("The database should be compressed with gzip.")
+	}
+}
+
+func testJsonlDatabaseMetadataNested(t *testing.T) {
+
+	path := testHelper.GetTestPath("metadata.jsonl")
+	ioutil.WriteFile(path, []byte(
+		`{"filename":"simple.txt","checksum":"0c17222d","algorithm":"sha1",`+
+			`"metadata":{"owner":"alice","camera":{"model":"X100","iso":200},"rating":5}}`+"\n"), 0644)
+	jsonlDatabase := NewJsonlDatabase(path, path, "")
+
+	jsonlDatabase.LoadFingerprints()
+	jsonlDatabase.SaveFingerprints()
+	jsonlDatabase.Clear()
+	jsonlDatabase.LoadFingerprints()
+
+	assertStoredFingerprintIsValid(t, jsonlDatabase.GetFingerprints())
+	metadata := jsonlDatabase.GetFingerprints().Front().Value.(*Fingerprint).Metadata
+	if metadata["owner"] != "alice" || metadata["camera"] != `{"model":"X100","iso":200}` || metadata["rating"] != "5" {
+		t.Errorf("Wrong metadata: %v.", metadata)
+	}
+	content, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(content), `"camera":{"model":"X100","iso":200}`) {
+		t.Errorf("Nested metadata is not preserved: %s.", content)
+	}
+}
+
+func testJsonlDatabaseBlocksAndAttributes(t *testing.T) {
+
+	path := testHelper.GetTestPath("blocks.jsonl")
+	jsonlDatabase := NewJsonlDatabase(path, path, "")
+	jsonlDatabase.AddFingerprint(&Fingerprint{
+		Filename: "disk.img", Checksum: []byte{1, 2}, Algorithm: "crc32",
+		BlockSize: 4096, BlockRoot: []byte{3, 4}, Blocks: [][]byte{{5, 6}, {7, 8}},
+		Mode: "-rw-r--r--", UID: "1000", GID: "1000", Xattrs: map[string][]byte{"user.origin": []byte("scan")}})
+	jsonlDatabase.AddFingerprint(&Fingerprint{
+		Filename: "plain.txt", Checksum: []byte{9}, Algorithm: "crc32", Mode: "-rw-------", Xattrs: map[string][]byte{}})
+
+	jsonlDatabase.SaveFingerprints()
+	jsonlDatabase.Clear()
+	jsonlDatabase.LoadFingerprints()
+
+	for element := jsonlDatabase.GetFingerprints().Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*Fingerprint)
+		if fingerprint.Filename == "disk.img" &&
+			(fingerprint.BlockSize != 4096 || len(fingerprint.BlockRoot) != 2 || len(fingerprint.Blocks) != 2 ||
+				fingerprint.UID != "1000" || string(fingerprint.Xattrs["user.origin"]) != "scan") {
+			t.Errorf("Blocks or attributes are not preserved: %v.", fingerprint)
+		}
+		if fingerprint.Filename == "plain.txt" && (fingerprint.Blocks != nil || fingerprint.Xattrs == nil) {
+			t.Errorf("Wrong blocks or attributes: %v.", fingerprint)
+		}
+	}
+}
+
+func testJsonlDatabaseZstd(t *testing.T) {
+
+	_, err1 := getJsonlCompression("snapshot.jsonl.zst")
+	_, err2 := newDecompressingReader(bytes.NewReader(append(zstdMagic, 0)))
+
+	if err1 != ErrZstdNotSupported || err2 != ErrZstdNotSupported {
+		t.Errorf("zstd should be rejected, got: %v, %v.", err1, err2)
+	}
+}
@@ -0,0 +1,169 @@
+package dal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmr/util"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// PublicKeyExtension The extension of the file containing the public part of a key pair.
+const PublicKeyExtension = ".pub"
+
+// SignatureExtension The extension of the detached signature of a database file.
+const SignatureExtension = ".sig"
+
+// ErrInvalidSignature Indicates that the signature does not match the content of the file.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// GenerateKeyPair Generates a new ed25519 key pair. The private key is saved to the given path, the public key to
+// "<path>.pub", both in PEM format. Existing keys are not overwritten.
+func GenerateKeyPair(privateKeyPath string) error {
+
+	publicKeyPath := privateKeyPath + PublicKeyExtension
+	if util.CheckIfFileExists(privateKeyPath) || util.CheckIfFileExists(publicKeyPath) {
+		return os.ErrExist
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	privateKeyPem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes})
+	if err := ioutil.WriteFile(privateKeyPath, privateKeyPem, 0600); err != nil {
+		return err
+	}
+	publicKeyPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes})
+
+	return ioutil.WriteFile(publicKeyPath, publicKeyPem, 0644)
+}
+
+// LoadPrivateKey Loads an ed25519 private key from the given PEM file.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+
+	block, err := readPemBlock(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 private key: " + path)
+	}
+
+	return privateKey, nil
+}
+
+// LoadPublicKey Loads an ed25519 public key from the given PEM file.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+
+	block, err := readPemBlock(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 public key: " + path)
+	}
+
+	return publicKey, nil
+}
+
+// SaveSignature Signs the given content and saves the detached signature to "<path>.sig". If keepBackup is true, the
+// previous signature is kept with a ".bak" extension, just like the previous version of the database.
+func SaveSignature(path string, content []byte, key ed25519.PrivateKey, keepBackup bool) error {
+
+	signature := ed25519.Sign(key, content)
+
+	file, err := util.CreateAtomicFile(path+SignatureExtension, keepBackup)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	if _, err := file.WriteString(base64.StdEncoding.EncodeToString(signature) + "\n"); err != nil {
+		return err
+	}
+
+	return file.Commit()
+}
+
+// updateSignature Signs the content of the database saved to the given path, before the database itself is committed:
+// an interrupted save leaves a signature that does not match rather than a database that seems valid. Without a key,
+// the signature of the previous version is removed (or kept as a backup), since it does not match the new content.
+func updateSignature(path string, content []byte, key ed25519.PrivateKey, keepBackup bool) error {
+
+	if key != nil {
+		return SaveSignature(path, content, key, keepBackup)
+	}
+
+	signaturePath := path + SignatureExtension
+	if !util.CheckIfFileExists(signaturePath) {
+		return nil
+	}
+	if keepBackup {
+		return os.Rename(signaturePath, signaturePath+util.BackupExtension)
+	}
+
+	return os.Remove(signaturePath)
+}
+
+// VerifySignature Checks the given content (read from the given path) against the detached signature stored in
+// "<path>.sig".
+func VerifySignature(path string, content []byte, key ed25519.PublicKey) error {
+
+	signatureText, err := ioutil.ReadFile(path + SignatureExtension)
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signatureText)))
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, content, signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func readPemBlock(path string, blockType string) (*pem.Block, error) {
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != blockType {
+		return nil, errors.New("no " + strings.ToLower(blockType) + " found in " + path)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,195 @@
+package dal
+
+import (
+	"container/list"
+	"crypto/ed25519"
+	"fmr/util"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+
+	setupSignatureTests()
+
+	t.Run("GenerateKeyPair_NoOverwrite", testGenerateKeyPairNoOverwrite)
+	t.Run("SaveAndVerifySignature", testSaveAndVerifySignature)
+	t.Run("VerifySignature_Tampered", testVerifySignatureTampered)
+	t.Run("VerifySignature_Missing", testVerifySignatureMissing)
+	t.Run("CsvDatabase_Signed", testCsvDatabaseSigned)
+	t.Run("JsonlDatabase_Signed", testJsonlDatabaseSigned)
+	t.Run("CsvDatabase_Unsigned", testCsvDatabaseUnsigned)
+	t.Run("BlockFile_Signed", testBlockFileSigned)
+
+	tearDownSignatureTests()
+}
+
+func setupSignatureTests() {
+
+	testHelper.CreateTestRootDirectory()
+
+	err := GenerateKeyPair(testHelper.GetTestPath("registry.key"))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func testGenerateKeyPairNoOverwrite(t *testing.T) {
+
+	err := GenerateKeyPair(testHelper.GetTestPath("registry.key"))
+
+	if err == nil {
+		t.Error("Existing keys should not be overwritten.")
+	}
+}
+
+func testSaveAndVerifySignature(t *testing.T) {
+
+	privateKey, publicKey := loadTestKeys(t)
+	path := testHelper.GetTestPath("signed.csv")
+	content := []byte("simple.txt,0c17222d,crc32,,,\n")
+
+	err1 := SaveSignature(path, content, privateKey, true)
+	err2 := VerifySignature(path, content, publicKey)
+
+	if err1 != nil {
+		t.Errorf("Cannot save signature: %s.", err1)
+	}
+	if err2 != nil {
+		t.Errorf("Valid signature is rejected: %s.", err2)
+	}
+}
+
+func testVerifySignatureTampered(t *testing.T) {
+
+	privateKey, publicKey := loadTestKeys(t)
+	path := testHelper.GetTestPath("tampered.csv")
+	content := []byte("simple.txt,0c17222d,crc32,,,\n")
+	tamperedContent := []byte("simple.txt,0c17222e,crc32,,,\n")
+
+	SaveSignature(path, content, privateKey, true)
+	err := VerifySignature(path, tamperedContent, publicKey)
+
+	if err != ErrInvalidSignature {
+		t.Errorf("Tampered content should be rejected, got: %v.", err)
+	}
+}
+
+func testVerifySignatureMissing(t *testing.T) {
+
+	_, publicKey := loadTestKeys(t)
+
+	err := VerifySignature(testHelper.GetTestPath("unsigned.csv"), []byte{}, publicKey)
+
+	if err == nil {
+		t.Error("Missing signature should be rejected.")
+	}
+}
+
+func testCsvDatabaseSigned(t *testing.T) {
+
+	privateKey, publicKey := loadTestKeys(t)
+	path := testHelper.GetTestPath("registry.csv")
+	fingerprint := &Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"}
+	csvDatabase := NewCsvDatabase(path, path, "")
+	csvDatabase.SetSigningKey(privateKey)
+	csvDatabase.SetVerificationKey(publicKey)
+
+	csvDatabase.AddFingerprint(fingerprint)
+	csvDatabase.SaveFingerprints()
+	csvDatabase.Clear()
+	csvDatabase.LoadFingerprints()
+
+	assertStoredFingerprintIsValid(t, csvDatabase.GetFingerprints())
+	content, _ := ioutil.ReadFile(path)
+	if err := VerifySignature(path, content, publicKey); err != nil {
+		t.Errorf("The saved database should have a valid signature: %s.", err)
+	}
+}
+
+func testJsonlDatabaseSigned(t *testing.T) {
+
+	privateKey, publicKey := loadTestKeys(t)
+	path := testHelper.GetTestPath("registry.jsonl.gz")
+	fingerprint := &Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"}
+	jsonlDatabase := NewJsonlDatabase(path, path, "")
+	jsonlDatabase.SetSigningKey(privateKey)
+	jsonlDatabase.SetVerificationKey(publicKey)
+
+	jsonlDatabase.AddFingerprint(fingerprint)
+	jsonlDatabase.SaveFingerprints()
+	jsonlDatabase.Clear()
+	jsonlDatabase.LoadFingerprints()
+
+	assertStoredFingerprintIsValid(t, jsonlDatabase.GetFingerprints())
+	content, _ := ioutil.ReadFile(path)
+	if err := VerifySignature(path, content, publicKey); err != nil {
+		t.Errorf("The saved database should have a valid signature: %s.", err)
+	}
+}
+
+func testCsvDatabaseUnsigned(t *testing.T) {
+
+	privateKey, _ := loadTestKeys(t)
+	path := testHelper.GetTestPath("unsigned.csv")
+	fingerprint := &Fingerprint{Filename: "simple.txt", Checksum: []byte{12, 23, 34, 45}, Algorithm: "sha1"}
+	signedDatabase := NewCsvDatabase("", path, "")
+	signedDatabase.SetSigningKey(privateKey)
+	signedDatabase.AddFingerprint(fingerprint)
+	signedDatabase.SaveFingerprints()
+	csvDatabase := NewCsvDatabase(path, path, "")
+
+	csvDatabase.LoadFingerprints()
+	csvDatabase.SaveFingerprints()
+
+	if util.CheckIfFileExists(path+SignatureExtension) ||
+		!util.CheckIfFileExists(path+SignatureExtension+util.BackupExtension) {
+		t.Error("The signature of the previous version should be moved to the backup when saving without a key.")
+	}
+}
+
+func testBlockFileSigned(t *testing.T) {
+
+	privateKey, publicKey := loadTestKeys(t)
+	path := testHelper.GetTestPath("blocks.csv")
+	fingerprint := &Fingerprint{Filename: "disk.img", Checksum: []byte{12, 23}, Algorithm: "sha1", BlockSize: 4,
+		Blocks: [][]byte{{1}, {2}}}
+	csvDatabase := NewCsvDatabase(path, path, "")
+	csvDatabase.SetSigningKey(privateKey)
+	csvDatabase.AddFingerprint(fingerprint)
+	csvDatabase.SaveFingerprints()
+	loaded := list.New()
+	loaded.PushFront(&Fingerprint{Filename: "disk.img", Algorithm: "sha1", BlockSize: 4})
+
+	validErr := LoadBlockFile(path+BlockFileSuffix, loaded, publicKey)
+	content, _ := ioutil.ReadFile(path + BlockFileSuffix)
+	ioutil.WriteFile(path+BlockFileSuffix, []byte(strings.Replace(string(content), ",01,", ",ff,", 1)), 0644)
+	tamperedErr := LoadBlockFile(path+BlockFileSuffix, loaded, publicKey)
+
+	if validErr != nil || len(loaded.Front().Value.(*Fingerprint).Blocks) != 2 {
+		t.Errorf("The signed block file should be loaded: %v.", validErr)
+	}
+	if tamperedErr != ErrInvalidSignature {
+		t.Errorf("A changed block file should be rejected: %v.", tamperedErr)
+	}
+}
+
+func tearDownSignatureTests() {
+
+	testHelper.CleanUp()
+}
+
+func loadTestKeys(t *testing.T) (ed25519.PrivateKey, ed25519.PublicKey) {
+
+	privateKey, err := LoadPrivateKey(testHelper.GetTestPath("registry.key"))
+	if err != nil {
+		t.Fatalf("Cannot load private key: %s.", err)
+	}
+	publicKey, err := LoadPublicKey(testHelper.GetTestPath("registry.key" + PublicKeyExtension))
+	if err != nil {
+		t.Fatalf("Cannot load public key: %s.", err)
+	}
+
+	return privateKey, publicKey
+}
@@ -0,0 +1,199 @@
+package dal
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/hex"
+	"fmr/util"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// XattrPrefix Stores the prefix of the extended attributes holding checksums. The name of the algorithm follows, e.g.
+// user.checksum.sha256, the value is the checksum in hexadecimal.
+const XattrPrefix string = "user.checksum."
+
+// XattrModifiedAt Stores the name of the extended attribute holding the modification time of the file when it was
+// hashed.
+const XattrModifiedAt string = XattrPrefix + "mtime"
+
+// XattrDatabase Stores fingerprints in the extended attributes of the files themselves, so that the checksums travel
+// with the files when they are renamed or moved. The files are listed under a directory and named relative to a base
+// path, just like when calculating checksums. Only the checksum and the modification time are stored.
+type XattrDatabase struct {
+	directory    string
+	basePath     string
+	fingerprints *list.List
+	namePairs    *list.List
+}
+
+// NewXattrDatabase Instantiates a new XattrDatabase object for the files under the given directory.
+func NewXattrDatabase(directory string, basePath string) *XattrDatabase {
+
+	return &XattrDatabase{directory, basePath, list.New(), list.New()}
+}
+
+// AddFingerprint Adds a fingerprint to the database.
+func (db *XattrDatabase) AddFingerprint(fingerprint *Fingerprint) {
+
+	if fingerprint != nil {
+		db.fingerprints.PushFront(fingerprint)
+	}
+}
+
+// AddFingerprints Adds a list of fingerprints to the database.
+func (db *XattrDatabase) AddFingerprints(fingerprints *list.List) {
+
+	for element := fingerprints.Front(); element != nil; element = element.Next() {
+		fingerprint := element.Value.(*Fingerprint)
+		db.AddFingerprint(fingerprint)
+	}
+}
+
+// AddNamePair Adds a name pair to the database.
+func (db *XattrDatabase) AddNamePair(namePair *NamePair) {
+
+	if namePair != nil {
+		db.namePairs.PushFront(namePair)
+	}
+}
+
+// Clear Removes all entries from the database. The extended attributes of the files are kept.
+func (db *XattrDatabase) Clear() {
+
+	db.fingerprints.Init()
+	db.namePairs.Init()
+}
+
+// GetFingerprints Returns stored fingerprints.
+func (db *XattrDatabase) GetFingerprints() *list.List {
+
+	return db.fingerprints
+}
+
+// GetNamePairs Returns stored name pairs.
+func (db *XattrDatabase) GetNamePairs() *list.List {
+
+	return db.namePairs
+}
+
+// LoadFingerprints Loads a fingerprint for each checksum found in the extended attributes of the files under the
+// directory. Checksums of unknown algorithms are ignored.
+func (db *XattrDatabase) LoadFingerprints() {
+
+	db.readFingerprints(func(fingerprint *Fingerprint) {
+		db.fingerprints.PushFront(fingerprint)
+	})
+}
+
+// LoadNamesFromFingeprints Passes the names of the files having a checksum to the given StringWriter.
+func (db *XattrDatabase) LoadNamesFromFingeprints(writer util.StringWriter) {
+
+	db.readFingerprints(func(fingerprint *Fingerprint) {
+		writer.Write(fingerprint.Filename)
+	})
+}
+
+// SaveFingerprints Writes the checksum and the modification time of each fingerprint to the extended attributes of its
+// file under the base path. Attributes already holding the same value are not written again. Symbolic links recorded
+// with their target are skipped, they cannot have extended attributes of their own.
+func (db *XattrDatabase) SaveFingerprints() {
+
+	for element := db.fingerprints.Back(); element != nil; element = element.Prev() {
+		fingerprint := element.Value.(*Fingerprint)
+		if fingerprint.LinkTarget != "" {
+			continue
+		}
+
+		fullPath := path.Join(db.basePath, fingerprint.Filename)
+		if err := writeXattrFingerprint(fullPath, fingerprint); err != nil {
+			util.LogWarn(fmt.Sprintf("Cannot store the checksum of %s: %s.", fullPath, err), util.Field("file", fullPath))
+		}
+	}
+}
+
+// SaveNamePairs Does nothing, the name pairs are not stored with the files.
+func (db *XattrDatabase) SaveNamePairs() {
+}
+
+func (db *XattrDatabase) readFingerprints(process func(fingerprint *Fingerprint)) {
+
+	effectiveBasePath := util.TrimPath(db.directory, db.basePath)
+	for _, file := range util.ListFilesRecursively(db.directory) {
+		fullPath := path.Join(db.directory, file)
+		xattrs, err := util.ReadXattrs(fullPath)
+		if err != nil {
+			util.LogWarn(fmt.Sprintf("Cannot read the extended attributes of %s: %s.", fullPath, err),
+				util.Field("file", fullPath))
+			continue
+		}
+
+		filename := util.NormalizePath(path.Join(effectiveBasePath, file))
+		for _, fingerprint := range createXattrFingerprints(filename, fullPath, xattrs) {
+			process(fingerprint)
+		}
+	}
+}
+
+// createXattrFingerprints Creates a fingerprint for each checksum stored in the given extended attributes.
+func createXattrFingerprints(filename string, fullPath string, xattrs map[string][]byte) []*Fingerprint {
+
+	fingerprints := make([]*Fingerprint, 0)
+	for name, value := range xattrs {
+		algorithm := strings.TrimPrefix(name, XattrPrefix)
+		if algorithm == name || !isXattrAlgorithm(algorithm) {
+			continue
+		}
+
+		checksum, err := hex.DecodeString(strings.TrimSpace(string(value)))
+		if err != nil {
+			util.LogWarn(fmt.Sprintf("Invalid checksum in %s of %s.", name, fullPath), util.Field("file", fullPath))
+			continue
+		}
+
+		fingerprint := &Fingerprint{Filename: filename, Checksum: checksum, Algorithm: algorithm}
+		fingerprint.ModifiedAt = string(xattrs[XattrModifiedAt])
+		if fileInfo, err := os.Stat(fullPath); err == nil {
+			fingerprint.Size = fileInfo.Size()
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	return fingerprints
+}
+
+func writeXattrFingerprint(fullPath string, fingerprint *Fingerprint) error {
+
+	xattrs, err := util.ReadXattrs(fullPath)
+	if err != nil {
+		return err
+	}
+
+	values := map[string][]byte{XattrPrefix + fingerprint.Algorithm: []byte(hex.EncodeToString(fingerprint.Checksum))}
+	if fingerprint.ModifiedAt != "" {
+		values[XattrModifiedAt] = []byte(fingerprint.ModifiedAt)
+	}
+
+	for name, value := range values {
+		if current, found := xattrs[name]; found && bytes.Equal(current, value) {
+			continue
+		}
+		if err := util.SetXattr(fullPath, name, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isXattrAlgorithm(algorithm string) bool {
+
+	switch algorithm {
+	case CRC32, MD5, SHA1, SHA256, SHA512:
+		return true
+	}
+
+	return false
+}
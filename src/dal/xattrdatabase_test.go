@@ -0,0 +1,98 @@
+package dal
+
+import (
+	"fmr/util"
+	"testing"
+)
+
+func TestXattrDatabase(t *testing.T) {
+
+	setupXattrDatabaseTests()
+
+	t.Run("XattrDatabase_AddFingerprint", testXattrDatabaseAddFingerprint)
+	t.Run("XattrDatabase_AddNamePair", testXattrDatabaseAddNamePair)
+	t.Run("XattrDatabase_Clear", testXattrDatabaseClear)
+	t.Run("XattrDatabase_SaveAndLoadFingerprints", testXattrDatabaseSaveAndLoadFingerprints)
+
+	tearDownXattrDatabaseTests()
+}
+
+func setupXattrDatabaseTests() {
+
+	testHelper.CreateTestRootDirectory()
+	testHelper.CreateTestDirectory("xattrs")
+	testHelper.CreateTestFileWithContent("xattrs/simple.txt", "Hello World!")
+	testHelper.CreateTestFileWithContent("xattrs/other.txt", "Lorem ipsum")
+}
+
+func tearDownXattrDatabaseTests() {
+
+	testHelper.CleanUp()
+}
+
+func testXattrDatabaseAddFingerprint(t *testing.T) {
+
+	xattrDatabase := createXattrDatabase()
+	testDatabaseAddFingerprint(t, xattrDatabase)
+}
+
+func testXattrDatabaseAddNamePair(t *testing.T) {
+
+	xattrDatabase := createXattrDatabase()
+	testDatabaseAddNamePair(t, xattrDatabase)
+}
+
+func testXattrDatabaseClear(t *testing.T) {
+
+	xattrDatabase := createXattrDatabase()
+	testDatabaseClear(t, xattrDatabase)
+}
+
+func testXattrDatabaseSaveAndLoadFingerprints(t *testing.T) {
+
+	if err := util.SetXattr(testHelper.GetTestPath("xattrs/other.txt"), "user.test", []byte("1")); err != nil {
+		t.Skipf("Extended attributes are not supported: %s.", err)
+	}
+
+	// Arrange.
+	checksum := []byte{12, 23, 34, 45}
+	modifiedAt := "2019-08-24T14:15:22.123Z"
+	fingerprint := &Fingerprint{Filename: "xattrs/simple.txt", Checksum: checksum, Algorithm: "sha1"}
+	fingerprint.ModifiedAt = modifiedAt
+	xattrDatabase := createXattrDatabase()
+	xattrDatabase.AddFingerprint(fingerprint)
+
+	// Act.
+	xattrDatabase.SaveFingerprints()
+	loadedDatabase := createXattrDatabase()
+	loadedDatabase.LoadFingerprints()
+
+	// Assert.
+	xattrs, _ := util.ReadXattrs(testHelper.GetTestPath("xattrs/simple.txt"))
+	if string(xattrs[XattrPrefix+"sha1"]) != "0c17222d" {
+		t.Errorf("Wrong checksum in the extended attributes: %s.", xattrs[XattrPrefix+"sha1"])
+	}
+	assertStoredFingerprintIsValidAt(t, loadedDatabase, "xattrs/simple.txt")
+	loaded := loadedDatabase.GetFingerprints().Front().Value.(*Fingerprint)
+	if loaded.ModifiedAt != modifiedAt || loaded.Size != 12 {
+		t.Errorf("Wrong modification time or size: %s, %d.", loaded.ModifiedAt, loaded.Size)
+	}
+}
+
+func createXattrDatabase() *XattrDatabase {
+
+	return NewXattrDatabase(testHelper.GetTestPath("xattrs"), testHelper.GetTestRootDirectory())
+}
+
+func assertStoredFingerprintIsValidAt(t *testing.T, database Database, filename string) {
+
+	fingerprints := database.GetFingerprints()
+	if fingerprints.Len() != 1 {
+		t.Fatalf("Wrong number of fingerprints in the database: %d.", fingerprints.Len())
+	}
+	fingerprint := fingerprints.Front().Value.(*Fingerprint)
+	if fingerprint.Filename != filename || fingerprint.Algorithm != "sha1" ||
+		!util.CompareByteSlices(fingerprint.Checksum, []byte{12, 23, 34, 45}) {
+		t.Errorf("Wrong fingerprint in the database: %s, %s.", fingerprint.Filename, fingerprint.Algorithm)
+	}
+}
@@ -1,3 +1,8 @@
 module fmr
 
-go 1.12
+go 1.13
+
+require (
+	github.com/BurntSushi/toml v1.2.1
+	golang.org/x/text v0.3.8
+)
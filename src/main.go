@@ -1,10 +1,13 @@
 package main
 
-import "fmr/application"
+import (
+	"fmr/application"
+	"os"
+)
 
 func main() {
 
 	app := &application.Application{}
 	app.Initialize()
-	app.Execute()
+	os.Exit(app.Execute())
 }
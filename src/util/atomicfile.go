@@ -0,0 +1,150 @@
+package util
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// BackupExtension The extension of the file storing the previous version of an atomically replaced file.
+const BackupExtension = ".bak"
+
+// AtomicFile A temporary file that replaces its target only when it is committed. Readers of the target never see a
+// partially written file, and a crash during writing leaves the previous version intact.
+type AtomicFile struct {
+	*os.File
+	targetPath string
+	keepBackup bool
+	closed     bool
+}
+
+// CreateAtomicFile Creates a temporary file in the directory of the given target path. If keepBackup is true, the
+// previous version of the target is kept with a ".bak" extension when the file is committed.
+func CreateAtomicFile(targetPath string, keepBackup bool) (*AtomicFile, error) {
+
+	directory, name := filepath.Split(targetPath)
+	if directory == "" {
+		directory = "."
+	}
+
+	file, err := ioutil.TempFile(directory, "."+name+".tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(targetPath); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return &AtomicFile{file, targetPath, keepBackup, false}, nil
+}
+
+// Abort Discards the temporary file, the target is left untouched. Does nothing if the file is already committed.
+func (af *AtomicFile) Abort() {
+
+	if af.closed {
+		return
+	}
+
+	af.closed = true
+	af.File.Close()
+	os.Remove(af.File.Name())
+}
+
+// Commit Flushes the content of the temporary file to the disk and renames it to the target path.
+func (af *AtomicFile) Commit() error {
+
+	if af.closed {
+		return os.ErrClosed
+	}
+
+	if err := af.File.Sync(); err != nil {
+		af.Abort()
+		return err
+	}
+
+	af.closed = true
+	if err := af.File.Close(); err != nil {
+		os.Remove(af.File.Name())
+		return err
+	}
+
+	if af.keepBackup {
+		if err := createBackup(af.targetPath); err != nil {
+			os.Remove(af.File.Name())
+			return err
+		}
+	}
+
+	if err := os.Rename(af.File.Name(), af.targetPath); err != nil {
+		os.Remove(af.File.Name())
+		return err
+	}
+
+	syncDirectory(filepath.Dir(af.targetPath))
+
+	return nil
+}
+
+// createBackup Keeps the current version of the given file as "<path>.bak". A hard link is used if possible, so the
+// original file stays in place until it is replaced.
+func createBackup(path string) error {
+
+	if !CheckIfFileExists(path) {
+		return nil
+	}
+
+	backupPath := path + BackupExtension
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(path, backupPath); err == nil {
+		return nil
+	}
+
+	return copyFile(path, backupPath)
+}
+
+func copyFile(sourcePath string, targetPath string) error {
+
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.Create(targetPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+	if err := target.Sync(); err != nil {
+		target.Close()
+		return err
+	}
+
+	return target.Close()
+}
+
+// syncDirectory Flushes the directory entry of a renamed file. Not every platform supports it, errors are ignored.
+func syncDirectory(directory string) {
+
+	dir, err := os.Open(directory)
+	if err != nil {
+		return
+	}
+
+	dir.Sync()
+	dir.Close()
+}
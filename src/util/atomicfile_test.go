@@ -0,0 +1,99 @@
+package util
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestAtomicFile(t *testing.T) {
+
+	setupAtomicFileTests()
+
+	t.Run("Commit", testAtomicFileCommit)
+	t.Run("Commit_Backup", testAtomicFileCommitBackup)
+	t.Run("Abort", testAtomicFileAbort)
+
+	tearDownAtomicFileTests()
+}
+
+func setupAtomicFileTests() {
+
+	testHelper.CreateTestRootDirectory()
+	testHelper.CreateTestDirectory("atomic")
+}
+
+func testAtomicFileCommit(t *testing.T) {
+
+	targetPath := testHelper.GetTestPath("atomic/new.csv")
+
+	writeAtomicFile(t, targetPath, "first", false)
+
+	assertFileContent(t, targetPath, "first")
+	assertDirectoryEntryCount(t, "atomic", 1)
+}
+
+func testAtomicFileCommitBackup(t *testing.T) {
+
+	targetPath := testHelper.GetTestPath("atomic/registry.csv")
+
+	writeAtomicFile(t, targetPath, "first", true)
+	writeAtomicFile(t, targetPath, "second", true)
+	writeAtomicFile(t, targetPath, "third", true)
+
+	assertFileContent(t, targetPath, "third")
+	assertFileContent(t, targetPath+BackupExtension, "second")
+}
+
+func testAtomicFileAbort(t *testing.T) {
+
+	targetPath := testHelper.GetTestPath("atomic/aborted.csv")
+	writeAtomicFile(t, targetPath, "original", false)
+
+	file, err := CreateAtomicFile(targetPath, true)
+	if err != nil {
+		t.Fatalf("Cannot create atomic file: %s.", err)
+	}
+	file.WriteString("partial")
+	file.Abort()
+
+	assertFileContent(t, targetPath, "original")
+	if CheckIfFileExists(targetPath + BackupExtension) {
+		t.Error("No backup should be created when writing is aborted.")
+	}
+}
+
+func tearDownAtomicFileTests() {
+
+	testHelper.CleanUp()
+}
+
+func writeAtomicFile(t *testing.T, targetPath string, content string, keepBackup bool) {
+
+	file, err := CreateAtomicFile(targetPath, keepBackup)
+	if err != nil {
+		t.Fatalf("Cannot create atomic file: %s.", err)
+	}
+
+	file.WriteString(content)
+	if err := file.Commit(); err != nil {
+		t.Fatalf("Cannot commit atomic file: %s.", err)
+	}
+}
+
+func assertFileContent(t *testing.T, path string, expectedContent string) {
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("Cannot read file %s: %s.", path, err)
+	} else if string(content) != expectedContent {
+		t.Errorf("Wrong content in %s: \"%s\" (expected: \"%s\").", path, string(content), expectedContent)
+	}
+}
+
+func assertDirectoryEntryCount(t *testing.T, directory string, expectedCount int) {
+
+	files := ListDirectory(testHelper.GetTestPath(directory))
+	if len(files) != expectedCount {
+		t.Errorf("Wrong number of files in %s: %d (expected: %d).", directory, len(files), expectedCount)
+	}
+}
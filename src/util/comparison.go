@@ -18,3 +18,36 @@ func CompareByteSlices(slice1 []byte, slice2 []byte) bool {
 
 	return true
 }
+
+// DiffRecords Compares two lists of records, ignoring their order. Returns the records found only in the old list and
+// the records found only in the new list, both in their original order. A record repeated in one list is matched as
+// many times as it appears in the other one.
+func DiffRecords(oldRecords []string, newRecords []string) ([]string, []string) {
+
+	newCounts := make(map[string]int)
+	for _, record := range newRecords {
+		newCounts[record]++
+	}
+
+	removed := make([]string, 0)
+	oldCounts := make(map[string]int)
+	for _, record := range oldRecords {
+		if newCounts[record] > 0 {
+			newCounts[record]--
+			oldCounts[record]++
+		} else {
+			removed = append(removed, record)
+		}
+	}
+
+	added := make([]string, 0)
+	for _, record := range newRecords {
+		if oldCounts[record] > 0 {
+			oldCounts[record]--
+		} else {
+			added = append(added, record)
+		}
+	}
+
+	return removed, added
+}
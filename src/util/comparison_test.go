@@ -73,3 +73,15 @@ func TestCompareByteSlicesNotEqual(t *testing.T) {
 		t.Errorf("The two byte slices should not be equal.")
 	}
 }
+
+func TestDiffRecords(t *testing.T) {
+
+	oldRecords := []string{"header", "b", "a", "c", "a"}
+	newRecords := []string{"header", "a", "b", "d"}
+
+	removed, added := DiffRecords(oldRecords, newRecords)
+
+	if len(removed) != 2 || removed[0] != "c" || removed[1] != "a" || len(added) != 1 || added[0] != "d" {
+		t.Errorf("Wrong diff: %v, %v.", removed, added)
+	}
+}
@@ -0,0 +1,122 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigFile Stores the shared settings and the named profiles of a TOML configuration file.
+type ConfigFile struct {
+	settings map[string]string
+	profiles map[string]map[string]string
+}
+
+// LoadConfigFile Loads the given TOML configuration file. Top-level keys are shared by every profile, profiles are
+// defined in "[profiles.<name>]" tables.
+func LoadConfigFile(path string) *ConfigFile {
+
+	var content map[string]interface{}
+	_, err := toml.DecodeFile(path, &content)
+	CheckErrDontPanic(err, fmt.Sprintf("Cannot parse configuration file %s: %s", path, err))
+
+	configFile, err := NewConfigFile(content)
+	CheckErrDontPanic(err, fmt.Sprintf("Invalid configuration file %s: %s.", path, err))
+
+	return configFile
+}
+
+// NewConfigFile Instantiates a new ConfigFile object from decoded TOML content. The values are turned into option
+// values: arrays are joined with "," like the lists given on the command line, tables other than the profiles are
+// rejected.
+func NewConfigFile(content map[string]interface{}) (*ConfigFile, error) {
+
+	settings := make(map[string]string)
+	profiles := make(map[string]map[string]string)
+
+	for key, value := range content {
+		var err error
+		if key == "profiles" {
+			err = collectProfiles(value, profiles)
+		} else {
+			settings[key], err = formatConfigValue(key, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ConfigFile{settings, profiles}, nil
+}
+
+// GetProfile Returns the settings of the given profile merged with the shared settings. Environment variables in the
+// values are expanded. The second return value is false if there is no profile with the given name.
+func (cf *ConfigFile) GetProfile(name string) (map[string]string, bool) {
+
+	profile, ok := cf.profiles[name]
+	if !ok {
+		return nil, false
+	}
+
+	result := make(map[string]string)
+	for key, value := range cf.settings {
+		result[key] = os.ExpandEnv(value)
+	}
+	for key, value := range profile {
+		result[key] = os.ExpandEnv(value)
+	}
+
+	return result, true
+}
+
+func collectProfiles(value interface{}, profiles map[string]map[string]string) error {
+
+	tables, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("\"profiles\" must be a table")
+	}
+
+	for name, table := range tables {
+		entries, ok := table.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("profile \"%s\" must be a table", name)
+		}
+		profile := make(map[string]string)
+		for key, entry := range entries {
+			formatted, err := formatConfigValue("profiles."+name+"."+key, entry)
+			if err != nil {
+				return err
+			}
+			profile[key] = formatted
+		}
+		profiles[name] = profile
+	}
+
+	return nil
+}
+
+// formatConfigValue Formats a TOML value as an option value. The elements of an array are joined with ","; nested
+// arrays and tables have no option value and are rejected with an error naming the key.
+func formatConfigValue(key string, value interface{}) (string, error) {
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		return "", fmt.Errorf("\"%s\" must not be a table", key)
+	case []map[string]interface{}:
+		return "", fmt.Errorf("\"%s\" must not be an array of tables", key)
+	case []interface{}:
+		items := make([]string, len(typedValue))
+		for index, item := range typedValue {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}, []map[string]interface{}:
+				return "", fmt.Errorf("\"%s\" must be an array of plain values", key)
+			}
+			items[index] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ","), nil
+	}
+
+	return fmt.Sprint(value), nil
+}
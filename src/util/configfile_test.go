@@ -0,0 +1,108 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConfigFile(t *testing.T) {
+
+	setupConfigFileTests()
+
+	t.Run("GetProfile", testConfigFileGetProfile)
+	t.Run("GetProfile_EnvironmentVariables", testConfigFileGetProfileEnvironmentVariables)
+	t.Run("GetProfile_Unknown", testConfigFileGetProfileUnknown)
+	t.Run("NewConfigFile_Tables", testNewConfigFileTables)
+
+	tearDownConfigFileTests()
+}
+
+func setupConfigFileTests() {
+
+	testHelper.CreateTestRootDirectory()
+	testHelper.CreateTestFileWithContent(
+		"fmr.toml",
+		"log = \"/var/log/fmr.log\"\n"+
+			"alg = \"sha1\"\n"+
+			"\n"+
+			"[profiles.photos]\n"+
+			"bp = \"/mnt/archive\"\n"+
+			"alg = \"sha256\"\n"+
+			"missingonly = true\n"+
+			"replicas = [\"/mnt/backup1\", \"/mnt/backup2\"]\n"+
+			"\n"+
+			"[profiles.projects]\n"+
+			"inchk = \"${FMR_TEST_ROOT}/projects.csv\"\n")
+}
+
+func testConfigFileGetProfile(t *testing.T) {
+
+	configFile := LoadConfigFile(testHelper.GetTestPath("fmr.toml"))
+
+	profile, ok := configFile.GetProfile("photos")
+
+	if !ok {
+		t.Fatal("Profile \"photos\" should exist.")
+	}
+	assertProfileValue(t, profile, "bp", "/mnt/archive")
+	assertProfileValue(t, profile, "alg", "sha256")
+	assertProfileValue(t, profile, "missingonly", "true")
+	assertProfileValue(t, profile, "replicas", "/mnt/backup1,/mnt/backup2")
+	assertProfileValue(t, profile, "log", "/var/log/fmr.log")
+	if len(profile) != 5 {
+		t.Errorf("Wrong number of settings in profile: %d.", len(profile))
+	}
+}
+
+func testConfigFileGetProfileEnvironmentVariables(t *testing.T) {
+
+	os.Setenv("FMR_TEST_ROOT", "/srv/registry")
+	defer os.Unsetenv("FMR_TEST_ROOT")
+	configFile := LoadConfigFile(testHelper.GetTestPath("fmr.toml"))
+
+	profile, _ := configFile.GetProfile("projects")
+
+	assertProfileValue(t, profile, "inchk", "/srv/registry/projects.csv")
+	assertProfileValue(t, profile, "alg", "sha1")
+}
+
+func testConfigFileGetProfileUnknown(t *testing.T) {
+
+	configFile := LoadConfigFile(testHelper.GetTestPath("fmr.toml"))
+
+	_, ok := configFile.GetProfile("music")
+
+	if ok {
+		t.Error("Profile \"music\" should not exist.")
+	}
+}
+
+func testNewConfigFileTables(t *testing.T) {
+
+	shared := map[string]interface{}{"filter": map[string]interface{}{"name": "2019"}}
+	profile := map[string]interface{}{
+		"profiles": map[string]interface{}{"photos": map[string]interface{}{"tags": []interface{}{[]interface{}{"a"}}}}}
+
+	_, sharedErr := NewConfigFile(shared)
+	_, profileErr := NewConfigFile(profile)
+
+	if sharedErr == nil || !strings.Contains(sharedErr.Error(), "\"filter\"") {
+		t.Errorf("A table should be rejected with its key: %v.", sharedErr)
+	}
+	if profileErr == nil || !strings.Contains(profileErr.Error(), "\"profiles.photos.tags\"") {
+		t.Errorf("A nested array should be rejected with its key: %v.", profileErr)
+	}
+}
+
+func tearDownConfigFileTests() {
+
+	testHelper.CleanUp()
+}
+
+func assertProfileValue(t *testing.T, profile map[string]string, key string, expectedValue string) {
+
+	if profile[key] != expectedValue {
+		t.Errorf("Wrong value for \"%s\": \"%s\" (expected: \"%s\").", key, profile[key], expectedValue)
+	}
+}
@@ -1,13 +1,11 @@
 package util
 
-import "log"
-
 // CheckErr Displays the given error message if an error has happened and interrupts execution.
 func CheckErr(err error, message string) {
 
 	if err != nil {
 		if message != "" {
-			log.Println(message)
+			LogError(message)
 		}
 		panic(err)
 	}
@@ -18,9 +16,9 @@ func CheckErrDontPanic(err error, message string) {
 
 	if err != nil {
 		if message == "" {
-			log.Fatalln(err)
+			LogFatal(err.Error())
 		} else {
-			log.Fatalln(message)
+			LogFatal(message)
 		}
 	}
 }
@@ -0,0 +1,25 @@
+//go:build linux && (amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x)
+// +build linux
+// +build amd64 arm64 loong64 mips64 mips64le ppc64 ppc64le riscv64 s390x
+
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+// fadvDontNeed The POSIX_FADV_DONTNEED advice of posix_fadvise(2).
+const fadvDontNeed = 4
+
+// DropFileCache Advises the kernel that the cached pages of the given file are not needed any more, so that reading a
+// large tree does not evict the page cache of other processes.
+func DropFileCache(file *os.File) error {
+
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, file.Fd(), 0, 0, fadvDontNeed, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
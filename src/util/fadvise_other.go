@@ -0,0 +1,12 @@
+//go:build !linux || !(amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x)
+// +build !linux !amd64,!arm64,!loong64,!mips64,!mips64le,!ppc64,!ppc64le,!riscv64,!s390x
+
+package util
+
+import "os"
+
+// DropFileCache Does nothing: the page cache is only dropped on 64-bit Linux.
+func DropFileCache(file *os.File) error {
+
+	return nil
+}
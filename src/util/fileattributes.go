@@ -0,0 +1,68 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// FileAttributes Stores the permissions, ownership and extended attributes of a file. The mode is octal, including the
+// setuid, setgid and sticky bits; the owner and group are empty where ownership is not available.
+type FileAttributes struct {
+	Mode   string
+	UID    string
+	GID    string
+	Xattrs map[string][]byte
+}
+
+// ReadFileAttributes Reads the attributes of the given file. A symbolic link is followed if requested, otherwise the
+// attributes of the link itself are read. The extended attributes are nil where they are not supported (see
+// XattrsSupported).
+func ReadFileAttributes(p string, followLinks bool) (FileAttributes, error) {
+
+	stat := os.Lstat
+	if followLinks {
+		stat = os.Stat
+	}
+	fileInfo, err := stat(p)
+	if err != nil {
+		return FileAttributes{}, err
+	}
+
+	attributes := FileAttributes{Mode: FormatFileMode(fileInfo.Mode())}
+	if uid, gid, ok := getOwnership(fileInfo); ok {
+		attributes.UID = strconv.FormatUint(uint64(uid), 10)
+		attributes.GID = strconv.FormatUint(uint64(gid), 10)
+	}
+	if fileInfo.Mode()&os.ModeSymlink == 0 {
+		if attributes.Xattrs, err = listXattrs(p); err != nil {
+			return attributes, err
+		}
+	}
+
+	return attributes, nil
+}
+
+// ReadXattrs Reads the extended attributes of the given file. Returns nil where they are not supported (see
+// XattrsSupported).
+func ReadXattrs(p string) (map[string][]byte, error) {
+
+	return listXattrs(p)
+}
+
+// FormatFileMode Formats the permission bits of the given mode in octal, e.g. "0644" or "4755".
+func FormatFileMode(mode os.FileMode) string {
+
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+
+	return fmt.Sprintf("%04o", bits)
+}
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestFileAttributes(t *testing.T) {
+
+	setupFileAttributesTests()
+
+	t.Run("FormatFileMode", testFormatFileMode)
+	t.Run("ReadFileAttributes", testReadFileAttributes)
+	t.Run("ReadFileAttributes_Symlink", testReadFileAttributesSymlink)
+
+	tearDownFileAttributesTests()
+}
+
+func setupFileAttributesTests() {
+
+	testHelper.CreateTestRootDirectory()
+	testHelper.CreateTestFileWithContent("script.sh", "#!/bin/sh")
+}
+
+func testFormatFileMode(t *testing.T) {
+
+	mode1 := FormatFileMode(0644)
+	mode2 := FormatFileMode(os.ModeSetuid | 0755)
+	mode3 := FormatFileMode(os.ModeDir | os.ModeSticky | 0777)
+
+	if mode1 != "0644" || mode2 != "4755" || mode3 != "1777" {
+		t.Errorf("Wrong modes: %s, %s, %s.", mode1, mode2, mode3)
+	}
+}
+
+func testReadFileAttributes(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Permission bits are not supported on Windows.")
+	}
+	testPath := testHelper.GetTestPath("script.sh")
+	os.Chmod(testPath, 0750)
+
+	attributes, err := ReadFileAttributes(testPath, false)
+
+	if err != nil {
+		t.Fatalf("Cannot read the attributes: %s.", err)
+	}
+	if attributes.Mode != "0750" {
+		t.Errorf("Wrong mode: %s.", attributes.Mode)
+	}
+	if attributes.UID != strconv.Itoa(os.Getuid()) || attributes.GID == "" {
+		t.Errorf("Wrong ownership: %s:%s.", attributes.UID, attributes.GID)
+	}
+	if XattrsSupported && attributes.Xattrs == nil {
+		t.Error("The extended attributes should be listed where they are supported.")
+	}
+}
+
+func testReadFileAttributesSymlink(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Permission bits are not supported on Windows.")
+	}
+	testPath := testHelper.GetTestPath("script.sh")
+	os.Chmod(testPath, 0700)
+	linkPath := testHelper.GetTestPath("script-link.sh")
+	if err := os.Symlink("script.sh", linkPath); err != nil {
+		t.Skipf("Symbolic links are not supported: %s.", err)
+	}
+
+	followed, followedErr := ReadFileAttributes(linkPath, true)
+	link, linkErr := ReadFileAttributes(linkPath, false)
+
+	if followedErr != nil || linkErr != nil {
+		t.Fatalf("Cannot read the attributes: %v, %v.", followedErr, linkErr)
+	}
+	if followed.Mode != "0700" {
+		t.Errorf("The attributes of the target should be read when following the link: %s.", followed.Mode)
+	}
+	if link.Mode != "0777" || link.Xattrs != nil {
+		t.Errorf("The attributes of the link itself should be read otherwise: %s.", link.Mode)
+	}
+}
+
+func tearDownFileAttributesTests() {
+
+	testHelper.CleanUp()
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+func getOwnership(fileInfo os.FileInfo) (uint32, uint32, bool) {
+
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return uint32(stat.Uid), uint32(stat.Gid), true
+}
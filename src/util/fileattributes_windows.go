@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package util
+
+import "os"
+
+// getOwnership Returns false: files have no numeric owner and group on Windows.
+func getOwnership(fileInfo os.FileInfo) (uint32, uint32, bool) {
+
+	return 0, 0, false
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileID Identifies a file on the system regardless of its path.
+type FileID struct {
+	Device uint64
+	Inode  uint64
+}
+
+// GetHardlinkID Returns the identifier of the given file if it has several hard links, so that its content can be
+// hashed once. Returns false for files with a single link.
+func GetHardlinkID(fileInfo os.FileInfo) (FileID, bool) {
+
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink < 2 {
+		return FileID{}, false
+	}
+
+	return FileID{uint64(stat.Dev), uint64(stat.Ino)}, true
+}
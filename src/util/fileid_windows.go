@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package util
+
+import "os"
+
+// FileID Identifies a file on the system regardless of its path.
+type FileID struct {
+	Device uint64
+	Inode  uint64
+}
+
+// GetHardlinkID Returns false: the link count is not available from os.FileInfo on Windows, so hard links are hashed
+// like separate files.
+func GetHardlinkID(fileInfo os.FileInfo) (FileID, bool) {
+
+	return FileID{}, false
+}
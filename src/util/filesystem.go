@@ -9,6 +9,31 @@ import (
 	"strings"
 )
 
+// Policies for the symbolic links found when listing a directory.
+const (
+	// SymlinksFollow Lists the files the links point to as if they were in place of the links, including the
+	// contents of linked directories.
+	SymlinksFollow = "follow"
+	// SymlinksRecord Lists the links themselves, without following them.
+	SymlinksRecord = "record"
+	// SymlinksSkip Leaves the links out.
+	SymlinksSkip = "skip"
+)
+
+// IsSymlinkPolicy Checks whether the given text is one of the symlink policies.
+func IsSymlinkPolicy(policy string) bool {
+
+	return policy == SymlinksFollow || policy == SymlinksRecord || policy == SymlinksSkip
+}
+
+// IsSymlink Checks whether the given path is a symbolic link.
+func IsSymlink(path string) bool {
+
+	stat, err := os.Lstat(path)
+
+	return err == nil && stat.Mode()&os.ModeSymlink != 0
+}
+
 // CheckIfDirectoryExists Checks whether the given directory exist or not.
 func CheckIfDirectoryExists(path string) bool {
 
@@ -29,6 +54,28 @@ func CheckIfFileExists(path string) bool {
 	return false
 }
 
+// GetFileSize Returns the size of the given file or 0 if it cannot be determined.
+func GetFileSize(path string) int64 {
+
+	stat, err := os.Stat(path)
+	if err != nil || stat.IsDir() {
+		return 0
+	}
+
+	return stat.Size()
+}
+
+// GetTotalFileSize Returns the total size of the given files, the paths are relative to the given directory.
+func GetTotalFileSize(directory string, files []string) int64 {
+
+	var totalSize int64
+	for _, file := range files {
+		totalSize += GetFileSize(path.Join(directory, file))
+	}
+
+	return totalSize
+}
+
 // ListDirectory Lists the given directory (only the first level of the hierarchy).
 func ListDirectory(path string) []os.FileInfo {
 
@@ -38,11 +85,23 @@ func ListDirectory(path string) []os.FileInfo {
 	return files
 }
 
-// ListFilesRecursively Lists the given directory recursively. Returns a single path list that does not contain
-// directories.
+// ListFilesRecursively Lists the given directory recursively, following symbolic links. Returns a single path list
+// that does not contain directories.
 func ListFilesRecursively(p string) []string {
 
-	resultList := listDirectoryRecursively(p)
+	return ListFilesWithSymlinkPolicy(p, SymlinksFollow)
+}
+
+// ListFilesWithSymlinkPolicy Lists the given directory recursively, handling symbolic links according to the given
+// policy. A linked directory is not followed if it is the directory containing the link or one of its parents, so
+// loops end. Sockets, named pipes and devices are always left out. Returns a single path list that does not contain
+// directories.
+func ListFilesWithSymlinkPolicy(p string, policy string) []string {
+
+	root, err := os.Stat(p)
+	CheckErr(err, "Cannot list files in directory "+p+".")
+
+	resultList := listDirectoryRecursively(p, policy, []os.FileInfo{root})
 	result := make([]string, resultList.Len())
 
 	counter := 0
@@ -86,7 +145,9 @@ func TrimPath(fullPath string, basePath string) string {
 	return normalizedFullPath
 }
 
-func listDirectoryRecursively(p string) *list.List {
+// listDirectoryRecursively Lists the given directory. The ancestors are the directories being listed, from the root
+// down to the given one.
+func listDirectoryRecursively(p string, policy string, ancestors []os.FileInfo) *list.List {
 
 	result := list.New()
 
@@ -94,21 +155,50 @@ func listDirectoryRecursively(p string) *list.List {
 	CheckErr(err, "Cannot list files in directory "+p+".")
 
 	for _, file := range files {
-		if file.IsDir() {
-			subDirName := file.Name()
-			if subDirName != "." && subDirName != ".." {
-				fullSubDirPath := path.Join(p, subDirName)
-				subFiles := listDirectoryRecursively(fullSubDirPath)
-				mergePathLists(subFiles, result, subDirName)
+		fullPath := path.Join(p, file.Name())
+
+		if file.Mode()&os.ModeSymlink != 0 {
+			if policy == SymlinksSkip {
+				continue
+			} else if policy == SymlinksRecord {
+				result.PushFront(file.Name())
+				continue
+			}
+			if file, err = os.Stat(fullPath); err != nil {
+				LogWarn("Skipped broken symbolic link "+fullPath+".", Field("file", fullPath))
+				continue
+			}
+			if file.IsDir() && containsSameFile(ancestors, file) {
+				LogWarn("Skipped symbolic link "+fullPath+", it would create a loop.", Field("file", fullPath))
+				continue
 			}
+		}
+
+		if file.IsDir() {
+			subDirName := path.Base(fullPath)
+			subFiles := listDirectoryRecursively(fullPath, policy, append(ancestors, file))
+			mergePathLists(subFiles, result, subDirName)
+		} else if file.Mode().IsRegular() {
+			result.PushFront(path.Base(fullPath))
 		} else {
-			result.PushFront(file.Name())
+			LogWarn("Skipped special file "+fullPath+".", Field("file", fullPath))
 		}
 	}
 
 	return result
 }
 
+func containsSameFile(files []os.FileInfo, file os.FileInfo) bool {
+
+	for _, other := range files {
+		if os.SameFile(other, file) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func mergePathLists(source *list.List, target *list.List, prefix string) {
 
 	for element := source.Front(); element != nil; element = element.Next() {
@@ -1,6 +1,9 @@
 package util
 
-import "testing"
+import (
+	"os"
+	"testing"
+)
 
 func TestFilesystem(t *testing.T) {
 
@@ -8,10 +11,14 @@ func TestFilesystem(t *testing.T) {
 
 	t.Run("CheckIfDirectoryExists", testCheckIfDirectoryExists)
 	t.Run("CheckIfFileExists", testCheckIfFileExists)
+	t.Run("GetFileSize", testGetFileSize)
+	t.Run("GetTotalFileSize", testGetTotalFileSize)
 	t.Run("ListDirectory", testListDirectory)
 	t.Run("ListFilesRecursively", testListFilesRecursively)
 	t.Run("NormalizePath", testNormalizePath)
 	t.Run("TrimPath", testTrimPath)
+	t.Run("ListFilesWithSymlinkPolicy", testListFilesWithSymlinkPolicy)
+	t.Run("GetHardlinkID", testGetHardlinkID)
 
 	tearDownFilesystemTests()
 }
@@ -25,7 +32,7 @@ func setupFilesystemTests() {
 	testHelper.CreateTestFile("dir1/sample1.xml")
 	testHelper.CreateTestFile("dir1/sample2.png")
 	testHelper.CreateTestFile("dir2/sample3.jpg")
-	testHelper.CreateTestFile("dir2/sample4.go")
+	testHelper.CreateTestFileWithContent("dir2/sample4.go", "package util")
 }
 
 func testCheckIfDirectoryExists(t *testing.T) {
@@ -60,6 +67,31 @@ func testCheckIfFileExists(t *testing.T) {
 	}
 }
 
+func testGetFileSize(t *testing.T) {
+
+	size1 := GetFileSize(testHelper.GetTestPath("dir2/sample4.go"))
+	size2 := GetFileSize(testHelper.GetTestPath("dir2"))
+	size3 := GetFileSize(testHelper.GetTestPath("dir3/sample5.txt"))
+
+	if size1 != 12 {
+		t.Errorf("Wrong file size: %d.", size1)
+	}
+	if size2 != 0 || size3 != 0 {
+		t.Errorf("The size of directories and missing files should be 0: %d, %d.", size2, size3)
+	}
+}
+
+func testGetTotalFileSize(t *testing.T) {
+
+	totalSize := GetTotalFileSize(
+		testHelper.GetTestRootDirectory(),
+		[]string{"test.txt", "dir2/sample4.go", "dir2/sample5.txt"})
+
+	if totalSize != 12 {
+		t.Errorf("Wrong total file size: %d.", totalSize)
+	}
+}
+
 func testListDirectory(t *testing.T) {
 
 	files := ListDirectory(testHelper.GetTestRootDirectory())
@@ -134,6 +166,57 @@ func testTrimPath(t *testing.T) {
 	}
 }
 
+func testListFilesWithSymlinkPolicy(t *testing.T) {
+
+	testHelper.CreateTestDirectory("links")
+	testHelper.CreateTestFileWithContent("links/target.txt", "Target")
+	if err := os.Symlink("target.txt", testHelper.GetTestPath("links/file.lnk")); err != nil {
+		t.Skipf("Symbolic links are not supported: %s.", err)
+	}
+	os.Symlink("../dir1", testHelper.GetTestPath("links/dir.lnk"))
+	os.Symlink(".", testHelper.GetTestPath("links/loop.lnk"))
+	os.Symlink("missing.txt", testHelper.GetTestPath("links/broken.lnk"))
+	linksPath := testHelper.GetTestPath("links")
+
+	followed := ListFilesWithSymlinkPolicy(linksPath, SymlinksFollow)
+	recorded := ListFilesWithSymlinkPolicy(linksPath, SymlinksRecord)
+	skipped := ListFilesWithSymlinkPolicy(linksPath, SymlinksSkip)
+
+	if len(followed) != 4 ||
+		!testHelper.HasStringValues(followed, "target.txt", "file.lnk", "dir.lnk/sample1.xml", "dir.lnk/sample2.png") {
+		t.Errorf("Wrong files listed when following links: %v.", followed)
+	}
+	if len(recorded) != 5 ||
+		!testHelper.HasStringValues(recorded, "target.txt", "file.lnk", "dir.lnk", "loop.lnk", "broken.lnk") {
+		t.Errorf("Wrong files listed when recording links: %v.", recorded)
+	}
+	if len(skipped) != 1 || skipped[0] != "target.txt" {
+		t.Errorf("Wrong files listed when skipping links: %v.", skipped)
+	}
+}
+
+func testGetHardlinkID(t *testing.T) {
+
+	testHelper.CreateTestFileWithContent("original.txt", "Original")
+	if err := os.Link(testHelper.GetTestPath("original.txt"), testHelper.GetTestPath("hardlink.txt")); err != nil {
+		t.Skipf("Hard links are not supported: %s.", err)
+	}
+	original, _ := os.Stat(testHelper.GetTestPath("original.txt"))
+	hardlink, _ := os.Stat(testHelper.GetTestPath("hardlink.txt"))
+	single, _ := os.Stat(testHelper.GetTestPath("test.txt"))
+
+	originalID, ok1 := GetHardlinkID(original)
+	hardlinkID, ok2 := GetHardlinkID(hardlink)
+	_, ok3 := GetHardlinkID(single)
+
+	if ok1 != ok2 || originalID != hardlinkID {
+		t.Error("Hard links of the same file should have the same identifier.")
+	}
+	if ok3 {
+		t.Error("A file with a single link should have no hard link identifier.")
+	}
+}
+
 func tearDownFilesystemTests() {
 
 	testHelper.CleanUp()
@@ -0,0 +1,175 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogLevel The severity of a log entry.
+type LogLevel int
+
+// The log levels, from the most verbose to the least verbose.
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// LogFormatText Writes each entry as a line holding the time and the message, like the standard log package.
+const LogFormatText = "text"
+
+// LogFormatJSON Writes each entry as a JSON object holding the time, the level, the message and the fields of the
+// entry.
+const LogFormatJSON = "json"
+
+// logLevelNames Stores the names of the levels written in JSON entries.
+var logLevelNames = map[LogLevel]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// LogField A named value attached to a log entry, e.g. the file, the algorithm, the outcome or the duration. Fields are
+// only written in JSON entries, the text format shows the message only.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Field Instantiates a new LogField object.
+func Field(key string, value interface{}) LogField {
+
+	return LogField{key, value}
+}
+
+// logger Writes log entries at or above its level to its output.
+type logger struct {
+	mutex  sync.Mutex
+	output io.Writer
+	level  LogLevel
+	format string
+}
+
+var defaultLogger = &logger{output: os.Stderr, level: LevelInfo, format: LogFormatText}
+
+// ConfigureLog Sets the output, the minimum level and the format of the log.
+func ConfigureLog(output io.Writer, level LogLevel, format string) {
+
+	defaultLogger.mutex.Lock()
+	defer defaultLogger.mutex.Unlock()
+
+	defaultLogger.output = output
+	defaultLogger.level = level
+	defaultLogger.format = format
+}
+
+// IsLogFormat Checks whether the given name is a known log format.
+func IsLogFormat(format string) bool {
+
+	return format == LogFormatText || format == LogFormatJSON
+}
+
+// LogDebug Logs details that are only of interest when following a run closely, e.g. each valid file.
+func LogDebug(message string, fields ...LogField) {
+
+	defaultLogger.write(LevelDebug, message, fields)
+}
+
+// LogInfo Logs the progress and the results of a task.
+func LogInfo(message string, fields ...LogField) {
+
+	defaultLogger.write(LevelInfo, message, fields)
+}
+
+// LogWarn Logs problems that do not stop the task, e.g. a skipped or missing file.
+func LogWarn(message string, fields ...LogField) {
+
+	defaultLogger.write(LevelWarn, message, fields)
+}
+
+// LogError Logs failures, e.g. a corrupt file.
+func LogError(message string, fields ...LogField) {
+
+	defaultLogger.write(LevelError, message, fields)
+}
+
+// LogFatal Logs an error and exits with status 1.
+func LogFatal(message string, fields ...LogField) {
+
+	LogError(message, fields...)
+	os.Exit(1)
+}
+
+func (l *logger) write(level LogLevel, message string, fields []LogField) {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if level < l.level {
+		return
+	}
+
+	now := time.Now()
+	if l.format == LogFormatJSON {
+		l.output.Write(formatJSONLogEntry(now, level, message, fields))
+	} else {
+		fmt.Fprintf(l.output, "%s %s\n", now.Format("2006/01/02 15:04:05"), message)
+	}
+}
+
+// formatJSONLogEntry Formats an entry as a JSON object on a single line. The time, the level and the message come
+// first, followed by the fields in the given order.
+func formatJSONLogEntry(now time.Time, level LogLevel, message string, fields []LogField) []byte {
+
+	entry := new(bytes.Buffer)
+	entry.WriteString("{")
+	writeJSONLogField(entry, "time", now.Format(time.RFC3339Nano))
+	entry.WriteString(",")
+	writeJSONLogField(entry, "level", logLevelNames[level])
+	entry.WriteString(",")
+	writeJSONLogField(entry, "msg", message)
+	for _, field := range fields {
+		entry.WriteString(",")
+		writeJSONLogField(entry, field.Key, field.Value)
+	}
+	entry.WriteString("}\n")
+
+	return entry.Bytes()
+}
+
+func writeJSONLogField(entry *bytes.Buffer, key string, value interface{}) {
+
+	if err, isError := value.(error); isError {
+		value = err.Error()
+	}
+
+	encodedKey, _ := marshalLogValue(key)
+	encodedValue, err := marshalLogValue(value)
+	if err != nil {
+		encodedValue, _ = marshalLogValue(fmt.Sprint(value))
+	}
+
+	entry.Write(encodedKey)
+	entry.WriteString(":")
+	entry.Write(encodedValue)
+}
+
+// marshalLogValue Encodes the given value without escaping HTML characters, which are common in filenames.
+func marshalLogValue(value interface{}) ([]byte, error) {
+
+	encoded := new(bytes.Buffer)
+	encoder := json.NewEncoder(encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(encoded.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogging(t *testing.T) {
+
+	t.Run("Log_Text", testLogText)
+	t.Run("Log_JSON", testLogJSON)
+	t.Run("Log_Level", testLogLevel)
+
+	ConfigureLog(os.Stderr, LevelInfo, LogFormatText)
+}
+
+func testLogText(t *testing.T) {
+
+	output := new(bytes.Buffer)
+	ConfigureLog(output, LevelInfo, LogFormatText)
+
+	LogInfo("Corrupt: a&b.txt", Field("file", "a&b.txt"))
+
+	if !strings.HasSuffix(output.String(), " Corrupt: a&b.txt\n") || strings.Contains(output.String(), "file") {
+		t.Errorf("The text format should show the message only: %q.", output.String())
+	}
+}
+
+func testLogJSON(t *testing.T) {
+
+	output := new(bytes.Buffer)
+	ConfigureLog(output, LevelInfo, LogFormatJSON)
+
+	LogError("Corrupt: a&b.txt", Field("file", "a&b.txt"), Field("duration", 1.5), Field("error", errors.New("io")))
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatalf("Invalid JSON entry: %s.", err)
+	}
+	if entry["level"] != "error" || entry["msg"] != "Corrupt: a&b.txt" || entry["file"] != "a&b.txt" ||
+		entry["duration"] != 1.5 || entry["error"] != "io" || entry["time"] == nil {
+		t.Errorf("Wrong JSON entry: %s.", output.String())
+	}
+	if !strings.HasPrefix(output.String(), `{"time":`) || strings.Count(output.String(), "\n") != 1 {
+		t.Errorf("The entry should be a single line starting with the time: %s.", output.String())
+	}
+}
+
+func testLogLevel(t *testing.T) {
+
+	output := new(bytes.Buffer)
+	ConfigureLog(output, LevelWarn, LogFormatText)
+
+	LogDebug("debug")
+	LogInfo("info")
+	LogWarn("warn")
+	LogError("error")
+
+	if lines := strings.Split(strings.TrimSpace(output.String()), "\n"); len(lines) != 2 ||
+		!strings.HasSuffix(lines[0], " warn") || !strings.HasSuffix(lines[1], " error") {
+		t.Errorf("Only warnings and errors should be logged: %q.", output.String())
+	}
+}
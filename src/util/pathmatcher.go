@@ -0,0 +1,103 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// PathMatcher Compares paths written on different systems. Unicode normalization matches the decomposed names (NFD)
+// written on macOS with the composed ones (NFC) used elsewhere, case folding matches names on case-insensitive
+// filesystems. Without either option paths only match if they are equal.
+type PathMatcher struct {
+	Normalize bool
+	FoldCase  bool
+}
+
+// NewPathMatcher Instantiates a new PathMatcher object.
+func NewPathMatcher(normalize bool, foldCase bool) PathMatcher {
+
+	return PathMatcher{normalize, foldCase}
+}
+
+// IsExact Checks whether the matcher compares paths as they are.
+func (matcher PathMatcher) IsExact() bool {
+
+	return !matcher.Normalize && !matcher.FoldCase
+}
+
+// GetMatchKey Returns the form of the given path that is compared: NFC normalized and case folded if requested.
+func (matcher PathMatcher) GetMatchKey(p string) string {
+
+	if matcher.Normalize || matcher.FoldCase {
+		p = norm.NFC.String(p)
+	}
+	if matcher.FoldCase {
+		p = cases.Fold().String(p)
+	}
+
+	return p
+}
+
+// Matches Checks whether the given paths match.
+func (matcher PathMatcher) Matches(p1 string, p2 string) bool {
+
+	return p1 == p2 || (!matcher.IsExact() && matcher.GetMatchKey(p1) == matcher.GetMatchKey(p2))
+}
+
+// PathResolver Finds files under a base path by a relative path that may differ from the name on disk in Unicode
+// normalization or case. The directory listings are cached.
+type PathResolver struct {
+	basePath string
+	matcher  PathMatcher
+	listings map[string]map[string]string
+}
+
+// NewPathResolver Instantiates a new PathResolver object.
+func NewPathResolver(basePath string, matcher PathMatcher) *PathResolver {
+
+	return &PathResolver{basePath, matcher, make(map[string]map[string]string)}
+}
+
+// Resolve Returns the full path of the file matching the given relative path. The path is returned as it is if it
+// exists or no match is found; the second value tells whether the file exists.
+func (resolver *PathResolver) Resolve(relativePath string) (string, bool) {
+
+	fullPath := path.Join(resolver.basePath, relativePath)
+	if _, err := os.Lstat(fullPath); err == nil || resolver.matcher.IsExact() {
+		return fullPath, err == nil
+	}
+
+	directory := resolver.basePath
+	for _, name := range strings.Split(NormalizePath(relativePath), "/") {
+		actualName, found := resolver.getListing(directory)[resolver.matcher.GetMatchKey(name)]
+		if !found {
+			return fullPath, false
+		}
+		directory = path.Join(directory, actualName)
+	}
+
+	return directory, true
+}
+
+// getListing Returns the names in the given directory by their match keys.
+func (resolver *PathResolver) getListing(directory string) map[string]string {
+
+	if listing, isListed := resolver.listings[directory]; isListed {
+		return listing
+	}
+
+	listing := make(map[string]string)
+	if files, err := ioutil.ReadDir(directory); err == nil {
+		for _, file := range files {
+			listing[resolver.matcher.GetMatchKey(file.Name())] = file.Name()
+		}
+	}
+	resolver.listings[directory] = listing
+
+	return listing
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"path"
+	"testing"
+)
+
+func TestPathMatcher(t *testing.T) {
+
+	setupPathMatcherTests()
+
+	t.Run("GetMatchKey", testPathMatcherGetMatchKey)
+	t.Run("Matches", testPathMatcherMatches)
+	t.Run("PathResolver_Resolve", testPathResolverResolve)
+
+	tearDownPathMatcherTests()
+}
+
+func setupPathMatcherTests() {
+
+	testHelper.CreateTestRootDirectory()
+	testHelper.CreateTestDirectory("Cafe\u0301")
+	testHelper.CreateTestFileWithContent("Cafe\u0301/Menu.TXT", "Espresso")
+}
+
+func testPathMatcherGetMatchKey(t *testing.T) {
+
+	decomposed := "Cafe\u0301/Menu.TXT"
+
+	key1 := NewPathMatcher(false, false).GetMatchKey(decomposed)
+	key2 := NewPathMatcher(true, false).GetMatchKey(decomposed)
+	key3 := NewPathMatcher(true, true).GetMatchKey(decomposed)
+
+	if key1 != decomposed {
+		t.Errorf("An exact matcher should not change the path: %q.", key1)
+	}
+	if key2 != "Caf\u00e9/Menu.TXT" {
+		t.Errorf("The path should be NFC normalized: %q.", key2)
+	}
+	if key3 != "caf\u00e9/menu.txt" {
+		t.Errorf("The path should be NFC normalized and case folded: %q.", key3)
+	}
+}
+
+func testPathMatcherMatches(t *testing.T) {
+
+	exact := NewPathMatcher(false, false)
+	normalizing := NewPathMatcher(true, false)
+	folding := NewPathMatcher(false, true)
+
+	if exact.Matches("Cafe\u0301", "Caf\u00e9") || !normalizing.Matches("Cafe\u0301", "Caf\u00e9") {
+		t.Error("Decomposed and composed names should only match after normalization.")
+	}
+	if normalizing.Matches("MENU.txt", "menu.txt") || !folding.Matches("MENU.txt", "menu.txt") {
+		t.Error("Names differing in case should only match with case folding.")
+	}
+}
+
+func testPathResolverResolve(t *testing.T) {
+
+	rootPath := testHelper.GetTestRootDirectory()
+	resolver := NewPathResolver(rootPath, NewPathMatcher(true, true))
+	exactResolver := NewPathResolver(rootPath, NewPathMatcher(false, false))
+
+	resolved, found := resolver.Resolve("caf\u00e9/menu.txt")
+	_, foundExact := exactResolver.Resolve("caf\u00e9/menu.txt")
+	_, foundMissing := resolver.Resolve("caf\u00e9/missing.txt")
+
+	if !found || resolved != path.Join(rootPath, "Cafe\u0301/Menu.TXT") {
+		t.Errorf("The file should be found by a matching path: %q.", resolved)
+	}
+	if foundExact || foundMissing {
+		t.Error("Only matching files should be found.")
+	}
+}
+
+func tearDownPathMatcherTests() {
+
+	testHelper.CleanUp()
+}
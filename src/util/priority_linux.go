@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"io/ioutil"
+	"strconv"
+	"syscall"
+)
+
+// PrioritySupported Tells whether the CPU and I/O priority of the process can be lowered on this platform.
+const PrioritySupported = true
+
+// The arguments of ioprio_set(2) selecting a thread and the idle I/O class.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// SetIdleIOPriority Sets the I/O scheduling class of the process to idle: its disk requests are only served when no
+// other process needs the disk. Only the CFQ and BFQ schedulers honour the class.
+func SetIdleIOPriority() error {
+
+	return forEachThread(func(tid int) error {
+		_, _, errno := syscall.Syscall(
+			syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassIdle<<ioprioClassShift)
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	})
+}
+
+// SetNiceness Sets the CPU niceness of the process, from -20 (highest priority) to 19 (lowest priority).
+func SetNiceness(niceness int) error {
+
+	return forEachThread(func(tid int) error {
+		return syscall.Setpriority(syscall.PRIO_PROCESS, tid, niceness)
+	})
+}
+
+// forEachThread Calls the given function for each thread of the process. On Linux the priorities belong to the
+// threads; the threads started later inherit them from the thread creating them.
+func forEachThread(f func(tid int) error) error {
+
+	tasks, err := ioutil.ReadDir("/proc/self/task")
+	if err != nil {
+		return f(0)
+	}
+
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if err := f(tid); err != nil && err != syscall.ESRCH {
+			return err
+		}
+	}
+
+	return nil
+}
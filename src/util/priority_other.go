@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import "errors"
+
+// PrioritySupported Tells whether the CPU and I/O priority of the process can be lowered on this platform.
+const PrioritySupported = false
+
+// SetIdleIOPriority Fails: the I/O priority is only set on Linux.
+func SetIdleIOPriority() error {
+
+	return errors.New("the I/O priority cannot be set on this platform")
+}
+
+// SetNiceness Fails: the niceness is only set on Linux.
+func SetNiceness(niceness int) error {
+
+	return errors.New("the niceness cannot be set on this platform")
+}
@@ -0,0 +1,14 @@
+package util
+
+import "os"
+
+// IsTerminal Checks whether the given file is attached to a terminal.
+func IsTerminal(file *os.File) bool {
+
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
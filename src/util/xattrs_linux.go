@@ -0,0 +1,71 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"bytes"
+	"syscall"
+)
+
+// XattrsSupported Tells whether extended attributes can be read on this platform.
+const XattrsSupported = true
+
+// listXattrs Reads the extended attributes of the given file, e.g. user.* attributes and SELinux labels. Returns an
+// empty map if the filesystem does not support them.
+func listXattrs(p string) (map[string][]byte, error) {
+
+	xattrs := make(map[string][]byte)
+
+	names, err := readXattr(func(dest []byte) (int, error) { return syscall.Listxattr(p, dest) })
+	if err == syscall.ENOTSUP {
+		return xattrs, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, name := range bytes.Split(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		value, err := readXattr(func(dest []byte) (int, error) { return syscall.Getxattr(p, string(name), dest) })
+		if err == syscall.ENODATA {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		xattrs[string(name)] = value
+	}
+
+	return xattrs, nil
+}
+
+// SetXattr Sets the value of the given extended attribute of the file.
+func SetXattr(p string, name string, value []byte) error {
+
+	return syscall.Setxattr(p, name, value, 0)
+}
+
+// readXattr Calls the given function with a buffer of the size it reports, retrying if the value grows meanwhile.
+func readXattr(read func(dest []byte) (int, error)) ([]byte, error) {
+
+	for {
+		size, err := read(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return []byte{}, nil
+		}
+
+		buffer := make([]byte, size)
+		size, err = read(buffer)
+		if err == syscall.ERANGE {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		return buffer[:size], nil
+	}
+}
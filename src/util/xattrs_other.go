@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import "errors"
+
+// XattrsSupported Tells whether extended attributes can be read on this platform.
+const XattrsSupported = false
+
+// listXattrs Returns nil: extended attributes are only read on Linux.
+func listXattrs(p string) (map[string][]byte, error) {
+
+	return nil, nil
+}
+
+// SetXattr Fails: extended attributes are only written on Linux.
+func SetXattr(p string, name string, value []byte) error {
+
+	return errors.New("extended attributes are not supported on this platform")
+}